@@ -1,5 +1,12 @@
 package ggfnt
 
+// Flags describing the characteristics of an animated glyph mapping group
+// (groups with more than one glyph). The lower 4 bits are the flags defined
+// below, while the upper 4 bits are an animation group index whose meaning
+// is still undefined. See the mapping section of the ggfnt specification.
+//
+// Frame timings are not part of the format, so renderers must decide the
+// frame durations on their own.
 type AnimationFlags uint8
 const (
 	AnimFlagLoopable AnimationFlags = 0b0000_0001 // can wrap back to start after reaching the end
@@ -8,3 +15,33 @@ const (
 	AnimFlagSplit AnimationFlags = 0b0000_1000 // frames are independent and we can stop/rest at any of them
 	AnimFlagsGroupMask AnimationFlags = 0b1111_0000 // usage still undefined. maybe better leave as custom use flags?
 )
+
+// Returns whether the animation can wrap back to the start after
+// reaching the end.
+func (self AnimationFlags) IsLoopable() bool {
+	return self & AnimFlagLoopable != 0
+}
+
+// Returns whether the animation must always be played sequentially,
+// either from start to end or backwards.
+func (self AnimationFlags) IsSequential() bool {
+	return self & AnimFlagSequential != 0
+}
+
+// Returns whether the animation represents a vanishing or destructive
+// sequence that shouldn't be rewinded or replayed automatically.
+func (self AnimationFlags) IsTerminal() bool {
+	return self & AnimFlagTerminal != 0
+}
+
+// Returns whether the animation is composed of independent frames that
+// the animation can be stopped at. Split animations are never sequential.
+func (self AnimationFlags) IsSplit() bool {
+	return self & AnimFlagSplit != 0
+}
+
+// Returns the animation group index stored in the upper 4 bits of the
+// flags, in [0, 15]. Its meaning is still undefined by the format.
+func (self AnimationFlags) Group() uint8 {
+	return uint8(self & AnimFlagsGroupMask) >> 4
+}
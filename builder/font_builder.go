@@ -2,6 +2,7 @@ package builder
 
 import "fmt"
 import "io"
+import "bytes"
 import "slices"
 import "errors"
 
@@ -12,6 +13,7 @@ import "github.com/tinne26/ggfnt/internal"
 const debugBuildGlyphEncoding = false
 
 const invalidInternalState = "invalid internal state"
+const invalidFontData = "invalid font data"
 const fontBuilderDefaultFontName = "Unnamed"
 const fontBuilderDefaultFontAuthor = "Authorless"
 const fontBuilderDefaultFontAbout = "No information available."
@@ -61,6 +63,7 @@ type Font struct {
 	lineGap uint8
 	vertLineWidth uint8
 	vertLineGap uint8
+	strictGlyphMetrics bool // see SetStrictGlyphMetrics
 
 	// color sections
 	dyes []dyeSection
@@ -168,10 +171,65 @@ func New() *Font {
 	return builder
 }
 
+// Resets the builder to the same state it would have after [New](),
+// clearing all glyphs, mappings, rules, kerning and edition data. If
+// keepFontID is true, the current font ID is preserved. Otherwise, a
+// new one is generated. Internal buffers are kept for reuse.
+func (self *Font) Reset(keepFontID bool) {
+	fresh := New()
+	if keepFontID { fresh.fontID = self.fontID }
+
+	clear(self.tempGlyphIndexLookup)
+	fresh.tempGlyphIndexLookup = self.tempGlyphIndexLookup
+	fresh.tempSortingBuffer = self.tempSortingBuffer[ : 0]
+	fresh.tempMaskEncoder = self.tempMaskEncoder
+	*self = *fresh
+}
+
 // Creates a [Font] builder already initialized with the given font
 // values, to make it easier to modify an existing font.
-func NewFrom(font *Font) *Font {
-	panic("unimplemented")
+//
+// Glyphs get new UIDs, but their order is preserved, and all sections
+// (mappings, rewrite rules, kerning, etc.) are rebuilt against those
+// UIDs, so calling [Font.Build]() right away results in an equivalent
+// font. The font ID is also preserved. Edition data is not part of
+// .ggfnt files, so it has to be loaded separately with
+// [Font.ParseEditionData]() if needed.
+//
+// The given font must be valid. Malformed data will cause a panic.
+func NewFrom(font *ggfnt.Font) *Font {
+	builder := New()
+	builder.loadHeader(font)
+	builder.loadMetrics(font)
+	builder.loadColors(font)
+	glyphUIDs := builder.loadGlyphs(font)
+	builder.loadSettings(font)
+	builder.loadMapping(font, glyphUIDs)
+	builder.loadRewrites(font, glyphUIDs)
+	builder.loadKerning(font, glyphUIDs)
+	return builder
+}
+
+// Parses the given font file data, loads it into a new builder with
+// [NewFrom]() and builds it again, checking that the resulting font
+// data is identical to the original. This is mostly a testing utility
+// to catch asymmetries between the builder and the parser, which font
+// authors can also run in their own CI against their fonts.
+func CheckRoundTrip(data []byte) error {
+	font, err := ggfnt.Parse(bytes.NewReader(data))
+	if err != nil { return err }
+	reFont, err := NewFrom(font).Build()
+	if err != nil { return errors.New("failed to rebuild font: " + err.Error()) }
+
+	if len(font.Data) != len(reFont.Data) {
+		return fmt.Errorf("round trip data size mismatch (%d vs %d bytes)", len(font.Data), len(reFont.Data))
+	}
+	for i := 0; i < len(font.Data); i++ {
+		if font.Data[i] != reFont.Data[i] {
+			return fmt.Errorf("round trip data mismatch at byte %d", i)
+		}
+	}
+	return nil
 }
 
 // Converts all the current data into a read-only [Font] object.
@@ -202,6 +260,8 @@ func (self *Font) Build() (*ggfnt.Font, error) {
 	}
 
 	// --- header ---
+	err = self.CheckDateConsistency()
+	if err != nil { return nil, err }
 	var appendDateTo = func(date ggfnt.Date, outBuff []byte) []byte {
 		return append(internal.AppendUint16LE(outBuff, date.Year), date.Month, date.Day)
 	}
@@ -240,7 +300,7 @@ func (self *Font) Build() (*ggfnt.Font, error) {
 	numColorSections := len(self.dyes) + len(self.palettes)
 	if numColorSections > 255 { panic(invalidInternalState) }
 	if numColorSections == 0 { // add main dye if nothing else exists
-		err := self.AddDye("main", 255)
+		_, err := self.AddDye("main", []uint8{255})
 		if err != nil { return nil, err }
 	}
 	
@@ -337,6 +397,13 @@ func (self *Font) Build() (*ggfnt.Font, error) {
 		
 		// append glyph placement
 		gp := glyph.Placement
+		if glyph.AdvanceAdjustment != 0 {
+			advance := int(gp.Advance) + int(glyph.AdvanceAdjustment)
+			if advance < 0 || advance > 255 {
+				return nil, fmt.Errorf("glyph %d advance adjustment leads to an out of range advance (%d)", i, advance)
+			}
+			gp.Advance = uint8(advance)
+		}
 		if self.hasVertLayout {
 			data = append(data, gp.Advance, gp.TopAdvance, gp.BottomAdvance, gp.HorzCenter)
 		} else {
@@ -377,16 +444,19 @@ func (self *Font) Build() (*ggfnt.Font, error) {
 			wordsList[index] = word
 		}
 		slices.Sort(wordsList)
+		for index, word := range wordsList {
+			words[word] = int16(index)
+		}
 
 		// WordEndOffsets
 		var offset uint16
-		for word, _ := range words {
+		for _, word := range wordsList {
 			offset += uint16(len(word))
 			data = internal.AppendUint16LE(data, offset)
 		}
 
 		// Words | append actual words
-		for word, _ := range words {
+		for _, word := range wordsList {
 			data = append(data, word...)
 		}
 	}
@@ -409,6 +479,11 @@ func (self *Font) Build() (*ggfnt.Font, error) {
 			data = append(data, self.settings[i].Name...)
 		}
 
+		// SettingInitValues
+		for i, _ := range self.settings {
+			data = append(data, self.settings[i].InitValue)
+		}
+
 		// SettingEndOffsets
 		font.OffsetToSettingDefinitions = uint32(len(data))
 		offset = 0
@@ -513,12 +588,32 @@ func (self *Font) Build() (*ggfnt.Font, error) {
 	}
 
 	// rewrite sets
+	if len(self.rewriteRuneSets) > 255 { panic(invalidInternalState) }
+	if len(self.rewriteRuneSets) != len(self.runeSetsOrder) { panic(invalidInternalState) }
+	numRuneSets := uint8(len(self.rewriteRuneSets))
 	font.OffsetToRewriteUtf8Sets = uint32(len(data))
+	data = append(data, numRuneSets) // NumUTF8Sets
 	var runeSetsMap = make(map[uint64]uint8)
-	data = append(data, 0) // NumUtf8Sets
-	// ... (TODO)
 	if len(self.rewriteRuneSets) > 0 {
-		panic("rune sets encoding unimplemented")
+		// UTF8SetEndOffsets
+		var offset uint32
+		for index, setUID := range self.runeSetsOrder {
+			runeSetsMap[setUID] = uint8(index)
+			set, found := self.rewriteRuneSets[setUID]
+			if !found { panic(invalidInternalState) }
+			offset += set.GetSize()
+			if offset > 65535 {
+				return nil, errors.New("rewrite rune sets contain too much data (can't exceed 65535 bytes)")
+			}
+			data = internal.AppendUint16LE(data, uint16(offset))
+		}
+
+		// UTF8Sets
+		for _, setUID := range self.runeSetsOrder {
+			set := self.rewriteRuneSets[setUID]
+			data, err = set.AppendTo(data)
+			if err != nil { return nil, err }
+		}
 	}
 	
 	if len(self.rewriteGlyphSets) > 255 { panic(invalidInternalState) }
@@ -652,7 +747,7 @@ func (self *Font) Build() (*ggfnt.Font, error) {
 	for _, pair64 := range self.tempSortingBuffer { // VertKerningValues
 		glyphUID1 := self.glyphOrder[uint16(pair64 >> 16)]
 		glyphUID2 := self.glyphOrder[uint16(pair64)]
-		kerningInfo, found := self.horzKerningPairs[[2]uint64{glyphUID1, glyphUID2}]
+		kerningInfo, found := self.vertKerningPairs[[2]uint64{glyphUID1, glyphUID2}]
 		if !found { panic(invalidInternalState) }
 		if kerningInfo.Class == 0 {
 			data = append(data, uint8(kerningInfo.Value))
@@ -696,6 +791,7 @@ func (self *Font) ParseEditionData(reader io.Reader) error {
 	parser.FileType = "ggwkfnt"
 
 	// read signature first (this is not gzipped, so it's important)
+	parser.Section = "signature"
 	n, err := reader.Read(parser.TempBuff[0 : 6])
 	if err != nil || n != 6 {
 		return parser.NewError("failed to read file signature")
@@ -705,10 +801,10 @@ func (self *Font) ParseEditionData(reader io.Reader) error {
 	}
 
 	err = parser.InitGzipReader(reader)
-	if err != nil { return parser.NewError(err.Error()) }
+	if err != nil { return parser.WrapError(err) }
 
 	// --- categories ---
-	
+	parser.Section = "categories"
 	fontID, err := parser.ReadUint64()
 	if err != nil { return err }
 	if fontID != self.fontID {
@@ -812,8 +908,9 @@ func (self *Font) ParseEditionData(reader io.Reader) error {
 
 	// --- EOF ---
 	// ensure we reach EOF exactly at the right time
+	parser.Section = "EOF"
 	err = parser.EnsureEOF()
-	if err != nil { return parser.NewError(err.Error()) }
+	if err != nil { return parser.WrapError(err) }
 
 	// done
 	completedWithoutErrors = true
@@ -834,9 +931,36 @@ func (self *Font) ClearEditionData() {
 	}
 }
 
+// Returns an error if any edition data is inconsistent with the font.
+//
+// Categories partition the glyph index space in order, so their sizes
+// can't add up to more than the number of glyphs. Any glyphs beyond the
+// last category are considered uncategorized.
 func (self *Font) ValidateEditionData() error {
-	// ...
-	panic("unimplemented")
+	var categorizedGlyphs int
+	for i, _ := range self.categories {
+		if self.categories[i].Size == 0 {
+			return errors.New("glyph category '" + self.categories[i].Name + "' is empty")
+		}
+		for j := 0; j < i; j++ {
+			if self.categories[j].Name == self.categories[i].Name {
+				return errors.New("glyph category '" + self.categories[i].Name + "' is defined more than once")
+			}
+		}
+		categorizedGlyphs += int(self.categories[i].Size)
+	}
+	if categorizedGlyphs > len(self.glyphData) {
+		return fmt.Errorf("glyph categories cover %d glyphs, but the font only has %d", categorizedGlyphs, len(self.glyphData))
+	}
+
+	for _, pairs := range [2]map[[2]uint64]*editionKerningPair{self.horzKerningPairs, self.vertKerningPairs} {
+		for _, pair := range pairs {
+			if pair.HasClass() && int(pair.Class) > len(self.kerningClasses) {
+				return errors.New("kerning pair references an undefined kerning class")
+			}
+		}
+	}
+	return nil
 }
 
 func (self *Font) computeNumSwitchCases(switchIndex uint8) int {
@@ -0,0 +1,233 @@
+package builder
+
+import "io"
+import "fmt"
+import "bufio"
+import "errors"
+import "image"
+import "strconv"
+import "strings"
+import "encoding/hex"
+
+import "github.com/tinne26/ggfnt/mask"
+
+// Creates a new font from BDF (Glyph Bitmap Distribution Format) data.
+//
+// Ascent and descent are taken from the FONTBOUNDINGBOX, while CAP_HEIGHT,
+// X_HEIGHT and FAMILY_NAME properties are used when present. Glyphs are
+// added with their BBX offsets relative to the baseline, using DWIDTH as
+// the advance (or the glyph width if undefined), and mapped to their
+// ENCODING code point.
+//
+// Glyphs that can't be imported (exceeding the font's ascent or descent,
+// with advances above 255, mapped to control codes, etc.) are skipped,
+// and a description of each issue is returned in the warnings.
+func ImportBDF(reader io.Reader) (*Font, []string, error) {
+	importer := bdfImporter{ font: New(), scanner: bufio.NewScanner(reader) }
+	err := importer.run()
+	if err != nil { return nil, nil, err }
+	return importer.font, importer.warnings, nil
+}
+
+type bdfImporter struct {
+	font *Font
+	scanner *bufio.Scanner
+	lineNum int
+	warnings []string
+
+	ascent int
+	descent int
+	defaultAdvance int // -1 if undefined
+}
+
+type bdfGlyph struct {
+	name string
+	encoding int
+	advance int // -1 if undefined
+	bbx [4]int // width, height, x offset, y offset
+	hasBBX bool
+	rows [][]byte
+}
+
+// Returns the fields of the next non-empty line, or nil on EOF.
+func (self *bdfImporter) nextLine() ([]string, error) {
+	for self.scanner.Scan() {
+		self.lineNum += 1
+		fields := strings.Fields(self.scanner.Text())
+		if len(fields) > 0 { return fields, nil }
+	}
+	return nil, self.scanner.Err()
+}
+
+func (self *bdfImporter) errorf(format string, args ...any) error {
+	return fmt.Errorf("BDF line %d: " + format, append([]any{self.lineNum}, args...)...)
+}
+
+func (self *bdfImporter) warnf(format string, args ...any) {
+	self.warnings = append(self.warnings, fmt.Sprintf(format, args...))
+}
+
+func (self *bdfImporter) run() error {
+	fields, err := self.nextLine()
+	if err != nil { return err }
+	if fields == nil || fields[0] != "STARTFONT" {
+		return errors.New("BDF data must start with STARTFONT")
+	}
+
+	self.defaultAdvance = -1
+	var hasBoundingBox bool
+	var capHeight, xHeight int = -1, -1
+	for {
+		fields, err = self.nextLine()
+		if err != nil { return err }
+		if fields == nil { return errors.New("BDF data ended without ENDFONT") }
+
+		switch fields[0] {
+		case "FONTBOUNDINGBOX":
+			values, err := self.parseInts(fields, 4)
+			if err != nil { return err }
+			self.ascent, self.descent = values[1] + values[3], -values[3]
+			if self.ascent <= 0 || self.ascent > 255 || self.descent < 0 || self.descent > 255 {
+				return self.errorf("FONTBOUNDINGBOX ascent and descent must fit in [0, 255], with ascent > 0")
+			}
+			self.font.SetAscent(uint8(self.ascent))
+			self.font.SetDescent(uint8(self.descent))
+			hasBoundingBox = true
+		case "DWIDTH":
+			values, err := self.parseInts(fields, 2)
+			if err != nil { return err }
+			self.defaultAdvance = values[0]
+		case "CAP_HEIGHT", "X_HEIGHT":
+			values, err := self.parseInts(fields, 1)
+			if err != nil { return err }
+			if fields[0] == "CAP_HEIGHT" { capHeight = values[0] } else { xHeight = values[0] }
+		case "FAMILY_NAME":
+			name := strings.Trim(strings.Join(fields[1 : ], " "), "\"")
+			if name == "" { break }
+			err = self.font.SetNameTruncated(name)
+			if err == nil { err = self.font.SetFamily(self.font.GetName()) }
+			if err != nil { self.warnf("family name %q ignored: %s", name, err) }
+		case "STARTCHAR":
+			if !hasBoundingBox { return self.errorf("STARTCHAR found before FONTBOUNDINGBOX") }
+			glyph, err := self.parseGlyph(strings.Join(fields[1 : ], " "))
+			if err != nil { return err }
+			self.addGlyph(glyph)
+		case "ENDFONT":
+			if !hasBoundingBox { return errors.New("BDF data is missing FONTBOUNDINGBOX") }
+			self.setVerticalMetrics(capHeight, xHeight)
+			return nil
+		}
+	}
+}
+
+func (self *bdfImporter) parseInts(fields []string, n int) ([]int, error) {
+	if len(fields) < n + 1 { return nil, self.errorf("%s expects %d values", fields[0], n) }
+	values := make([]int, n)
+	for i := 0; i < n; i++ {
+		value, err := strconv.Atoi(fields[i + 1])
+		if err != nil { return nil, self.errorf("invalid %s value %q", fields[0], fields[i + 1]) }
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (self *bdfImporter) parseGlyph(name string) (bdfGlyph, error) {
+	glyph := bdfGlyph{ name: name, encoding: -1, advance: self.defaultAdvance }
+	for {
+		fields, err := self.nextLine()
+		if err != nil { return glyph, err }
+		if fields == nil { return glyph, errors.New("BDF data ended inside glyph '" + name + "'") }
+
+		switch fields[0] {
+		case "ENCODING":
+			values, err := self.parseInts(fields, 1)
+			if err != nil { return glyph, err }
+			glyph.encoding = values[0]
+		case "DWIDTH":
+			values, err := self.parseInts(fields, 2)
+			if err != nil { return glyph, err }
+			glyph.advance = values[0]
+		case "BBX":
+			values, err := self.parseInts(fields, 4)
+			if err != nil { return glyph, err }
+			if values[0] < 0 || values[1] < 0 { return glyph, self.errorf("BBX size can't be negative") }
+			copy(glyph.bbx[ : ], values)
+			glyph.hasBBX = true
+		case "BITMAP":
+			if !glyph.hasBBX { return glyph, self.errorf("BITMAP found before BBX") }
+			rowSize := (glyph.bbx[0] + 7) >> 3
+			glyph.rows = make([][]byte, 0, glyph.bbx[1])
+			for len(glyph.rows) < glyph.bbx[1] {
+				fields, err = self.nextLine()
+				if err != nil { return glyph, err }
+				if fields == nil || fields[0] == "ENDCHAR" {
+					return glyph, self.errorf("glyph '%s' has fewer BITMAP rows than its BBX height", name)
+				}
+				row, err := hex.DecodeString(fields[0])
+				if err != nil || len(row) < rowSize {
+					return glyph, self.errorf("invalid BITMAP row %q in glyph '%s'", fields[0], name)
+				}
+				glyph.rows = append(glyph.rows, row)
+			}
+		case "ENDCHAR":
+			if !glyph.hasBBX { return glyph, self.errorf("glyph '%s' is missing BBX", name) }
+			if glyph.advance < 0 { glyph.advance = glyph.bbx[0] }
+			return glyph, nil
+		}
+	}
+}
+
+func (self *bdfImporter) addGlyph(glyph bdfGlyph) {
+	if glyph.advance < 0 || glyph.advance > 255 {
+		self.warnf("glyph '%s' skipped: advance %d outside [0, 255]", glyph.name, glyph.advance)
+		return
+	}
+	if glyph.encoding >= 0 && glyph.encoding < ' ' {
+		self.warnf("glyph '%s' skipped: mapped to control code %d", glyph.name, glyph.encoding)
+		return
+	}
+
+	// bdf offsets go from the origin to the bottom-left
+	// corner, with y growing upwards
+	width, height, xOffset, yOffset := glyph.bbx[0], glyph.bbx[1], glyph.bbx[2], glyph.bbx[3]
+	rect := image.Rect(xOffset, -(yOffset + height), xOffset + width, -yOffset)
+	glyphMask := image.NewAlpha(rect)
+	for y, row := range glyph.rows {
+		for x := 0; x < width; x++ {
+			if row[x >> 3] & (0b1000_0000 >> (x & 0b111)) != 0 {
+				glyphMask.Pix[y*glyphMask.Stride + x] = 255
+			}
+		}
+	}
+	inked := mask.ComputeRect(glyphMask)
+	if !inked.Empty() && (inked.Min.Y < -self.ascent || inked.Max.Y > self.descent) {
+		self.warnf("glyph '%s' skipped: bounds %v exceed font ascent %d and descent %d", glyph.name, inked, self.ascent, self.descent)
+		return
+	}
+
+	glyphUID, err := self.font.AddGlyph(glyphMask)
+	if err != nil {
+		self.warnf("glyph '%s' skipped: %s", glyph.name, err)
+		return
+	}
+	placement := self.font.glyphData[glyphUID].Placement
+	placement.Advance = uint8(glyph.advance)
+	placement.HorzCenter = uint8(glyph.advance/2)
+	self.font.glyphData[glyphUID].Placement = placement
+
+	if glyph.name != "" {
+		err = self.font.SetGlyphName(glyphUID, glyph.name)
+		if err != nil { self.warnf("glyph '%s' name ignored: %s", glyph.name, err) }
+	}
+	if glyph.encoding >= 0 {
+		err = self.font.Map(rune(glyph.encoding), glyphUID)
+		if err != nil { self.warnf("glyph '%s' not mapped: %s", glyph.name, err) }
+	}
+}
+
+func (self *bdfImporter) setVerticalMetrics(capHeight, xHeight int) {
+	if capHeight < 0 || capHeight > self.ascent { capHeight = self.ascent }
+	if xHeight < 0 || xHeight > capHeight { xHeight = capHeight }
+	self.font.SetUppercaseAscent(uint8(capHeight))
+	self.font.SetMidlineAscent(uint8(xHeight))
+}
@@ -3,6 +3,8 @@ package builder
 import "errors"
 import "image/color"
 
+import "github.com/tinne26/ggfnt"
+
 // --- dyes ---
 
 type dyeSection struct {
@@ -23,6 +25,7 @@ func (self *Font) EachDyeAlpha(name string, fn func(alpha, index uint8)) error {
 			for index, alpha := range self.dyes[index].alphas {
 				fn(alpha, uint8(clrIndex - index)) // TODO: this might be an inverse order...
 			}
+			return nil
 		} else {
 			clrIndex -= len(self.dyes[index].alphas)
 		}
@@ -30,19 +33,30 @@ func (self *Font) EachDyeAlpha(name string, fn func(alpha, index uint8)) error {
 	return errors.New("dye section not found")
 }
 
-func (self *Font) AddDye(name string, alphas ...uint8) error {
-	// ensure no name collisions
-	err := self.checkColorSectionNameCollision(name)
-	if err != nil { return err }
-	count := self.getColorIndexCount()
-	if count + len(alphas) > 255 {
-		return errors.New("font colors can't exceed 255 indices")
-	}
+// Adds a new dye section with the given alphas and returns its key,
+// which matches the [ggfnt.DyeKey] of the dye in the built font.
+func (self *Font) AddDye(name string, alphas []uint8) (ggfnt.DyeKey, error) {
+	err := self.checkColorSectionAddition(name, len(alphas))
+	if err != nil { return 0, err }
 	dyeSection := dyeSection{ name: name }
 	dyeSection.alphas = make([]uint8, len(alphas))
 	copy(dyeSection.alphas, alphas)
 	self.dyes = append(self.dyes, dyeSection)
-	return nil
+	return ggfnt.DyeKey(len(self.dyes) - 1), nil
+}
+
+// Replaces the alphas of an existing dye section. The number of alphas
+// can't change, as that would shift the color indices used by the glyphs.
+func (self *Font) SetDyeAlphas(name string, alphas ...uint8) error {
+	for index, _ := range self.dyes {
+		if self.dyes[index].name != name { continue }
+		if len(alphas) != len(self.dyes[index].alphas) {
+			return errors.New("the number of dye alphas can't be modified")
+		}
+		copy(self.dyes[index].alphas, alphas)
+		return nil
+	}
+	return errors.New("dye section not found")
 }
 
 // --- palettes ---
@@ -72,25 +86,53 @@ func (self *Font) EachPaletteColor(name string, fn func(rgba color.RGBA, index u
 			for index, clr := range self.palettes[index].colors {
 				fn(clr, uint8(clrIndex - index)) // TODO: this might be an inverse order...
 			}
+			return nil
 		} else {
-			clrIndex -= len(self.dyes[index].alphas)
+			clrIndex -= len(self.palettes[index].colors)
 		}
 	}
 	return errors.New("palette section not found")
 }
 
-func (self *Font) AddPalette(name string, colors ...color.RGBA) error {
-	// ensure no name collisions
-	err := self.checkColorSectionNameCollision(name)
-	if err != nil { return err }
-	count := self.getColorIndexCount()
-	if count + len(colors) > 255 {
-		return errors.New("font colors can't exceed 255 indices")
-	}
+// Adds a new palette section with the given colors and returns its key,
+// which matches the [ggfnt.PaletteKey] of the palette in the built font.
+// Colors must be alpha-premultiplied.
+func (self *Font) AddPalette(name string, colors []color.RGBA) (ggfnt.PaletteKey, error) {
+	err := self.checkColorSectionAddition(name, len(colors))
+	if err != nil { return 0, err }
+	err = checkPremultColors(colors)
+	if err != nil { return 0, err }
 	paletteSection := paletteSection{ name: name }
 	paletteSection.colors = make([]color.RGBA, len(colors))
 	copy(paletteSection.colors, colors)
 	self.palettes = append(self.palettes, paletteSection)
+	return ggfnt.PaletteKey(len(self.palettes) - 1), nil
+}
+
+// Replaces the colors of an existing palette section. The number of colors
+// can't change, as that would shift the color indices used by the glyphs.
+func (self *Font) SetPaletteColors(name string, colors ...color.RGBA) error {
+	for index, _ := range self.palettes {
+		if self.palettes[index].name != name { continue }
+		if len(colors) != len(self.palettes[index].colors) {
+			return errors.New("the number of palette colors can't be modified")
+		}
+		err := checkPremultColors(colors)
+		if err != nil { return err }
+		copy(self.palettes[index].colors, colors)
+		return nil
+	}
+	return errors.New("palette section not found")
+}
+
+// Replaces a single color of an existing palette section, with the
+// index being relative to the palette's first color.
+func (self *Font) SetPaletteColor(key ggfnt.PaletteKey, index uint8, c color.RGBA) error {
+	if int(key) >= len(self.palettes) { return errors.New("palette section not found") }
+	colors := self.palettes[key].colors
+	if int(index) >= len(colors) { return errors.New("palette color index out of range") }
+	if !ggfnt.IsPremultRGBA(c) { return errors.New(nonPremultColorErr) }
+	colors[index] = c
 	return nil
 }
 
@@ -100,6 +142,15 @@ func (self *Font) AddPalette(name string, colors ...color.RGBA) error {
 
 // --- shared helpers ---
 
+const nonPremultColorErr = "palette colors must be alpha-premultiplied (RGB values can't exceed alpha)"
+
+func checkPremultColors(colors []color.RGBA) error {
+	for _, rgba := range colors {
+		if !ggfnt.IsPremultRGBA(rgba) { return errors.New(nonPremultColorErr) }
+	}
+	return nil
+}
+
 func (self *Font) checkColorSectionNameCollision(name string) error {
 	for index, _ := range self.dyes {
 		if self.dyes[index].name == name {
@@ -114,6 +165,20 @@ func (self *Font) checkColorSectionNameCollision(name string) error {
 	return nil
 }
 
+// Returns an error if a new color section with the given name
+// and number of color indices can't be added to the font.
+func (self *Font) checkColorSectionAddition(name string, numIndices int) error {
+	err := self.checkColorSectionNameCollision(name)
+	if err != nil { return err }
+	if len(self.dyes) + len(self.palettes) >= 255 {
+		return errors.New("font can't have more than 255 color sections")
+	}
+	if self.getColorIndexCount() + numIndices > 255 {
+		return errors.New("font colors can't exceed 255 indices")
+	}
+	return nil
+}
+
 func (self *Font) getColorIndexCount() int {
 	var count int
 	for index, _ := range self.dyes {
@@ -0,0 +1,161 @@
+package builder
+
+import "image"
+import "image/color"
+import "errors"
+import "fmt"
+
+// Splits the given paletted sheet into a grid of cells of the given size
+// and adds each cell as a new glyph, in row-major order. The glyph origin
+// is placed at (originX, originY) relative to each cell's top-left corner.
+//
+// The sheet palette must match the font's colors: fully transparent
+// entries are mapped to 0, and any other entry must be exactly equal to
+// one of the font's dye alphas (as white with the given alpha) or palette
+// colors, which will be mapped to the corresponding color index. Colors
+// must be defined on the font before calling this method.
+//
+// Returns the UIDs of the added glyphs. If an error happens midway, the
+// glyphs added up to that point are kept and their UIDs still returned.
+func (self *Font) AddColoredGlyphsFromGrid(sheet *image.Paletted, cellWidth, cellHeight, originX, originY int) ([]uint64, error) {
+	if cellWidth <= 0 || cellHeight <= 0 { return nil, errors.New("grid cell sizes must be strictly positive") }
+	bounds := sheet.Bounds()
+	if bounds.Dx() % cellWidth != 0 || bounds.Dy() % cellHeight != 0 {
+		return nil, errors.New("sheet size is not a multiple of the grid cell size")
+	}
+
+	// map sheet palette indices to font color indices
+	indexMapping, err := self.mapPaletteToColorIndices(sheet.Palette)
+	if err != nil { return nil, err }
+
+	// add glyphs
+	var uids []uint64
+	for cellY := bounds.Min.Y; cellY < bounds.Max.Y; cellY += cellHeight {
+		for cellX := bounds.Min.X; cellX < bounds.Max.X; cellX += cellWidth {
+			glyphMask := image.NewAlpha(image.Rect(-originX, -originY, cellWidth - originX, cellHeight - originY))
+			for y := 0; y < cellHeight; y++ {
+				for x := 0; x < cellWidth; x++ {
+					paletteIndex := sheet.ColorIndexAt(cellX + x, cellY + y)
+					if int(paletteIndex) >= len(indexMapping) {
+						return uids, errors.New("sheet uses a color index outside its palette")
+					}
+					value := indexMapping[paletteIndex]
+					if value == 0 { continue }
+					glyphMask.SetAlpha(x - originX, y - originY, color.Alpha{ value })
+				}
+			}
+			uid, err := self.AddGlyph(glyphMask)
+			if err != nil { return uids, err }
+			uids = append(uids, uid)
+		}
+	}
+
+	return uids, nil
+}
+
+// Like [Font.AddColoredGlyphsFromGrid](), but accepting any image type and
+// mapping each added glyph to the rune at the same cell position in runes.
+//
+// Each pixel color must be exactly equal to one of the font's dye alphas
+// (as white with the given alpha) or palette colors, which will be mapped
+// to the corresponding color index. White pixels can also use any alpha
+// not assigned to a color section, which is then used as the color index
+// directly, as [ggfnt.FontColor.ResolveColor]() does on the way back. Any
+// other color is rejected with an error.
+//
+// Cells without any visible pixels are skipped along with their runes, so
+// blank glyphs like spaces have to be added with [Font.AddBlankGlyph]().
+// Cells beyond len(runes) are ignored.
+//
+// Returns the UIDs of the added glyphs. If an error happens midway, the
+// glyphs added up to that point are kept and their UIDs still returned.
+func (self *Font) ImportGlyphSheet(sheet image.Image, cellWidth, cellHeight, originX, originY int, runes []rune) ([]uint64, error) {
+	if cellWidth <= 0 || cellHeight <= 0 { return nil, errors.New("grid cell sizes must be strictly positive") }
+	bounds := sheet.Bounds()
+	if bounds.Dx() % cellWidth != 0 || bounds.Dy() % cellHeight != 0 {
+		return nil, errors.New("sheet size is not a multiple of the grid cell size")
+	}
+	cellsPerRow := bounds.Dx()/cellWidth
+	numCells := cellsPerRow*(bounds.Dy()/cellHeight)
+	if len(runes) > numCells { return nil, errors.New("more runes given than grid cells") }
+	for _, codePoint := range runes {
+		if codePoint < ' ' { return nil, errors.New("can't map code points before ' ' (space)") }
+	}
+
+	// map font colors to their color indices
+	fontColors := self.getColorIndexRGBAs()
+	colorIndices := make(map[color.RGBA]uint8, len(fontColors))
+	for n, fontColor := range fontColors {
+		_, found := colorIndices[fontColor]
+		if !found { colorIndices[fontColor] = uint8(255 - n) }
+	}
+
+	var uids []uint64
+	for i, codePoint := range runes {
+		cellX := bounds.Min.X + (i % cellsPerRow)*cellWidth
+		cellY := bounds.Min.Y + (i / cellsPerRow)*cellHeight
+		glyphMask := image.NewAlpha(image.Rect(-originX, -originY, cellWidth - originX, cellHeight - originY))
+		var empty bool = true
+		for y := 0; y < cellHeight; y++ {
+			for x := 0; x < cellWidth; x++ {
+				rgba := color.RGBAModel.Convert(sheet.At(cellX + x, cellY + y)).(color.RGBA)
+				if rgba.A == 0 { continue }
+				index, found := colorIndices[rgba]
+				if !found {
+					isWhite := (rgba.R == rgba.A && rgba.G == rgba.A && rgba.B == rgba.A)
+					if !isWhite || int(rgba.A) > 255 - len(fontColors) {
+						return uids, fmt.Errorf("sheet color %v at (%d, %d) is not defined in the font", rgba, cellX + x, cellY + y)
+					}
+					index = rgba.A
+				}
+				glyphMask.SetAlpha(x - originX, y - originY, color.Alpha{ index })
+				empty = false
+			}
+		}
+		if empty { continue }
+
+		uid, err := self.AddGlyph(glyphMask)
+		if err != nil { return uids, err }
+		uids = append(uids, uid)
+		err = self.Map(codePoint, uid)
+		if err != nil { return uids, err }
+	}
+
+	return uids, nil
+}
+
+func (self *Font) mapPaletteToColorIndices(palette color.Palette) ([]uint8, error) {
+	if len(palette) > 256 { panic(brokenCode) }
+	fontColors := self.getColorIndexRGBAs()
+	indexMapping := make([]uint8, len(palette))
+	for i, clr := range palette {
+		rgba := color.RGBAModel.Convert(clr).(color.RGBA)
+		if rgba.A == 0 { continue } // transparent, keep index 0
+
+		found := false
+		for n, fontColor := range fontColors {
+			if fontColor == rgba {
+				indexMapping[i] = uint8(255 - n)
+				found = true
+				break
+			}
+		}
+		if !found { return nil, errors.New("sheet palette includes colors not defined in the font") }
+	}
+	return indexMapping, nil
+}
+
+// Returns the colors of the font, starting from color index 255 and
+// going downwards. Dye alphas are converted to white with that alpha.
+func (self *Font) getColorIndexRGBAs() []color.RGBA {
+	colors := make([]color.RGBA, 0, self.getColorIndexCount())
+	for index, _ := range self.dyes {
+		for _, alpha := range self.dyes[index].alphas {
+			colors = append(colors, color.RGBA{ alpha, alpha, alpha, alpha })
+		}
+	}
+	for index, _ := range self.palettes {
+		colors = append(colors, self.palettes[index].colors...)
+	}
+	return colors
+}
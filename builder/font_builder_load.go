@@ -0,0 +1,278 @@
+package builder
+
+import "image"
+import "strings"
+import "image/color"
+
+import "github.com/tinne26/ggfnt"
+import "github.com/tinne26/ggfnt/internal"
+
+// Helpers for [NewFrom](). The font is assumed to be valid, as returned
+// by [ggfnt.Parse]() or [Font.Build](), so malformed data will panic.
+// All strings are cloned, as the font getters return unsafe strings.
+
+func (self *Font) loadHeader(font *ggfnt.Font) {
+	header := font.Header()
+	self.fontID = header.ID()
+	self.versionMajor = header.VersionMajor()
+	self.versionMinor = header.VersionMinor()
+	self.firstVersionDate = header.FirstVersionDate()
+	self.majorVersionDate = header.MajorVersionDate()
+	self.minorVersionDate = header.MinorVersionDate()
+	self.fontName = strings.Clone(header.Name())
+	self.fontFamily = strings.Clone(header.Family())
+	self.fontAuthor = strings.Clone(header.Author())
+	self.fontAbout = strings.Clone(header.About())
+}
+
+func (self *Font) loadMetrics(font *ggfnt.Font) {
+	metrics := font.Metrics()
+	self.hasVertLayout = metrics.HasVertLayout()
+	self.monoWidth = metrics.MonoWidth()
+	self.ascent = metrics.Ascent()
+	self.extraAscent = metrics.ExtraAscent()
+	self.descent = metrics.Descent()
+	self.extraDescent = metrics.ExtraDescent()
+	self.uppercaseAscent = metrics.UppercaseAscent()
+	self.midlineAscent = metrics.MidlineAscent()
+	self.horzInterspacing = metrics.HorzInterspacing()
+	self.vertInterspacing = metrics.VertInterspacing()
+	self.lineGap = metrics.LineGap()
+	self.vertLineWidth = metrics.VertLineWidth()
+	self.vertLineGap = metrics.VertLineGap()
+}
+
+func (self *Font) loadColors(font *ggfnt.Font) {
+	colors := font.Color()
+	colors.EachDye(func(key ggfnt.DyeKey, name string) {
+		dye := dyeSection{ name: strings.Clone(name) }
+		colors.EachDyeAlpha(key, func(alpha uint8) {
+			dye.alphas = append(dye.alphas, alpha)
+		})
+		self.dyes = append(self.dyes, dye)
+	})
+	colors.EachPalette(func(key ggfnt.PaletteKey, name string) {
+		palette := paletteSection{ name: strings.Clone(name) }
+		colors.EachPaletteColor(key, func(rgba color.RGBA) {
+			palette.colors = append(palette.colors, rgba)
+		})
+		self.palettes = append(self.palettes, palette)
+	})
+}
+
+// Glyph UIDs are returned in glyph index order.
+func (self *Font) loadGlyphs(font *ggfnt.Font) []uint64 {
+	glyphs := font.Glyphs()
+	numGlyphs := glyphs.Count()
+	for i := uint16(0); i < numGlyphs; i++ {
+		glyphUID, err := self.newGlyphUID()
+		if err != nil { panic(err) }
+		glyphMask := glyphs.RasterizeMask(ggfnt.GlyphIndex(i))
+		if glyphMask == nil { glyphMask = image.NewAlpha(image.Rectangle{}) }
+		self.glyphData[glyphUID] = &glyphData{
+			Placement: glyphs.Placement(ggfnt.GlyphIndex(i)),
+			Mask: glyphMask,
+		}
+		self.glyphOrder = append(self.glyphOrder, glyphUID)
+	}
+
+	glyphs.EachNamed(func(glyphIndex ggfnt.GlyphIndex, name string) {
+		self.glyphData[self.glyphOrder[glyphIndex]].Name = strings.Clone(name)
+	})
+	return self.glyphOrder
+}
+
+func (self *Font) loadSettings(font *ggfnt.Font) {
+	settings := font.Settings()
+	settings.Each(func(key ggfnt.SettingKey, name string) {
+		entry := settingEntry{ Name: strings.Clone(name), InitValue: settings.GetInitValue(key) }
+		numOptions := settings.GetNumOptions(key)
+		for option := uint8(0); option < numOptions; option++ {
+			entry.Options = append(entry.Options, strings.Clone(settings.GetOptionName(key, option)))
+		}
+		self.settings = append(self.settings, entry)
+	})
+}
+
+func (self *Font) loadMapping(font *ggfnt.Font, glyphUIDs []uint64) {
+	// mapping switches
+	numSwitches := uint32(font.Mapping().NumSwitchTypes())
+	offsetToSwitchesData := font.OffsetToMappingSwitches + 1 + (numSwitches << 1)
+	var startOffset uint32
+	for i := uint32(0); i < numSwitches; i++ {
+		endOffset := uint32(internal.DecodeUint16LE(font.Data[font.OffsetToMappingSwitches + 1 + (i << 1) : ]))
+		settings := make([]uint8, endOffset - startOffset)
+		copy(settings, font.Data[offsetToSwitchesData + startOffset : offsetToSwitchesData + endOffset])
+		self.mappingSwitches = append(self.mappingSwitches, mappingSwitchEntry{ Settings: settings })
+		startOffset = endOffset
+	}
+
+	// main mapping
+	numEntries := uint32(font.Mapping().NumEntries())
+	offsetToCodePoints := font.OffsetToMapping + 2
+	offsetToEndOffsets := offsetToCodePoints + (numEntries << 2)
+	offsetToMappingData := offsetToEndOffsets + (numEntries << 1) + numEntries
+	startOffset = 0
+	for i := uint32(0); i < numEntries; i++ {
+		codePoint := rune(int32(internal.DecodeUint32LE(font.Data[offsetToCodePoints + (i << 2) : ])))
+		endOffset := internal.DecodeUint24LE(font.Data[offsetToEndOffsets + (i << 1) + i : ])
+		data := font.Data[offsetToMappingData + startOffset : offsetToMappingData + endOffset]
+		self.runeMapping[codePoint] = decodeMappingEntry(data, glyphUIDs)
+		startOffset = endOffset
+	}
+}
+
+func decodeMappingEntry(data []byte, glyphUIDs []uint64) mappingEntry {
+	entry := mappingEntry{ SwitchType: data[0] }
+	if entry.SwitchType == 255 { // inconditional mapping
+		glyphUID := glyphUIDs[internal.DecodeUint16LE(data[1 : ])]
+		entry.SwitchCases = []mappingGroup{ mappingGroup{ Glyphs: []uint64{glyphUID} } }
+		return entry
+	}
+
+	index := uint32(1)
+	for index < uint32(len(data)) {
+		decoded := internal.DecodeMappingGroup(data, index)
+		index = decoded.EndOffset()
+		group := mappingGroup{ AnimationFlags: ggfnt.AnimationFlags(decoded.AnimFlags) }
+		for n := uint8(0); n < decoded.Size; n++ {
+			group.Glyphs = append(group.Glyphs, glyphUIDs[decoded.Glyph(data, n)])
+		}
+		entry.SwitchCases = append(entry.SwitchCases, group)
+	}
+	if index != uint32(len(data)) { panic(invalidFontData) } // discretionary assertion
+	return entry
+}
+
+func (self *Font) loadRewrites(font *ggfnt.Font, glyphUIDs []uint64) {
+	rewrites := font.Rewrites()
+
+	// conditions
+	numConditions := uint32(rewrites.NumConditions())
+	offsetToConditionsData := font.OffsetToRewriteConditions + 1 + (numConditions << 1)
+	var startOffset uint32
+	for i := uint32(0); i < numConditions; i++ {
+		endOffset := uint32(internal.DecodeUint16LE(font.Data[font.OffsetToRewriteConditions + 1 + (i << 1) : ]))
+		data := make([]uint8, endOffset - startOffset)
+		copy(data, font.Data[offsetToConditionsData + startOffset : offsetToConditionsData + endOffset])
+		self.rewriteConditions = append(self.rewriteConditions, rewriteCondition{ data: data })
+		startOffset = endOffset
+	}
+
+	// utf8 sets
+	numUtf8Sets := rewrites.NumUTF8Sets()
+	for i := uint8(0); i < numUtf8Sets; i++ {
+		setUID, err := self.CreateRuneSet()
+		if err != nil { panic(err) }
+		runeSet := self.rewriteRuneSets[setUID]
+		data := rewrites.GetUtf8Set(i).Data
+		numRanges := int(data[0])
+		for n := 0; n < numRanges; n++ {
+			first := rune(int32(internal.DecodeUint32LE(data[1 + n*5 : ])))
+			runeSet.ranges = append(runeSet.ranges, runeRange{ first, first + rune(data[1 + n*5 + 4]) })
+		}
+		listIndex := 1 + numRanges*5
+		for n := 0; n < int(data[listIndex]); n++ {
+			runeSet.list = append(runeSet.list, rune(int32(internal.DecodeUint32LE(data[listIndex + 1 + n*4 : ]))))
+		}
+		self.rewriteRuneSets[setUID] = runeSet
+	}
+
+	// glyph sets
+	numGlyphSets := rewrites.NumGlyphSets()
+	for i := uint8(0); i < numGlyphSets; i++ {
+		setUID, err := self.CreateGlyphSet()
+		if err != nil { panic(err) }
+		glyphSet := self.rewriteGlyphSets[setUID]
+		set := rewrites.GetGlyphSet(i)
+		_ = set.EachRange(func(glyphRange ggfnt.GlyphRange) error {
+			glyphSet.ranges = append(glyphSet.ranges, uidRange{ glyphUIDs[glyphRange.First], glyphUIDs[glyphRange.Last] })
+			return nil
+		})
+		_ = set.EachListGlyph(func(glyphIndex ggfnt.GlyphIndex) error {
+			glyphSet.list = append(glyphSet.list, glyphUIDs[glyphIndex])
+			return nil
+		})
+		self.rewriteGlyphSets[setUID] = glyphSet
+	}
+
+	// utf8 rules
+	numUtf8Rules := rewrites.NumUTF8Rules()
+	for i := uint16(0); i < numUtf8Rules; i++ {
+		data := rewrites.GetUtf8Rule(i).Data
+		rule := utf8RewriteRule{ condition: data[0], headLen: data[1], bodyLen: data[2], tailLen: data[3] }
+		index := 5
+		for n := 0; n < int(data[4]); n++ {
+			rule.output = append(rule.output, rune(int32(internal.DecodeUint32LE(data[index : ]))))
+			index += 4
+		}
+		index = decodeRuleInput(data, index, &rule.inElemsAreGroups, 4, func(group uint8) {
+			rule.inGroups = append(rule.inGroups, self.runeSetsOrder[group])
+		}, func(elem []byte) {
+			rule.inRunes = append(rule.inRunes, rune(int32(internal.DecodeUint32LE(elem))))
+		})
+		if index != len(data) { panic(invalidFontData) } // discretionary assertion
+		self.utf8Rules = append(self.utf8Rules, rule)
+	}
+
+	// glyph rules
+	numGlyphRules := rewrites.NumGlyphRules()
+	for i := uint16(0); i < numGlyphRules; i++ {
+		data := rewrites.GetGlyphRule(i).Data
+		rule := glyphRewriteRule{ condition: data[0], headLen: data[1], bodyLen: data[2], tailLen: data[3] }
+		index := 5
+		for n := 0; n < int(data[4]); n++ {
+			rule.output = append(rule.output, glyphUIDs[internal.DecodeUint16LE(data[index : ])])
+			index += 2
+		}
+		index = decodeRuleInput(data, index, &rule.inElemsAreGroups, 2, func(group uint8) {
+			rule.inGroups = append(rule.inGroups, self.glyphSetsOrder[group])
+		}, func(elem []byte) {
+			rule.inGlyphs = append(rule.inGlyphs, glyphUIDs[internal.DecodeUint16LE(elem)])
+		})
+		if index != len(data) { panic(invalidFontData) } // discretionary assertion
+		self.glyphRules = append(self.glyphRules, rule)
+	}
+}
+
+// Decodes the head, body and tail blocks of a rewrite rule starting at
+// the given index, which must point right after the rule's output. Each
+// block is made of fragments with groups first and elements afterwards,
+// or a single zero byte if the block is empty. Returns the index after
+// the last block.
+func decodeRuleInput(data []byte, index int, areGroups *internal.BoolList, elemSize int, group func(uint8), elem func([]byte)) int {
+	for _, blockLen := range [3]uint8{data[1], data[2], data[3]} {
+		if blockLen == 0 {
+			index += 1
+			continue
+		}
+
+		var decoded int
+		for decoded < int(blockLen) {
+			numGroups, numElems := int(data[index] >> 4), int(data[index] & 0x0F)
+			index += 1
+			for n := 0; n < numGroups; n++ {
+				areGroups.Push(true)
+				group(data[index])
+				index += 1
+			}
+			for n := 0; n < numElems; n++ {
+				areGroups.Push(false)
+				elem(data[index : index + elemSize])
+				index += elemSize
+			}
+			decoded += numGroups + numElems
+		}
+	}
+	return index
+}
+
+func (self *Font) loadKerning(font *ggfnt.Font, glyphUIDs []uint64) {
+	kerning := font.Kerning()
+	kerning.EachPair(func(prev, curr ggfnt.GlyphIndex, kern int8) {
+		self.SetKerningPair(glyphUIDs[prev], glyphUIDs[curr], kern)
+	})
+	kerning.EachVertPair(func(prev, curr ggfnt.GlyphIndex, kern int8) {
+		self.SetVertKerningPair(glyphUIDs[prev], glyphUIDs[curr], kern)
+	})
+}
@@ -1,8 +1,8 @@
 package builder
 
-import "slices"
 import "errors"
 import "fmt"
+import "slices"
 
 import "github.com/tinne26/ggfnt"
 import "github.com/tinne26/ggfnt/internal"
@@ -25,6 +25,9 @@ func (self *Font) AddSwitch(settings ...ggfnt.SettingKey) (uint8, error) {
 		if setting >= ggfnt.SettingKey(numSettings) {
 			return 0, errors.New("mapping switch contains undefined setting")
 		}
+		if len(self.settings[setting].Options) == 0 {
+			return 0, errors.New("mapping switch can't contain settings without options")
+		}
 		_, alreadyAdded := repeated[setting]
 		if alreadyAdded {
 			return 0, errors.New("mapping switch can't contain repeated settings")
@@ -38,6 +41,62 @@ func (self *Font) AddSwitch(settings ...ggfnt.SettingKey) (uint8, error) {
 	return key, nil
 }
 
+// Same as [Font.AddSwitch](), but with the settings given as raw
+// setting keys. Returns the key of the new mapping switch.
+func (self *Font) AddMappingSwitch(settings ...uint8) (uint8, error) {
+	settingKeys := make([]ggfnt.SettingKey, len(settings))
+	for i, setting := range settings {
+		settingKeys[i] = ggfnt.SettingKey(setting)
+	}
+	return self.AddSwitch(settingKeys...)
+}
+
+// Deletes the given mapping switch. Deletion is rejected with an error if
+// any code point is still mapped with the switch, as the mapping cases
+// would become meaningless. Switches with higher keys are shifted down
+// by one, and the mappings using them are updated accordingly, so any
+// switch keys stored externally must be updated too.
+func (self *Font) DeleteMappingSwitch(key uint8) error {
+	if int(key) >= len(self.mappingSwitches) {
+		return errors.New("can't delete undefined mapping switch")
+	}
+	for codePoint, mapping := range self.runeMapping {
+		if mapping.SwitchType == key {
+			return fmt.Errorf("can't delete mapping switch %d while '%c' (U+%04X) is mapped with it", key, codePoint, codePoint)
+		}
+	}
+
+	self.mappingSwitches = slices.Delete(self.mappingSwitches, int(key), int(key) + 1)
+	for codePoint, mapping := range self.runeMapping {
+		if mapping.SwitchType < 254 && mapping.SwitchType > key {
+			mapping.SwitchType -= 1
+			self.runeMapping[codePoint] = mapping
+		}
+	}
+	return nil
+}
+
+// Swaps the keys of two mapping switches, updating the mappings that
+// use them so they keep resolving to the same glyphs.
+func (self *Font) SwapMappingSwitches(a, b uint8) error {
+	if int(a) >= len(self.mappingSwitches) || int(b) >= len(self.mappingSwitches) {
+		return errors.New("can't swap undefined mapping switch")
+	}
+	if a == b { return nil }
+
+	self.mappingSwitches[a], self.mappingSwitches[b] = self.mappingSwitches[b], self.mappingSwitches[a]
+	for codePoint, mapping := range self.runeMapping {
+		switch mapping.SwitchType {
+		case a: mapping.SwitchType = b
+		case b: mapping.SwitchType = a
+		default:
+			continue
+		}
+		self.runeMapping[codePoint] = mapping
+	}
+	return nil
+}
+
 type mappingEntry struct {
 	SwitchType uint8
 	SwitchCases []mappingGroup
@@ -99,8 +158,8 @@ func (self *mappingGroup) AppendTo(data []byte, glyphLookup map[uint64]uint16, s
 		scratchBuffer[i] = glyphIndex
 	}
 
-	// sort scratch buffer, makes it easier to see if glyphs are consecutive
-	slices.Sort(scratchBuffer)
+	// glyph order must be preserved, as groups are often animation frames,
+	// so ranges can only be used when the glyphs are already consecutive
 	if isContinuousSlice(scratchBuffer) {
 		data = append(data, 0b1000_0000 | uint8(len(self.Glyphs) - 1))
 		data = append(data, uint8(self.AnimationFlags))
@@ -186,6 +245,8 @@ func (self *Font) MapWithSwitchSingles(codePoint rune, mapSwitch uint8, glyphUID
 	if len(glyphUIDs) != numSwitchCases {
 		return fmt.Errorf("switch %d expects %d glyph groups, but received %d", mapSwitch, numSwitchCases, len(glyphUIDs))
 	}
+	err := self.validateMapGlyphs(codePoint, glyphUIDs...)
+	if err != nil { return err }
 	
 	cases := make([]mappingGroup, 0, len(glyphUIDs))
 	for _, glyphUID := range glyphUIDs {
@@ -198,7 +259,9 @@ func (self *Font) MapWithSwitchSingles(codePoint rune, mapSwitch uint8, glyphUID
 	return nil
 }
 
-// TODO: I also need removal, edit (modify) and get. messy.
+// Maps the given code point to a different glyph group for each case of
+// the given mapping switch. Single glyph groups don't take animation flags,
+// while each multi-glyph group consumes one from animFlags, in order.
 func (self *Font) MapWithSwitch(codePoint rune, mapSwitch uint8, glyphUIDs [][]uint64, animFlags []ggfnt.AnimationFlags) error {
 	// basic validation
 	if codePoint < ' ' {
@@ -214,13 +277,16 @@ func (self *Font) MapWithSwitch(codePoint rune, mapSwitch uint8, glyphUIDs [][]u
 	if len(glyphUIDs) != numSwitchCases {
 		return fmt.Errorf("switch %d expects %d glyph groups, but received %d", mapSwitch, numSwitchCases, len(glyphUIDs))
 	}
-	finalAnimFlags := make([]ggfnt.AnimationFlags, 0, len(glyphUIDs))
+	finalAnimFlags := make([]ggfnt.AnimationFlags, len(glyphUIDs)) // single glyph groups keep 0
 	animFlagIndex := 0
-	for _, group := range glyphUIDs {
-		if len(group) == 0 { panic("glyph groups can't be empty") }
+	for i, group := range glyphUIDs {
+		if len(group) == 0 { return errors.New("glyph groups can't be empty") }
+		if len(group) > 128 { return errors.New("glyph groups can't exceed 128 glyphs") }
+		err := self.validateMapGlyphs(codePoint, group...)
+		if err != nil { return err }
 		if len(group) == 1 { continue }
 		if len(animFlags) <= animFlagIndex { return errors.New("not enough animation flags for all multi-glyph groups") }
-		finalAnimFlags = append(finalAnimFlags, animFlags[animFlagIndex])
+		finalAnimFlags[i] = animFlags[animFlagIndex]
 		animFlagIndex += 1
 	}
 	if animFlagIndex != len(animFlags) {
@@ -229,7 +295,7 @@ func (self *Font) MapWithSwitch(codePoint rune, mapSwitch uint8, glyphUIDs [][]u
 	
 	cases := make([]mappingGroup, 0, len(glyphUIDs))
 	for i, group := range glyphUIDs {
-		cases = append(cases, mappingGroup{ Glyphs: group, AnimationFlags: finalAnimFlags[i] })
+		cases = append(cases, mappingGroup{ Glyphs: slices.Clone(group), AnimationFlags: finalAnimFlags[i] })
 	}
 	self.runeMapping[codePoint] = mappingEntry{
 		SwitchType: mapSwitch,
@@ -0,0 +1,93 @@
+package builder
+
+import "fmt"
+import "errors"
+import "encoding/json"
+
+import "github.com/tinne26/ggfnt"
+
+// Subset of the [ggfnt.Font.MetadataJSON]() schema that can be applied
+// to a builder. Nil fields were not present in the document.
+type jsonHeaderMetadata struct {
+	Name *string `json:"name"`
+	Family *string `json:"family"`
+	Author *string `json:"author"`
+	About *string `json:"about"`
+	VersionMajor *uint16 `json:"versionMajor"`
+	VersionMinor *uint16 `json:"versionMinor"`
+	FirstVersionDate *string `json:"firstVersionDate"`
+	MajorVersionDate *string `json:"majorVersionDate"`
+	MinorVersionDate *string `json:"minorVersionDate"`
+}
+
+// Sets the name, family, author, about, version and dates from a JSON
+// document using the same schema as [ggfnt.Font.MetadataJSON](). Fields
+// not present in the document are left unchanged, and any other fields
+// (metrics, glyphs, settings, etc.) are ignored.
+//
+// Each field is validated through the regular setters. If any of them
+// fails, the header is left as it was before the call.
+func (self *Font) ApplyMetadataJSON(data []byte) error {
+	var metadata jsonHeaderMetadata
+	err := json.Unmarshal(data, &metadata)
+	if err != nil { return err }
+
+	// keep a copy of the header to restore it on failure
+	backup := *self
+	err = self.applyHeaderMetadata(&metadata)
+	if err != nil {
+		self.fontName, self.fontFamily = backup.fontName, backup.fontFamily
+		self.fontAuthor, self.fontAbout = backup.fontAuthor, backup.fontAbout
+		self.versionMajor, self.versionMinor = backup.versionMajor, backup.versionMinor
+		self.firstVersionDate = backup.firstVersionDate
+		self.majorVersionDate = backup.majorVersionDate
+		self.minorVersionDate = backup.minorVersionDate
+	}
+	return err
+}
+
+func (self *Font) applyHeaderMetadata(metadata *jsonHeaderMetadata) error {
+	var err error
+	if metadata.Name != nil {
+		err = self.SetName(*metadata.Name)
+		if err != nil { return err }
+	}
+	if metadata.Family != nil {
+		err = self.SetFamily(*metadata.Family)
+		if err != nil { return err }
+	}
+	if metadata.Author != nil {
+		err = self.SetAuthor(*metadata.Author)
+		if err != nil { return err }
+	}
+	if metadata.About != nil {
+		err = self.SetAbout(*metadata.About)
+		if err != nil { return err }
+	}
+	if metadata.VersionMajor != nil { self.versionMajor = *metadata.VersionMajor }
+	if metadata.VersionMinor != nil { self.versionMinor = *metadata.VersionMinor }
+
+	dateFields := [3]*string{ metadata.FirstVersionDate, metadata.MajorVersionDate, metadata.MinorVersionDate }
+	dateSetters := [3]func(ggfnt.Date) error{ self.SetFirstVerDate, self.SetMajorVerDate, self.SetMinorVerDate }
+	for i, dateStr := range dateFields {
+		if dateStr == nil { continue }
+		date, err := parseMetadataDate(*dateStr)
+		if err != nil { return err }
+		err = dateSetters[i](date)
+		if err != nil { return err }
+	}
+	return nil
+}
+
+// Parses dates in "YYYY-MM-DD" format.
+func parseMetadataDate(str string) (ggfnt.Date, error) {
+	var year, month, day int
+	n, err := fmt.Sscanf(str, "%04d-%02d-%02d", &year, &month, &day)
+	if err != nil || n != 3 || len(str) != 10 {
+		return ggfnt.Date{}, errors.New("invalid date '" + str + "', expected YYYY-MM-DD format")
+	}
+	if year < 0 || month < 0 || day < 0 || year > 65535 || month > 255 || day > 255 {
+		return ggfnt.Date{}, ErrInvalidDate
+	}
+	return ggfnt.Date{ Year: uint16(year), Month: uint8(month), Day: uint8(day) }, nil
+}
@@ -1,6 +1,8 @@
 package builder
 
 import "fmt"
+import "cmp"
+import "slices"
 import "image"
 import "errors"
 import "unicode/utf8"
@@ -28,7 +30,6 @@ func (self *Font) SetFirstVerDate(date ggfnt.Date) error {
 	if !date.IsValid() { return ErrInvalidDate }
 	self.firstVersionDate = date
 	return nil
-	// TODO: global error checking method for dates consistency at the end
 }
 
 func (self *Font) SetMajorVerDate(date ggfnt.Date) error {
@@ -43,6 +44,22 @@ func (self *Font) SetMinorVerDate(date ggfnt.Date) error {
 	return nil
 }
 
+// Sets all the version dates at once. Unlike the individual setters,
+// this also checks that the dates are ordered as first <= major <= minor,
+// and leaves the current dates untouched if that's not the case. See
+// also [Font.CheckDateConsistency]().
+func (self *Font) SetVersionDates(first, major, minor ggfnt.Date) error {
+	if !first.IsValid() || !major.IsValid() || !minor.IsValid() { return ErrInvalidDate }
+	prevFirst, prevMajor, prevMinor := self.firstVersionDate, self.majorVersionDate, self.minorVersionDate
+	self.firstVersionDate, self.majorVersionDate, self.minorVersionDate = first, major, minor
+	err := self.CheckDateConsistency()
+	if err != nil {
+		self.firstVersionDate, self.majorVersionDate, self.minorVersionDate = prevFirst, prevMajor, prevMinor
+		return err
+	}
+	return nil
+}
+
 // Also updates the relevant dates.
 func (self *Font) RaiseMajorVersion() {
 	self.versionMajor += 1
@@ -68,15 +85,21 @@ func (self *Font) SetVersion(major, minor uint16) {
 
 func (self *Font) GetName() string { return self.fontName }
 func (self *Font) SetName(name string) error {
-	if len(name) > 255 { return errors.New("font name can't exceed 255 bytes") }
+	if len(name) > 255 { return fmt.Errorf("font name can't exceed 255 bytes (got %d)", len(name)) }
 	err := checkStringValidity(name)
 	if err != nil { return err }
 	self.fontName = name
 	return nil
 }
+
+// Like [Font.SetName](), but truncating the name on a rune boundary
+// if it exceeds the 255 bytes limit instead of returning an error.
+func (self *Font) SetNameTruncated(name string) error {
+	return self.SetName(truncateOnRuneBoundary(name, 255))
+}
 func (self *Font) GetFamily() string { return self.fontFamily }
 func (self *Font) SetFamily(name string) error {
-	if len(name) > 255 { return errors.New("family name can't exceed 255 bytes") }
+	if len(name) > 255 { return fmt.Errorf("family name can't exceed 255 bytes (got %d)", len(name)) }
 	err := checkStringValidity(name)
 	if err != nil { return err }
 	self.fontFamily = name
@@ -84,7 +107,7 @@ func (self *Font) SetFamily(name string) error {
 }
 func (self *Font) GetAuthor() string { return self.fontAuthor }
 func (self *Font) SetAuthor(name string) error {
-	if len(name) > 255 { return errors.New("author name can't exceed 255 bytes") }
+	if len(name) > 255 { return fmt.Errorf("author name can't exceed 255 bytes (got %d)", len(name)) }
 	err := checkStringValidity(name)
 	if err != nil { return err }
 	self.fontAuthor = name
@@ -92,13 +115,19 @@ func (self *Font) SetAuthor(name string) error {
 }
 func (self *Font) GetAbout() string { return self.fontAbout }
 func (self *Font) SetAbout(about string) error {
-	if len(about) > 65535 { return errors.New("font 'about' can't exceed 65535 bytes") }
+	if len(about) > 65535 { return fmt.Errorf("font 'about' can't exceed 65535 bytes (got %d)", len(about)) }
 	err := checkStringValidity(about)
 	if err != nil { return err }
 	self.fontAbout = about
 	return nil
 }
 
+// Like [Font.SetAbout](), but truncating the text on a rune boundary
+// if it exceeds the 65535 bytes limit instead of returning an error.
+func (self *Font) SetAboutTruncated(about string) error {
+	return self.SetAbout(truncateOnRuneBoundary(about, 65535))
+}
+
 // ---- metrics ----
 
 // Returns the error status of the metrics. If there are inconsistencies, an
@@ -115,10 +144,55 @@ func (self *Font) GetMetricsStatus() error {
 	return nil
 }
 
+// Returns warnings for metric configurations that are legal but
+// look suspicious, like extra ascents or descents that are as big as
+// the main ones. Like in the rest of the format, extra ascents and
+// descents are measured beyond the main ascent and descent, not as
+// part of them. Unlike [Font.GetMetricsStatus](), these warnings
+// don't prevent the font from being built.
+func (self *Font) MetricsWarnings() []string {
+	var warnings []string
+	if self.extraAscent > 0 && int(self.extraAscent)*2 > int(self.ascent) {
+		warnings = append(warnings, fmt.Sprintf("extra ascent (%d) is more than half the main ascent (%d)", self.extraAscent, self.ascent))
+	}
+	if self.extraDescent > 0 {
+		if self.extraDescent >= self.descent {
+			warnings = append(warnings, fmt.Sprintf("extra descent (%d) is not smaller than the main descent (%d)", self.extraDescent, self.descent))
+		} else if int(self.extraDescent)*2 > int(self.descent) {
+			warnings = append(warnings, fmt.Sprintf("extra descent (%d) is more than half the main descent (%d)", self.extraDescent, self.descent))
+		}
+	}
+	return warnings
+}
+
 func (self *Font) GetNumGlyphs() int { return len(self.glyphData) }
+
+// Enables or disables the vertical layout. When enabling it, glyphs
+// without vertical placement data get their top and bottom advances
+// set to the font's ascent and descent, and their horizontal center
+// set to the center of their drawn mask contents (or half their
+// advance if they are blank). When disabling it, the vertical
+// placement fields of all glyphs are reset to zero.
 func (self *Font) SetVertLayoutUsed(used bool) {
-	// TODO: unclear if I need to check or update anything
+	if used == self.hasVertLayout { return }
 	self.hasVertLayout = used
+	for _, glyphData := range self.glyphData {
+		placement := &glyphData.Placement
+		if !used {
+			placement.TopAdvance, placement.BottomAdvance, placement.HorzCenter = 0, 0, 0
+			continue
+		}
+		
+		if placement.TopAdvance != 0 || placement.BottomAdvance != 0 { continue }
+		placement.TopAdvance = self.ascent
+		placement.BottomAdvance = self.descent
+		rect := mask.ComputeRect(glyphData.Mask)
+		if rect.Empty() {
+			placement.HorzCenter = placement.Advance/2
+		} else {
+			placement.HorzCenter = uint8(max(0, min(255, rect.Min.X + rect.Dx()/2)))
+		}
+	}
 }
 func (self *Font) GetMonoWidth() uint8 { return self.monoWidth }
 func (self *Font) SetMonoWidth(width uint8) {
@@ -132,7 +206,6 @@ func (self *Font) GetExtraDescent() uint8 { return self.extraDescent }
 func (self *Font) GetUppercaseAscent() uint8 { return self.uppercaseAscent } // aka cap height
 func (self *Font) GetMidlineAscent() uint8 { return self.midlineAscent } // aka xheight
 
-// TODO: should I check for existing glyph collisions on any metrics that are set?
 func (self *Font) SetAscent(value uint8) { self.ascent = value }
 func (self *Font) SetExtraAscent(value uint8) { self.extraAscent = value }
 func (self *Font) SetDescent(value uint8) { self.descent = value }
@@ -173,52 +246,284 @@ func (self *Font) SetVertLineGap(value uint8) error {
 	return nil
 }
 
+// When strict glyph metrics are enabled, glyphs left out of bounds by
+// ascent, descent or monospacing width changes (see [Font.CheckGlyphMetricCollisions]())
+// are reported as errors by [Font.Validate]() instead of warnings.
+// Disabled by default, as editors may want to fix the glyphs afterwards.
+func (self *Font) SetStrictGlyphMetrics(strict bool) {
+	self.strictGlyphMetrics = strict
+}
+
+// Returns the UIDs of the glyphs whose masks don't fit within the current
+// ascent, descent and monospacing width, in glyph order. This can happen
+// when the metrics are modified after adding the glyphs.
+func (self *Font) CheckGlyphMetricCollisions() []uint64 {
+	return self.glyphBoxCollisions(self.glyphBox())
+}
+
+// Metrics that glyph masks must respect.
+type glyphBox struct {
+	ascent, extraAscent uint8
+	descent, extraDescent uint8
+	monoWidth uint8
+}
+
+func (self *Font) glyphBox() glyphBox {
+	return glyphBox{ self.ascent, self.extraAscent, self.descent, self.extraDescent, self.monoWidth }
+}
+
+// Returns an error if the given glyph mask rect doesn't fit the box.
+func (self *glyphBox) check(rect image.Rectangle) error {
+	if rect.Empty() { return nil }
+	if rect.Min.Y < 0 && -rect.Min.Y > int(self.ascent) + int(self.extraAscent) {
+		return errors.New("glyph exceeds font ascent")
+	}
+	if rect.Max.Y > 0 && rect.Max.Y > int(self.descent) + int(self.extraDescent) {
+		return errors.New("glyph exceeds font descent")
+	}
+	if self.monoWidth != 0 && (rect.Min.X < 0 || rect.Max.X > int(self.monoWidth)) {
+		return errors.New("glyph doesn't respect monospacing width")
+	}
+	// TODO: ok, monoHeight could actually be used to ensure that placement pre and
+	//       post offsets add to the relevant value. unclear how valuable that is
+	return nil
+}
+
+func (self *Font) glyphBoxCollisions(box glyphBox) []uint64 {
+	var collisions []uint64
+	for _, glyphUID := range self.glyphOrder {
+		err := box.check(mask.ComputeRect(self.glyphData[glyphUID].Mask))
+		if err != nil { collisions = append(collisions, glyphUID) }
+	}
+	return collisions
+}
+
+// Copies the ascents, descents, interspacings, line gap and vertical
+// layout metrics from the given font, which is useful to ensure that
+// related fonts (e.g. regular and bold variants) share metrics exactly.
+// The monospacing width is not copied, as it depends on the glyph
+// designs. Enabling or disabling the vertical layout also updates the
+// glyph placements, as described in [Font.SetVertLayoutUsed]().
+func (self *Font) CopyMetricsFrom(font *ggfnt.Font) error {
+	metrics := font.Metrics()
+	self.ascent, self.extraAscent = metrics.Ascent(), metrics.ExtraAscent()
+	self.descent, self.extraDescent = metrics.Descent(), metrics.ExtraDescent()
+	self.SetUppercaseAscent(metrics.UppercaseAscent())
+	self.SetMidlineAscent(metrics.MidlineAscent())
+	self.SetHorzInterspacing(metrics.HorzInterspacing())
+	self.SetVertInterspacing(metrics.VertInterspacing())
+	self.SetLineGap(metrics.LineGap())
+
+	self.SetVertLayoutUsed(metrics.HasVertLayout())
+	if !metrics.HasVertLayout() {
+		self.vertLineWidth, self.vertLineGap = 0, 0
+		return nil
+	}
+	err := self.SetVertLineWidth(metrics.VertLineWidth())
+	if err != nil { return err }
+	return self.SetVertLineGap(metrics.VertLineGap())
+}
+
 // ---- glyph data ----
 
 func (self *Font) AddGlyph(glyphMask *image.Alpha) (uint64, error) {
+	glyphUID, err := self.newGlyphUID()
+	if err != nil { return 0, err }
+	err = self.addGlyphWithUID(glyphUID, glyphMask)
+	if err != nil { return 0, err }
+	return glyphUID, nil
+}
+
+// Like [Font.AddGlyph](), but using an explicit glyph UID instead of a
+// random one. This is useful for import pipelines that want the same
+// source glyph to keep the same UID across rebuilds (e.g., by hashing
+// the source file name), so mappings and kerning remain stable.
+//
+// The UID must not be in use already, and it must have enough entropy
+// to make accidental collisions unlikely. Sequential or otherwise
+// structured values like 1, 2, 3 will be rejected.
+func (self *Font) AddGlyphWithUID(glyphUID uint64, glyphMask *image.Alpha) error {
+	if glyphUID == uint64(ggfnt.GlyphMissing) {
+		return errors.New("glyph UID can't match ggfnt.GlyphMissing")
+	}
+	_, alreadyExists := self.glyphData[glyphUID]
+	if alreadyExists { return errors.New("glyph UID already in use") }
+	if internal.LazyEntropyUint64(glyphUID) < internal.MinEntropyID {
+		return errors.New("glyph UID entropy too low")
+	}
+	return self.addGlyphWithUID(glyphUID, glyphMask)
+}
+
+func (self *Font) addGlyphWithUID(glyphUID uint64, glyphMask *image.Alpha) error {
+	if len(self.glyphData) >= ggfnt.MaxGlyphs {
+		return errors.New("reached font glyph count limit")
+	}
+
+	box := self.glyphBox()
+	err := box.check(mask.ComputeRect(glyphMask))
+	if err != nil { return err }
+
+	// vertical placement fields are left at zero without vertical
+	// layout, so SetVertLayoutUsed() can tell which glyphs to migrate
+	placement := ggfnt.GlyphPlacement{ Advance: uint8(min(255, glyphMask.Bounds().Dx())) }
+	if self.hasVertLayout {
+		placement.TopAdvance = self.ascent
+		placement.BottomAdvance = self.descent
+		placement.HorzCenter = uint8(min(255, glyphMask.Bounds().Dx()/2))
+	}
+	self.glyphData[glyphUID] = &glyphData{ Name: "", Placement: placement, Mask: glyphMask }
+	self.glyphOrder = append(self.glyphOrder, glyphUID)
+	return nil
+}
+
+// Adds a new glyph that uses the same mask as an existing glyph, but with
+// its own placement. Useful for combining marks and similar glyphs that
+// differ only in their positioning. The mask is shared, so modifying it
+// will affect both glyphs.
+func (self *Font) AddGlyphSharingMask(existingUID uint64, placement ggfnt.GlyphPlacement) (uint64, error) {
 	if len(self.glyphData) >= ggfnt.MaxGlyphs {
 		return 0, errors.New("reached font glyph count limit")
 	}
+	existing, found := self.glyphData[existingUID]
+	if !found { return 0, errors.New("glyph not found") }
 
-	rect := mask.ComputeRect(glyphMask)
-	if !rect.Empty() {
-		if rect.Min.Y < 0 && -rect.Min.Y > int(self.ascent) + int(self.extraAscent) {
-			return 0, errors.New("glyph exceeds font ascent")
-		}
-		if rect.Max.Y > 0 && rect.Max.Y > int(self.descent) + int(self.extraDescent) {
-			return 0, errors.New("glyph exceeds font descent")
-		}
-		if self.monoWidth != 0 && (rect.Min.X < 0 || rect.Max.X > int(self.monoWidth)) {
-			return 0, errors.New("glyph doesn't respect monospacing width")
-		}
-		// TODO: ok, monoHeight could actually be used to ensure that placement pre and
-		//       post offsets add to the relevant value. unclear how valuable that is
+	glyphUID, err := self.newGlyphUID()
+	if err != nil { return 0, err }
+	self.glyphData[glyphUID] = &glyphData{ Name: "", Placement: placement, Mask: existing.Mask }
+	self.glyphOrder = append(self.glyphOrder, glyphUID)
+	return glyphUID, nil
+}
+
+// Adds a new glyph with the contents of the given source glyph and a
+// one pixel outline of the given color index around them. The outlined
+// mask is shifted one pixel to the right and the advance is increased
+// by two, so outlines don't overlap when the outlined glyphs are used
+// in place of the originals (e.g., through a style setting). The outline
+// must still respect the font's metrics, or an error will be returned.
+func (self *Font) GenerateOutlinedGlyph(srcUID uint64, color uint8) (uint64, error) {
+	if color == 0 { return 0, errors.New("outline color index can't be 0") }
+	src, found := self.glyphData[srcUID]
+	if !found { return 0, errors.New("glyph not found") }
+	if src.Placement.Advance > 253 {
+		return 0, errors.New("outlined glyph advance would exceed 255")
 	}
 
+	outlined := mask.Outline(src.Mask, color)
+	outlined.Rect = outlined.Rect.Add(image.Pt(1, 0))
+	glyphUID, err := self.AddGlyph(outlined)
+	if err != nil { return 0, err }
+	placement := src.Placement
+	placement.Advance += 2
+	if self.hasVertLayout && placement.HorzCenter < 255 { placement.HorzCenter += 1 }
+	self.glyphData[glyphUID].Placement = placement
+	return glyphUID, nil
+}
+
+func (self *Font) newGlyphUID() (uint64, error) {
 	const MaxRerolls = 4
 	for i := 1; i <= MaxRerolls; i++ {
 		glyphUID, err := internal.CryptoRandUint64()
 		if err != nil { return 0, err } // I'm not sure this can ever happen
 		_, alreadyExists := self.glyphData[glyphUID]
 		if !alreadyExists && glyphUID != uint64(ggfnt.GlyphMissing) {
-			self.glyphData[glyphUID] = &glyphData{
-				Name: "",
-				Placement: ggfnt.GlyphPlacement{
-					Advance: uint8(min(255, glyphMask.Bounds().Dx())),
-					TopAdvance: self.ascent,
-					BottomAdvance: self.descent,
-					HorzCenter: uint8(min(255, glyphMask.Bounds().Dx()/2)),
-				},
-				Mask: glyphMask,
-			}
-			self.glyphOrder = append(self.glyphOrder, glyphUID)
 			return glyphUID, nil
 		}
 	}
-
 	return 0, errors.New("failed to generate unique glyph UID")
 }
 
+// Adds a glyph without any ink, like a space, with the given advance.
+func (self *Font) AddBlankGlyph(advance uint8) (uint64, error) {
+	if self.monoWidth != 0 && advance != self.monoWidth {
+		return 0, errors.New("blank glyph advance doesn't respect monospacing width")
+	}
+	glyphUID, err := self.AddGlyph(image.NewAlpha(image.Rectangle{}))
+	if err != nil { return 0, err }
+	glyphData := self.glyphData[glyphUID]
+	glyphData.Placement.Advance = advance
+	glyphData.Placement.HorzCenter = advance/2
+	return glyphUID, nil
+}
+
+// Returns a copy of the current glyph UIDs, in the order that
+// will determine their final glyph indices on [Font.Build]().
+func (self *Font) GlyphOrder() []uint64 {
+	order := make([]uint64, len(self.glyphOrder))
+	copy(order, self.glyphOrder)
+	return order
+}
+
+// Calls the given function for each glyph, following the glyph order
+// (see [Font.GlyphOrder]()). Unnamed glyphs have an empty name. The
+// glyph order must not be modified during the iteration.
+func (self *Font) EachGlyph(fn func(index int, glyphUID uint64, name string, placement ggfnt.GlyphPlacement)) {
+	for index, glyphUID := range self.glyphOrder {
+		glyphData := self.glyphData[glyphUID]
+		fn(index, glyphUID, glyphData.Name, glyphData.Placement)
+	}
+}
+
+// Moves the given glyph to the given position of the glyph order, shifting
+// the glyphs in between. Since most glyph references use UIDs while editing,
+// this mostly affects the final glyph indices on [Font.Build](). Glyph set
+// ranges are the exception, as they cover all the glyphs between their
+// endpoints in the glyph order, so any move that would change the glyphs
+// covered by a glyph set range is rejected with an error.
+func (self *Font) MoveGlyph(glyphUID uint64, toIndex int) error {
+	if toIndex < 0 || toIndex >= len(self.glyphOrder) {
+		return errors.New("target glyph index out of range")
+	}
+	fromIndex := slices.Index(self.glyphOrder, glyphUID)
+	if fromIndex == -1 { return errors.New("glyph not found") }
+	order := slices.Clone(self.glyphOrder)
+	order = slices.Delete(order, fromIndex, fromIndex + 1)
+	order = slices.Insert(order, toIndex, glyphUID)
+	return self.setGlyphOrder(order)
+}
+
+// Sorts the glyph order by glyph name. Unnamed glyphs are placed after
+// the named ones, keeping their relative order. Like [Font.MoveGlyph](),
+// this fails if the new order would change the glyphs covered by any
+// glyph set range, in which case the glyph order is left unmodified.
+func (self *Font) SortGlyphsByName() error {
+	order := slices.Clone(self.glyphOrder)
+	slices.SortStableFunc(order, func(a, b uint64) int {
+		nameA, nameB := self.glyphData[a].Name, self.glyphData[b].Name
+		if nameA == "" && nameB == "" { return 0 }
+		if nameA == "" { return +1 } // unnamed glyphs go last
+		if nameB == "" { return -1 }
+		return cmp.Compare(nameA, nameB)
+	})
+	return self.setGlyphOrder(order)
+}
+
+// Sets the glyph order if the glyphs covered by each glyph set range
+// remain the same, or returns an error otherwise.
+func (self *Font) setGlyphOrder(order []uint64) error {
+	for _, glyphSet := range self.rewriteGlyphSets {
+		for _, glyphRange := range glyphSet.ranges {
+			prevCovered := uidRangeCoverage(self.glyphOrder, glyphRange)
+			newCovered  := uidRangeCoverage(order, glyphRange)
+			if newCovered == nil || !slices.Equal(prevCovered, newCovered) {
+				return errors.New("glyph order change would modify the glyphs covered by a glyph set range")
+			}
+		}
+	}
+	self.glyphOrder = order
+	return nil
+}
+
+// Returns the sorted UIDs of the glyphs covered by the given range under
+// the given glyph order, or nil if the range endpoints are reversed.
+func uidRangeCoverage(order []uint64, glyphRange uidRange) []uint64 {
+	first := slices.Index(order, glyphRange.First)
+	last  := slices.Index(order, glyphRange.Last)
+	if first == -1 || last == -1 || last < first { return nil }
+	covered := slices.Clone(order[first : last + 1])
+	slices.Sort(covered)
+	return covered
+}
+
 func (self *Font) SetGlyphPlacement(glyphUID uint64, placement ggfnt.GlyphPlacement) error {
 	glyphData, found := self.glyphData[glyphUID]
 	if !found { return errors.New("glyph not found") }
@@ -226,6 +531,82 @@ func (self *Font) SetGlyphPlacement(glyphUID uint64, placement ggfnt.GlyphPlacem
 	return nil
 }
 
+// Returns the current placement of the given glyph.
+func (self *Font) GetGlyphPlacement(glyphUID uint64) (ggfnt.GlyphPlacement, bool) {
+	glyphData, found := self.glyphData[glyphUID]
+	if !found { return ggfnt.GlyphPlacement{}, false }
+	return glyphData.Placement, true
+}
+
+// Returns the mask of the given glyph. The mask is not a copy, so
+// it must not be modified unless you want to edit the glyph directly
+// (glyph masks are only processed on [Font.Build]()).
+func (self *Font) GetGlyphMask(glyphUID uint64) (*image.Alpha, bool) {
+	glyphData, found := self.glyphData[glyphUID]
+	if !found { return nil, false }
+	return glyphData.Mask, true
+}
+
+// Sets an advance adjustment for the given glyphs. This allows tuning the
+// spacing of whole classes of glyphs (e.g. punctuation or numerals) without
+// having to modify each placement or define kerning pairs for them.
+//
+// Adjustments are kept separate from the glyph placements while editing,
+// but they are folded into the advances on [Font.Build](), so the built
+// font only sees the final advance. The global horizontal interspacing
+// and the kerning are still applied on top of the adjusted advance, as
+// in [ggfnt.Font.PairAdvance](). Adjustments can't be used on monospaced
+// fonts, and using 0 removes any previous adjustment.
+func (self *Font) SetAdvanceAdjustment(adjustment int8, glyphUIDs ...uint64) error {
+	if self.monoWidth != 0 && adjustment != 0 {
+		return errors.New("advance adjustments can't be used on monospaced fonts")
+	}
+	for _, glyphUID := range glyphUIDs {
+		if !self.hasGlyph(glyphUID) { return errors.New("glyph not found") }
+	}
+	for _, glyphUID := range glyphUIDs {
+		self.glyphData[glyphUID].AdvanceAdjustment = adjustment
+	}
+	return nil
+}
+
+// Returns the advance adjustment for the given glyph. See
+// [Font.SetAdvanceAdjustment]() for more details.
+func (self *Font) GetAdvanceAdjustment(glyphUID uint64) (int8, error) {
+	glyphData, found := self.glyphData[glyphUID]
+	if !found { return 0, errors.New("glyph not found") }
+	return glyphData.AdvanceAdjustment, nil
+}
+
+// Recomputes the glyph placement based on the current glyph mask.
+// The advance is only set to the right edge of the drawn mask contents
+// if it was zero, as advances often include spacing beyond the ink.
+// When the font has vertical layout, the horizontal center is set to
+// the center of the drawn mask contents, and the top and bottom
+// advances are reset to the font's ascent and descent.
+//
+// See [ggfnt.GlyphPlacement] for more details on how the horizontal
+// center relates to the font's vert line width.
+func (self *Font) AutoPlaceGlyph(glyphUID uint64) error {
+	glyphData, found := self.glyphData[glyphUID]
+	if !found { return errors.New("glyph not found") }
+
+	placement := glyphData.Placement
+	rect := mask.ComputeRect(glyphData.Mask)
+	if placement.Advance == 0 && !rect.Empty() {
+		placement.Advance = uint8(max(0, min(255, rect.Max.X)))
+	}
+	if self.hasVertLayout {
+		placement.TopAdvance = self.ascent
+		placement.BottomAdvance = self.descent
+		if !rect.Empty() {
+			placement.HorzCenter = uint8(max(0, min(255, rect.Min.X + rect.Dx()/2)))
+		}
+	}
+	glyphData.Placement = placement
+	return nil
+}
+
 func (self *Font) SetGlyphName(glyphUID uint64, name string) error {
 	glyphData, found := self.glyphData[glyphUID]
 	if !found { return errors.New("glyph not found") }
@@ -235,6 +616,24 @@ func (self *Font) SetGlyphName(glyphUID uint64, name string) error {
 	return nil
 }
 
+// Returns the UID of the glyph with the given name, if any.
+func (self *Font) GetGlyphUID(name string) (uint64, bool) {
+	if name == "" { return 0, false }
+	for _, uid := range self.glyphOrder {
+		if self.glyphData[uid].Name == name { return uid, true }
+	}
+	return 0, false
+}
+
+// Returns the longest prefix of str that doesn't exceed maxBytes
+// and doesn't split any UTF-8 sequence.
+func truncateOnRuneBoundary(str string, maxBytes int) string {
+	if len(str) <= maxBytes { return str }
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(str[cut]) { cut -= 1 }
+	return str[ : cut]
+}
+
 func checkStringValidity(str string) error {
 	if !utf8.ValidString(str) { return errors.New("string contains invalid characters") }
 	for _, codePoint := range str {
@@ -259,3 +658,85 @@ func (self *Font) SetKerningPair(uidPrev, uidNext uint64, kerning int8) {
 		}
 	}
 }
+
+// Same as [Font.SetKerningPair](), but for vertical kerning.
+func (self *Font) SetVertKerningPair(uidPrev, uidNext uint64, kerning int8) {
+	if kerning == 0 {
+		delete(self.vertKerningPairs, [2]uint64{uidPrev, uidNext})
+	} else {
+		self.vertKerningPairs[[2]uint64{uidPrev, uidNext}] = &editionKerningPair{
+			First: uidPrev,
+			Second: uidNext,
+			Class: 0,
+			Value: kerning,
+		}
+	}
+}
+
+// Returns the kerning between the given glyphs. If the pair uses a kerning
+// class, the class value is returned. The second return value will be false
+// if no kerning is defined for the pair.
+func (self *Font) GetKerningPair(uidPrev, uidNext uint64) (int8, bool) {
+	kerningPair, found := self.horzKerningPairs[[2]uint64{uidPrev, uidNext}]
+	if !found { return 0, false }
+	if kerningPair.HasClass() {
+		return self.kerningClasses[kerningPair.Class - 1].Value, true
+	}
+	return kerningPair.Value, true
+}
+
+// Same as [Font.GetKerningPair](), but using glyph names instead of UIDs.
+// If any of the names can't be found, the second return value will be false.
+func (self *Font) GetKerningByName(prev, next string) (int8, bool) {
+	uidPrev, found := self.GetGlyphUID(prev)
+	if !found { return 0, false }
+	uidNext, found := self.GetGlyphUID(next)
+	if !found { return 0, false }
+	return self.GetKerningPair(uidPrev, uidNext)
+}
+
+// Same as [Font.SetKerningPair](), but using glyph names instead of UIDs.
+func (self *Font) SetKerningByName(prev, next string, kerning int8) error {
+	uidPrev, found := self.GetGlyphUID(prev)
+	if !found { return errors.New("glyph '" + prev + "' not found") }
+	uidNext, found := self.GetGlyphUID(next)
+	if !found { return errors.New("glyph '" + next + "' not found") }
+	self.SetKerningPair(uidPrev, uidNext, kerning)
+	return nil
+}
+
+// Describes a kerning pair that has both a kerning class and a literal
+// value. In these cases, the class value takes precedence on [Font.Build]()
+// and the literal value is ignored. See [Font.KerningConflicts]().
+type KerningConflict struct {
+	First uint64 // glyph UID
+	Second uint64 // glyph UID
+	Vertical bool
+	Value int8 // literal value, ignored
+	ClassValue int8 // value used on build
+}
+
+// Returns all the kerning pairs whose literal value is being overridden
+// by a kerning class, sorted by glyph UIDs, horizontal pairs first.
+func (self *Font) KerningConflicts() []KerningConflict {
+	var conflicts []KerningConflict
+	for i, pairs := range [2]map[[2]uint64]*editionKerningPair{self.horzKerningPairs, self.vertKerningPairs} {
+		start := len(conflicts)
+		for _, pair := range pairs {
+			if !pair.HasClass() || pair.Value == 0 { continue }
+			if int(pair.Class) > len(self.kerningClasses) { panic(invalidInternalState) }
+			conflicts = append(conflicts, KerningConflict{
+				First: pair.First,
+				Second: pair.Second,
+				Vertical: (i == 1),
+				Value: pair.Value,
+				ClassValue: self.kerningClasses[pair.Class - 1].Value,
+			})
+		}
+		slices.SortFunc(conflicts[start : ], func(a, b KerningConflict) int {
+			if a.First != b.First { return cmp.Compare(a.First, b.First) }
+			return cmp.Compare(a.Second, b.Second)
+		})
+	}
+	return conflicts
+}
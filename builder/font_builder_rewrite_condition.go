@@ -3,6 +3,7 @@ package builder
 import "unsafe"
 import "errors"
 import "regexp"
+import "slices"
 import "strconv"
 
 import "github.com/tinne26/ggfnt/internal"
@@ -17,128 +18,10 @@ type rewriteCondition struct {
 // so if you are only displaying one rewrite condition it's fine to call it
 // every frame.
 func (self *rewriteCondition) String() string {
-	var str []byte = make([]byte, 0, 32)
-
-	var index int
-	switch self.data[index] >> 5 {
-	case 0b000: // OR group
-		numTerms := self.data[index] & 0b0001_1111
-		if numTerms < 2 { panic(invalidInternalState) }
-		index += 1
-		for i := uint8(0); i < numTerms; i++ {
-			index, str = self.appendSubTerm(index, str)
-			if i != numTerms - 1 {
-				str = append(str, []byte{' ', 'O', 'R', ' '}...)
-			}
-		}
-	case 0b001: // AND group
-		numTerms := self.data[index] & 0b0001_1111
-		if numTerms < 2 { panic(invalidInternalState) }
-		index += 1
-		for i := uint8(0); i < numTerms; i++ {
-			index, str = self.appendSubTerm(index, str)
-			if i != numTerms - 1 {
-				str = append(str, []byte{' ', 'A', 'N', 'D', ' '}...)
-			}
-		}
-	default:
-		index, str = self.appendSubTerm(index, str)
-	}
-	
-	if index != len(self.data) { panic(invalidInternalState) }
+	str := internal.AppendConditionString(make([]byte, 0, 32), self.data)
 	return unsafe.String(&str[0], len(str))
 }
 
-// Returns the next index (can be at most len(self.data)),
-// and the str with the new term appended.
-func (self *rewriteCondition) appendSubTerm(index int, str []byte) (int, []byte) {
-	if index >= len(self.data) { panic(invalidInternalState) }
-
-	switch self.data[index] >> 5 {
-	case 0b000: // OR group
-		numTerms := self.data[index] & 0b0001_1111
-		if numTerms < 2 { panic(invalidInternalState) }
-		index += 1
-		str = append(str, '(')
-		for i := uint8(0); i < numTerms; i++ {
-			index, str = self.appendSubTerm(index, str)
-			if i == numTerms - 1 {
-				str = append(str, ')')
-			} else {
-				str = append(str, []byte{' ', 'O', 'R', ' '}...)
-			}
-		}
-	case 0b001: // AND group
-		numTerms := self.data[index] & 0b0001_1111
-		if numTerms < 2 { panic(invalidInternalState) }
-		index += 1
-		str = append(str, '(')
-		for i := uint8(0); i < numTerms; i++ {
-			index, str = self.appendSubTerm(index, str)
-			if i == numTerms - 1 {
-				str = append(str, ')')
-			} else {
-				str = append(str, []byte{' ', 'A', 'N', 'D', ' '}...)
-			}
-		}
-	case 0b010: // comparison
-		// append first operand (setting)
-		str = append(str, '#')
-		str = internal.AppendByteDigits(self.data[index + 1], str)
-
-		// append comparison operator
-		str = append(str, ' ')
-		switch self.data[index] & 0b0000_1111 {
-		case 0b000: str = append(str, '=', '=')
-		case 0b001: str = append(str, '!', '=')
-		case 0b010: str = append(str, '<')
-		case 0b011: str = append(str, '>')
-		case 0b100: str = append(str, '<', '=')
-		case 0b101: str = append(str, '>', '=')	
-		default:
-			panic(invalidInternalState)
-		}
-		str = append(str, ' ')
-
-		// append second operand
-		if (self.data[index] & 0b0001_0000) == 0 { // second operand is a setting too
-			str = append(str, '#')	
-		}
-		str = internal.AppendByteDigits(self.data[index + 2], str)
-
-		// advance index
-		index += 3
-	case 0b011: // quick 'setting == const'
-		str = append(str, '#')
-		str = internal.AppendByteDigits(self.data[index + 1], str)
-		str = append(str, ' ', '=', '=', ' ')
-		str = internal.AppendByteDigits(self.data[index] & 0b0001_1111, str)
-		index += 2
-	case 0b100: // quick 'setting != const'
-		str = append(str, '#')
-		str = internal.AppendByteDigits(self.data[index + 1], str)
-		str = append(str, ' ', '!', '=', ' ')
-		str = internal.AppendByteDigits(self.data[index] & 0b0001_1111, str)
-		index += 2
-	case 0b101: // quick 'setting < const'
-		str = append(str, '#')
-		str = internal.AppendByteDigits(self.data[index + 1], str)
-		str = append(str, ' ', '<', ' ')
-		str = internal.AppendByteDigits(self.data[index] & 0b0001_1111, str)
-		index += 2
-	case 0b110: // quick 'setting > const'
-		str = append(str, '#')
-		str = internal.AppendByteDigits(self.data[index + 1], str)
-		str = append(str, ' ', '>', ' ')
-		str = internal.AppendByteDigits(self.data[index] & 0b0001_1111, str)
-		index += 2
-	default:
-		panic(invalidInternalState)
-	}
-	
-	return index, str
-}
-
 // grammar:
 // EXPR: (EXPR)
 // EXPR: TERM
@@ -158,7 +41,7 @@ func compileRewriteCondition(definition string) (rewriteCondition, error) {
 	}
 
 	var err error
-	start, end, err = condition.appendNextExpr(definition, start, end, false)
+	start, end, err = condition.appendNextExpr(definition, start, end)
 	if err != nil { return condition, err }
 	if start < end + 1 {
 		return condition, errors.New("definition expected to end after '" + definition[0 : start] + "', but it continues")
@@ -170,77 +53,57 @@ func compileRewriteCondition(definition string) (rewriteCondition, error) {
 	return condition, nil
 }
 
-func (self *rewriteCondition) appendNextExpr(definition string, start, end int, inner bool) (int, int, error) {
-	start, end = trimSpaces(definition, start, end)
-	if end < start {
-		return start, end, errors.New("expected expression after '" + definition[: start] + "'")
+// Returns the index after the parsed expression, the unmodified end and
+// any error found during parsing.
+func (self *rewriteCondition) appendNextExpr(definition string, start, end int) (int, int, error) {
+	index := len(self.data)
+	start, end, err := self.appendNextInnerExpr(definition, start, end)
+	if err != nil { return start, end, err }
+	start = skipSpaces(definition, start, end)
+
+	var connector string
+	var ctrlCode uint8
+	if startsWith(definition, start, end, "OR ") {
+		connector, ctrlCode = "OR ", 0b0000_0000
+	} else if startsWith(definition, start, end, "AND ") {
+		connector, ctrlCode = "AND ", 0b0010_0000
+	} else {
+		return start, end, nil // single inner expression
 	}
-	if end + 1 > len(definition) { panic("broken code") }
-	
-	var err error
-	if inner {
-		if definition[start] == '(' {
-			start, end, err = self.appendNextExpr(definition, start, end, false)
-			if err != nil { return start, end, err }
-			start, end = trimSpaces(definition, start, end)
-			if end >= len(definition) || definition[end] != ')' {
-				return start, end, errors.New("expected closing parenthesis after '" + definition[ : end] + "'")
-			}
-			return start, end, nil
-		} else {
-			return self.appendNextTerm(definition, start, end)
-		}
-	} else { // outer
-		if definition[start] == '(' {
-			start, end, err = self.appendNextExpr(definition, start, end, false)
-			if err != nil { return start, end, err }
-			start, end = trimSpaces(definition, start, end)
-			if end >= len(definition) || definition[end] != ')' {
-				return start, end, errors.New("expected closing parenthesis after '" + definition[ : end] + "'")
-			}
-			return start, end, nil
-		} else if definition[start] == '#' {
-			return self.appendNextTerm(definition, start, end)
-		} else {
-			start, end, err = self.appendNextExpr(definition, start, end, true) // inner expression
-			if err != nil { return start, end, err }
-			if startsWith(definition, start, end, "OR ") {
-				index := len(self.data)
-				self.data = append(self.data, 0)
-				orCount := 2
-				start += 3
-				for {
-					start, end, err = self.appendNextExpr(definition, start + 3, end, true)
-					if err != nil { return start, end, err }
-					if !startsWith(definition, start, end, "OR ") { break }
-					start += 3
-					orCount += 1
-				}
-				if orCount >= 32 { return start, end, errors.New("OR chain can't have more than 31 terms") }
-				self.data[index] = uint8(orCount)
-				return start, end, nil
-			} else if startsWith(definition, start, end, "AND ") {
-				index := len(self.data)
-				self.data = append(self.data, 0)
-				andCount := 2
-				start += 4
-				for {
-					start, end, err = self.appendNextExpr(definition, start, end, true)
-					if err != nil { return start, end, err }
-					if !startsWith(definition, start, end, "AND ") { break }
-					start += 4
-					andCount += 1
-				}
-				if andCount >= 32 { return start, end, errors.New("AND chain can't have more than 31 terms") }
-				self.data[index] = 0b0010_0000 | uint8(andCount)
-				return start, end, nil
-			} else {
-				if end == start { return start, end, nil }
-				// TODO: error message not great here, it should mention parens when relevant and more
-				return start, end, errors.New("expected AND, OR or expression end after '" + definition[ : start] + "'")
-			}
-		}
+
+	numTerms := 1
+	for startsWith(definition, start, end, connector) {
+		start, end, err = self.appendNextInnerExpr(definition, start + len(connector), end)
+		if err != nil { return start, end, err }
+		start = skipSpaces(definition, start, end)
+		numTerms += 1
+	}
+	if numTerms >= 32 {
+		return start, end, errors.New(connector[ : len(connector) - 1] + " chain can't have more than 31 terms")
+	}
+	if startsWith(definition, start, end, "OR ") || startsWith(definition, start, end, "AND ") {
+		return start, end, errors.New("can't mix AND and OR without parentheses after '" + definition[ : start] + "'")
+	}
+	self.data = slices.Insert(self.data, index, ctrlCode | uint8(numTerms))
+	return start, end, nil
+}
+
+func (self *rewriteCondition) appendNextInnerExpr(definition string, start, end int) (int, int, error) {
+	start = skipSpaces(definition, start, end)
+	if start > end {
+		return start, end, errors.New("expected expression after '" + definition[ : start] + "'")
+	}
+	if definition[start] != '(' {
+		return self.appendNextTerm(definition, start, end)
+	}
+
+	start, end, err := self.appendNextExpr(definition, start + 1, end)
+	if err != nil { return start, end, err }
+	start = skipSpaces(definition, start, end)
+	if start > end || definition[start] != ')' {
+		return start, end, errors.New("expected closing parenthesis after '" + definition[ : start] + "'")
 	}
+	return start + 1, end, nil
 }
 
 var termRegexp = regexp.MustCompile(`^#([0-9]+) *(==|!=|<|>|<=|>=) *(#?)([0-9]+)`)
@@ -292,11 +155,97 @@ func (self *rewriteCondition) appendNextTerm(definition string, start, end int)
 		}
 
 		if rightOpIsSetting {
-			self.data = append(self.data, 0b0100_0000 | opCode, uint8(leftOpSettingIndex))
+			self.data = append(self.data, 0b0100_0000 | opCode, uint8(leftOpSettingIndex), uint8(rightOpValue))
 		} else {
-			self.data = append(self.data, 0b0101_0000 | opCode, uint8(leftOpSettingIndex))
+			self.data = append(self.data, 0b0101_0000 | opCode, uint8(leftOpSettingIndex), uint8(rightOpValue))
 		}
 	}
 
 	return start + len(matches[0]), end, nil
 }
+
+// Returns the largest setting index referenced by the condition
+// data starting at the given index, and the index after it.
+func (self *rewriteCondition) maxSettingIndex(index int) (int, uint8) {
+	var maxSetting uint8
+	switch self.data[index] >> 5 {
+	case 0b000, 0b001: // OR, AND groups
+		numTerms := self.data[index] & 0b0001_1111
+		index += 1
+		for i := uint8(0); i < numTerms; i++ {
+			var setting uint8
+			index, setting = self.maxSettingIndex(index)
+			maxSetting = max(maxSetting, setting)
+		}
+		return index, maxSetting
+	case 0b010: // comparison
+		maxSetting = self.data[index + 1]
+		if (self.data[index] & 0b0001_0000) == 0 { // second operand is a setting too
+			maxSetting = max(maxSetting, self.data[index + 2])
+		}
+		return index + 3, maxSetting
+	case 0b011, 0b100, 0b101, 0b110: // quick comparisons
+		return index + 2, self.data[index + 1]
+	default:
+		panic(invalidInternalState)
+	}
+}
+
+// --- public API ---
+
+// Compiles the given definition (e.g. "#0 == 3 AND (#1 > 0 OR #2 != #3)")
+// and adds it as a new rewrite condition, returning its key.
+func (self *Font) AddRewriteCondition(definition string) (uint8, error) {
+	if len(self.rewriteConditions) >= 254 {
+		return 0, errors.New("can't have more than 254 rewrite conditions")
+	}
+	condition, err := compileRewriteCondition(definition)
+	if err != nil { return 0, err }
+	_, maxSetting := condition.maxSettingIndex(0)
+	if int(maxSetting) >= len(self.settings) {
+		return 0, errors.New("rewrite condition references undefined setting #" + strconv.Itoa(int(maxSetting)))
+	}
+
+	key := uint8(len(self.rewriteConditions))
+	self.rewriteConditions = append(self.rewriteConditions, condition)
+	return key, nil
+}
+
+// Removes the given rewrite condition. Removal is rejected with an error if
+// any rewrite rule is still using the condition. Conditions with higher keys
+// are shifted down by one, and the rules using them are updated accordingly.
+func (self *Font) RemoveRewriteCondition(key uint8) error {
+	if int(key) >= len(self.rewriteConditions) {
+		return errors.New("can't remove undefined rewrite condition")
+	}
+	for i, _ := range self.glyphRules {
+		if self.glyphRules[i].condition == key {
+			return errors.New("can't remove rewrite condition #" + strconv.Itoa(int(key)) + " while glyph rewrite rule #" + strconv.Itoa(i) + " uses it")
+		}
+	}
+	for i, _ := range self.utf8Rules {
+		if self.utf8Rules[i].condition == key {
+			return errors.New("can't remove rewrite condition #" + strconv.Itoa(int(key)) + " while utf8 rewrite rule #" + strconv.Itoa(i) + " uses it")
+		}
+	}
+
+	self.rewriteConditions = slices.Delete(self.rewriteConditions, int(key), int(key) + 1)
+	for i, _ := range self.glyphRules {
+		rule := &self.glyphRules[i]
+		if rule.condition != 255 && rule.condition > key { rule.condition -= 1 }
+	}
+	for i, _ := range self.utf8Rules {
+		rule := &self.utf8Rules[i]
+		if rule.condition != 255 && rule.condition > key { rule.condition -= 1 }
+	}
+	return nil
+}
+
+// Returns the given rewrite condition in the same format accepted
+// by [Font.AddRewriteCondition]().
+func (self *Font) GetRewriteConditionString(key uint8) (string, error) {
+	if int(key) >= len(self.rewriteConditions) {
+		return "", errors.New("undefined rewrite condition")
+	}
+	return self.rewriteConditions[key].String(), nil
+}
@@ -1,7 +1,12 @@
 package builder
 
 import "errors"
+import "slices"
+import "strings"
+import "strconv"
 
+import "github.com/tinne26/ggfnt"
+import "github.com/tinne26/ggfnt/rerules"
 import "github.com/tinne26/ggfnt/internal"
 
 // --- glyph rewrite rules ---
@@ -215,6 +220,9 @@ func (self *utf8RewriteRule) appendAccumulatedInBlock(data []byte, groups []uint
 func (self *Font) AddSimpleUtf8RewriteRule(replacement rune, sequence ...rune) error {
 	if len(sequence) == 0 { return errors.New("rewrite rule sequence can't be empty") }
 	if len(sequence) > 255 { return errors.New("rewrite rule sequence can't exceed 255 runes") }
+	if len(sequence) == 1 && sequence[0] == replacement {
+		return errors.New("rewrite rule output is identical to its body (no-op rule)")
+	}
 	
 	rule := utf8RewriteRule{ condition: 255, bodyLen: uint8(len(sequence)), inRunes: sequence, output: []rune{replacement} }
 	for i := 0; i < len(sequence); i++ {
@@ -226,13 +234,65 @@ func (self *Font) AddSimpleUtf8RewriteRule(replacement rune, sequence ...rune) e
 
 // The 'any' values can be only runes or uint64 ids for groups.
 func (self *Font) AddUtf8RewriteRule(headLen, bodyLen, tailLen uint8, input []any, sequence ...rune) error {
-	panic("unimplemented")
+	// validate input and output sizes
+	if bodyLen == 0 { return errors.New("rewrite rule input body must have len >= 1") }
+	headBodyLen := headLen + bodyLen
+	if headBodyLen < bodyLen || headBodyLen + tailLen < tailLen {
+		return errors.New("rewrite rule exceeds 255 input elements")
+	}
+	if int(headBodyLen + tailLen) != len(input) {
+		return errors.New("rewrite rule input block lengths don't match given input")
+	}
+	if len(sequence) > 255 { return errors.New("rewrite rule sequence can't exceed 255 runes") }
+
+	// reject no-op rules (body made of single runes and output identical to it)
+	if int(bodyLen) == len(sequence) {
+		isNoOp := true
+		for i, codePoint := range sequence {
+			in, isRune := input[int(headLen) + i].(rune)
+			if !isRune || in != codePoint { isNoOp = false ; break }
+		}
+		if isNoOp { return errors.New("rewrite rule output is identical to its body (no-op rule)") }
+	}
+
+	// create rule and classify in runes and groups
+	rule := utf8RewriteRule{ condition: 255, headLen: headLen, bodyLen: bodyLen, tailLen: tailLen }
+	for _, in := range input {
+		switch typedIn := in.(type) {
+		case rune:
+			rule.inElemsAreGroups.Push(false)
+			rule.inRunes = append(rule.inRunes, typedIn)
+		case uint64:
+			_, isGroup := self.rewriteRuneSets[typedIn]
+			if !isGroup { return errors.New("given input UID is not a rune set") }
+			rule.inElemsAreGroups.Push(true)
+			rule.inGroups = append(rule.inGroups, typedIn)
+		default:
+			return errors.New("rewrite rule input elements must be runes or rune set UIDs")
+		}
+	}
+	rule.output = slices.Clone(sequence)
+	self.utf8Rules = append(self.utf8Rules, rule)
+	return nil
 }
 
 // Ids can be for glyphs or glyph sets, we assume they won't collide.
 func (self *Font) AddGlyphRewriteRule(headLen, bodyLen, tailLen uint8, input []uint64, out ...uint64) error {
+	return self.addGlyphRewriteRule(255, headLen, bodyLen, tailLen, input, out)
+}
+
+// Same as [Font.AddGlyphRewriteRule](), but the rule will only be applied
+// while the given rewrite condition is satisfied. See [Font.AddRewriteCondition]().
+func (self *Font) AddConditionalGlyphRewriteRule(conditionKey uint8, headLen, bodyLen, tailLen uint8, input []uint64, out ...uint64) error {
+	if int(conditionKey) >= len(self.rewriteConditions) {
+		return errors.New("rewrite rule references undefined condition #" + strconv.Itoa(int(conditionKey)))
+	}
+	return self.addGlyphRewriteRule(conditionKey, headLen, bodyLen, tailLen, input, out)
+}
+
+func (self *Font) addGlyphRewriteRule(condition uint8, headLen, bodyLen, tailLen uint8, input []uint64, out []uint64) error {
 	// validate input sizes
-	if bodyLen == 0 { errors.New("rewrite rule exceeds input body must have len >= 1") }
+	if bodyLen == 0 { return errors.New("rewrite rule input body must have len >= 1") }
 	headBodyLen := headLen + bodyLen
 	if headBodyLen < bodyLen || headBodyLen + tailLen < tailLen {
 		return errors.New("rewrite rule exceeds 255 input elements")
@@ -248,7 +308,7 @@ func (self *Font) AddGlyphRewriteRule(headLen, bodyLen, tailLen uint8, input []u
 	}
 
 	// create rule
-	rule := glyphRewriteRule{ condition: 255 }
+	rule := glyphRewriteRule{ condition: condition }
 
 	// create in glyphs and groups
 	var inGlyphs []uint64
@@ -267,6 +327,16 @@ func (self *Font) AddGlyphRewriteRule(headLen, bodyLen, tailLen uint8, input []u
 			inGroups = append(inGroups, in)
 		}
 	}
+
+	// reject no-op rules (body made of single glyphs and output identical to it)
+	if int(bodyLen) == len(out) {
+		isNoOp := true
+		for i, glyphUID := range out {
+			inIndex := int(headLen) + i
+			if rule.inElemsAreGroups.Get(inIndex) || input[inIndex] != glyphUID { isNoOp = false ; break }
+		}
+		if isNoOp { return errors.New("rewrite rule output is identical to its body (no-op rule)") }
+	}
 	
 	rule.headLen = headLen
 	rule.bodyLen = bodyLen
@@ -277,3 +347,46 @@ func (self *Font) AddGlyphRewriteRule(headLen, bodyLen, tailLen uint8, input []u
 	self.glyphRules = append(self.glyphRules, rule)
 	return nil
 }
+
+// Builds a temporary font with the current data and applies its utf8
+// rewrite rules to the given text, returning the rewritten result. The
+// settings are applied in order, and settings not included in the slice
+// keep their default values.
+//
+// This is meant to give quick feedback while editing rewrite rules, but
+// the whole font is built each time, so it's not a cheap operation.
+func (self *Font) PreviewRewrites(text string, settings []uint8) (string, error) {
+	font, err := self.Build()
+	if err != nil { return "", err }
+	if len(settings) > int(font.Settings().Count()) {
+		return "", errors.New("given more setting values than font settings")
+	}
+	settingsCache := ggfnt.NewSettingsCache(font)
+	for i, option := range settings {
+		if option >= font.Settings().GetNumOptions(ggfnt.SettingKey(i)) {
+			return "", errors.New("setting option out of range")
+		}
+		settingsCache.Set(ggfnt.SettingKey(i), option)
+	}
+
+	var tester rerules.Utf8Tester
+	numRules := font.Rewrites().NumUTF8Rules()
+	for i := uint16(0); i < numRules; i++ {
+		err = tester.AddRule(font.Rewrites().GetUtf8Rule(i))
+		if err != nil { return "", err }
+	}
+
+	var out strings.Builder
+	var confirmRune = func(codePoint rune) { out.WriteRune(codePoint) }
+	err = tester.BeginSequence(font, settingsCache)
+	if err != nil { return "", err }
+	for _, codePoint := range text {
+		err = tester.Feed(codePoint, confirmRune)
+		if err != nil {
+			tester.FinishSequence(func(rune) {})
+			return "", err
+		}
+	}
+	tester.FinishSequence(confirmRune)
+	return out.String(), nil
+}
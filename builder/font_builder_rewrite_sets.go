@@ -21,8 +21,8 @@ func (self *reGlyphSet) GetSize() uint32 {
 }
 
 func (self *reGlyphSet) AppendTo(data []byte, glyphLookup map[uint64]uint16) ([]byte, error) {
-	if len(self.ranges) >= 255 { panic(invalidInternalState) }
-	if len(self.list) >= 255 { panic(invalidInternalState) }
+	if len(self.ranges) > 255 { panic(invalidInternalState) }
+	if len(self.list) > 255 { panic(invalidInternalState) }
 
 	// ranges
 	data = append(data, uint8(len(self.ranges)))
@@ -163,6 +163,80 @@ func (self *Font) EachGlyphSetListGlyph(setUID uint64, each func(uid uint64)) {
 	}
 }
 
+// Finds glyph sets with identical membership and merges them into the
+// first of them, updating the glyph rewrite rules that referenced the
+// removed sets. Sets containing invalid ranges are left untouched.
+// Returns the number of removed sets.
+func (self *Font) DeduplicateGlyphSets() int {
+	if len(self.glyphSetsOrder) < 2 { return 0 }
+
+	// compute glyph positions in order to normalize ranges
+	glyphPositions := make(map[uint64]int, len(self.glyphOrder))
+	for i, glyphUID := range self.glyphOrder {
+		glyphPositions[glyphUID] = i
+	}
+
+	// find duplicates by comparing normalized memberships
+	replacements := make(map[uint64]uint64) // removed set UID -> survivor set UID
+	survivors := make(map[string]uint64, len(self.glyphSetsOrder))
+	for _, setUID := range self.glyphSetsOrder {
+		key, ok := self.glyphSetMembershipKey(self.rewriteGlyphSets[setUID], glyphPositions)
+		if !ok { continue }
+		survivorUID, found := survivors[key]
+		if found {
+			replacements[setUID] = survivorUID
+		} else {
+			survivors[key] = setUID
+		}
+	}
+	if len(replacements) == 0 { return 0 }
+
+	// rewire rule references
+	for i, _ := range self.glyphRules {
+		for j, groupUID := range self.glyphRules[i].inGroups {
+			survivorUID, found := replacements[groupUID]
+			if found { self.glyphRules[i].inGroups[j] = survivorUID }
+		}
+	}
+
+	// remove duplicated sets
+	for setUID, _ := range replacements {
+		delete(self.rewriteGlyphSets, setUID)
+	}
+	self.glyphSetsOrder = slices.DeleteFunc(self.glyphSetsOrder, func(setUID uint64) bool {
+		_, removed := replacements[setUID]
+		return removed
+	})
+	return len(replacements)
+}
+
+// Returns a string representing the sorted glyph positions included in
+// the set, or false if the set contains invalid ranges or glyphs.
+func (self *Font) glyphSetMembershipKey(set reGlyphSet, glyphPositions map[uint64]int) (string, bool) {
+	var positions []int
+	for _, glyphRange := range set.ranges {
+		first, foundFirst := glyphPositions[glyphRange.First]
+		last , foundLast  := glyphPositions[glyphRange.Last]
+		if !foundFirst || !foundLast || last < first { return "", false }
+		for position := first; position <= last; position++ {
+			positions = append(positions, position)
+		}
+	}
+	for _, glyphUID := range set.list {
+		position, found := glyphPositions[glyphUID]
+		if !found { return "", false }
+		positions = append(positions, position)
+	}
+	slices.Sort(positions)
+	positions = slices.Compact(positions)
+
+	key := make([]byte, 0, len(positions)*2)
+	for _, position := range positions {
+		key = internal.AppendUint16LE(key, uint16(position))
+	}
+	return string(key), true
+}
+
 // --- rune set ---
 
 // rewrite rule rune set
@@ -171,6 +245,36 @@ type reRuneSet struct {
 	list []rune
 }
 
+func (self *reRuneSet) GetSize() uint32 {
+	return uint32(2 + (len(self.ranges) << 2) + len(self.ranges) + (len(self.list) << 2))
+}
+
+func (self *reRuneSet) AppendTo(data []byte) ([]byte, error) {
+	if len(self.ranges) > 255 { panic(invalidInternalState) }
+	if len(self.list) > 255 { panic(invalidInternalState) }
+
+	// ranges
+	data = append(data, uint8(len(self.ranges)))
+	for _, runeRange := range self.ranges {
+		if runeRange.Last < runeRange.First {
+			return data, errors.New("rewrite rune set range start and end points are reversed")
+		}
+		if runeRange.Last - runeRange.First > 255 {
+			return data, errors.New("rewrite rune set range can't exceed length 255")
+		}
+		data = internal.AppendUint32LE(data, uint32(runeRange.First))
+		data = append(data, uint8(runeRange.Last - runeRange.First))
+	}
+
+	// list
+	data = append(data, uint8(len(self.list)))
+	for _, codePoint := range self.list {
+		data = internal.AppendUint32LE(data, uint32(codePoint))
+	}
+
+	return data, nil
+}
+
 func (self *Font) CreateRuneSet() (uint64, error) {
 	if len(self.rewriteRuneSets) >= 255 {
 		return 0, errors.New("font can't contain more than 255 rune sets")
@@ -178,11 +282,13 @@ func (self *Font) CreateRuneSet() (uint64, error) {
 	
 	uid, err := internal.CryptoRandUint64()
 	if err != nil { return 0, err } // don't think this can ever happen
-	_, found := self.rewriteGlyphSets[uid]
+	_, found := self.rewriteRuneSets[uid]
 	if found {
 		return 0, errors.New("failed to generate unique rune set UID")
 	}
+	if self.rewriteRuneSets == nil { self.rewriteRuneSets = make(map[uint64]reRuneSet) }
 	self.rewriteRuneSets[uid] = reRuneSet{}
+	self.runeSetsOrder = append(self.runeSetsOrder, uid)
 	return uid, nil
 }
 
@@ -190,12 +296,16 @@ func (self *Font) RemoveRuneSet(setUID uint64) bool {
 	_, found := self.rewriteRuneSets[setUID]
 	if !found { return false }
 	delete(self.rewriteRuneSets, setUID)
+	index := slices.Index(self.runeSetsOrder, setUID)
+	if index == -1 { panic(invalidInternalState) }
+	self.runeSetsOrder = slices.Delete(self.runeSetsOrder, index, index + 1)
 	return true
 }
 
 func (self *Font) AddRuneSetRange(setUID uint64, rangeStart, rangeEnd rune) error {
 	set, found := self.rewriteRuneSets[setUID]
 	if !found { return errors.New("invalid rune set UID") }
+	if len(set.ranges) >= 255 { return errors.New("rune set can't contain more than 255 ranges") }
 	if rangeStart > rangeEnd {
 		return errors.New("invalid rune range (start > end)")
 	}
@@ -220,7 +330,7 @@ func (self *Font) RemoveRuneSetRange(setUID uint64, rangeStart, rangeEnd rune) b
 		}
 	}
 	if rangeIndex == -1 { return false }
-	set.ranges = slices.Delete(set.ranges, rangeIndex, rangeIndex)
+	set.ranges = slices.Delete(set.ranges, rangeIndex, rangeIndex + 1)
 	self.rewriteRuneSets[setUID] = set
 	return true
 }
@@ -236,6 +346,7 @@ func (self *Font) EachRuneSetRange(setUID uint64, each func(start, end rune)) {
 func (self *Font) AddRuneSetListRune(setUID uint64, codePoint rune) error {
 	set, found := self.rewriteRuneSets[setUID]
 	if !found { return errors.New("invalid rune set UID") }
+	if len(set.list) >= 255 { return errors.New("rune set can't contain more than 255 runes") }
 	for _, listGlyphUID := range set.list {
 		if listGlyphUID == codePoint {
 			return errors.New("rune already included")
@@ -257,7 +368,7 @@ func (self *Font) RemoveRuneSetListRune(setUID uint64, codePoint rune) bool {
 		}
 	}
 	if listIndex == -1 { return false }
-	set.list = slices.Delete(set.list, listIndex, listIndex)
+	set.list = slices.Delete(set.list, listIndex, listIndex + 1)
 	self.rewriteRuneSets[setUID] = set
 	return true
 }
@@ -8,6 +8,7 @@ import "github.com/tinne26/ggfnt/internal"
 type settingEntry struct {
 	Name string
 	Options []string
+	InitValue uint8 // index of the default option
 }
 
 func (self *settingEntry) AppendWords(words map[string]int16) {
@@ -104,3 +105,23 @@ func (self *Font) AddSetting(name string, options ...string) (ggfnt.SettingKey,
 	self.settings = append(self.settings, settingEntry{ Name: name, Options: options })
 	return key, nil
 }
+
+// Sets the init value of the given setting, which is the index of
+// the option that the setting will take by default. Init values are
+// zero unless set otherwise.
+func (self *Font) SetSettingInitValue(key ggfnt.SettingKey, option uint8) error {
+	if int(key) >= len(self.settings) {
+		return errors.New("setting key out of range")
+	}
+	if int(option) >= len(self.settings[key].Options) {
+		return errors.New("init value out of range for setting options")
+	}
+	self.settings[key].InitValue = option
+	return nil
+}
+
+// Adds a setting from one of the [ggfnt.PredefinedSetting] templates, using
+// its conventional name and option names.
+func (self *Font) AddPredefinedSetting(setting ggfnt.PredefinedSetting) (ggfnt.SettingKey, error) {
+	return self.AddSetting(setting.Name(), setting.Options()...)
+}
@@ -7,6 +7,7 @@ import "github.com/tinne26/ggfnt"
 type glyphData struct {
 	Name string // can be empty
 	Placement ggfnt.GlyphPlacement
+	AdvanceAdjustment int8 // folded into Placement.Advance on build
 	Mask *image.Alpha
 }
 
@@ -0,0 +1,253 @@
+package builder
+
+import "fmt"
+import "errors"
+import "slices"
+import "strconv"
+
+import "github.com/tinne26/ggfnt"
+import "github.com/tinne26/ggfnt/mask"
+
+// Severity levels for [ValidationIssue].
+type Severity uint8
+const (
+	SeverityWarning Severity = iota // the font can be built, but something looks off
+	SeverityError // the font can't be built or would be inconsistent
+)
+
+func (self Severity) String() string {
+	switch self {
+	case SeverityWarning: return "warning"
+	case SeverityError: return "error"
+	default:
+		panic("invalid severity")
+	}
+}
+
+type ValidationIssue struct {
+	Severity Severity
+	Category string // "metrics", "glyphs", "colors", "mappings", "rewrites", "kerning", "edition"
+	Message string
+}
+
+func (self *ValidationIssue) String() string {
+	return self.Severity.String() + " [" + self.Category + "]: " + self.Message
+}
+
+// Returned by [Font.Validate]().
+type ValidationReport struct {
+	Issues []ValidationIssue
+}
+
+func (self *ValidationReport) HasErrors() bool {
+	for i, _ := range self.Issues {
+		if self.Issues[i].Severity == SeverityError { return true }
+	}
+	return false
+}
+
+func (self *ValidationReport) add(severity Severity, category string, message string) {
+	self.Issues = append(self.Issues, ValidationIssue{ Severity: severity, Category: category, Message: message })
+}
+
+func (self *ValidationReport) addErr(category string, err error) {
+	if err == nil { return }
+	self.add(SeverityError, category, err.Error())
+}
+
+// Validates the whole font and returns a report with all the errors and
+// warnings found, categorized by section. Unlike most builder methods,
+// this doesn't stop at the first error, so it's suitable for editors
+// that want to display a list of problems.
+func (self *Font) Validate() ValidationReport {
+	var report ValidationReport
+	report.addErr("header", self.CheckDateConsistency())
+	report.addErr("metrics", self.GetMetricsStatus())
+	report.addErr("colors", self.GetColorStatus())
+	report.addErr("mappings", self.ValidateMappings())
+	report.addErr("rewrites", self.ValidateRewriteRules())
+	report.addErr("kerning", self.validateKerning())
+	report.addErr("edition", self.ValidateEditionData())
+
+	// warnings
+	for _, warning := range self.MetricsWarnings() {
+		report.add(SeverityWarning, "metrics", warning)
+	}
+	if len(self.glyphData) == 0 {
+		report.add(SeverityWarning, "glyphs", "font doesn't have any glyphs yet")
+	}
+	collisions := self.CheckGlyphMetricCollisions()
+	if len(collisions) > 0 {
+		severity := SeverityWarning
+		if self.strictGlyphMetrics { severity = SeverityError }
+		report.add(severity, "glyphs", fmt.Sprintf("%d glyph(s) don't fit the font ascent, descent or monospacing width", len(collisions)))
+	}
+	for _, warning := range self.GlyphPlacementWarnings() {
+		report.add(SeverityWarning, "glyphs", warning)
+	}
+	if len(self.runeMapping) == 0 {
+		report.add(SeverityWarning, "mappings", "font doesn't map any code points")
+	}
+	if self.hasVertLayout && self.vertLineWidth == 0 {
+		report.add(SeverityWarning, "metrics", "vert layout is enabled but vert line width is zero")
+	}
+
+	return report
+}
+
+// Returns an error if the version dates are not ordered as
+// first <= major <= minor. Undefined dates are not checked.
+// This is also checked on [Font.Build](). See [ggfnt.CheckVersionDatesOrder]().
+func (self *Font) CheckDateConsistency() error {
+	return ggfnt.CheckVersionDatesOrder(self.firstVersionDate, self.majorVersionDate, self.minorVersionDate)
+}
+
+// Returns warnings for glyphs whose ink extends beyond their advance,
+// which risks overlapping the next glyph, or whose advance is zero while
+// they have ink. These can be intentional (e.g. overhangs or combining
+// marks), so they don't prevent the font from being built. Advance
+// adjustments are taken into account.
+func (self *Font) GlyphPlacementWarnings() []string {
+	var warnings []string
+	for i, glyphUID := range self.glyphOrder {
+		glyph := self.glyphData[glyphUID]
+		rect := mask.ComputeRect(glyph.Mask)
+		if rect.Empty() { continue }
+
+		advance := int(glyph.Placement.Advance) + int(glyph.AdvanceAdjustment)
+		if advance == 0 {
+			warnings = append(warnings, fmt.Sprintf("glyph %s has ink but zero advance", self.glyphDebugName(i)))
+		} else if rect.Max.X > advance {
+			warnings = append(warnings, fmt.Sprintf("glyph %s ink extends %d pixels beyond its advance", self.glyphDebugName(i), rect.Max.X - advance))
+		}
+	}
+	return warnings
+}
+
+// Returns the glyph name in quotes if available, or #index otherwise.
+func (self *Font) glyphDebugName(orderIndex int) string {
+	name := self.glyphData[self.glyphOrder[orderIndex]].Name
+	if name != "" { return "'" + name + "'" }
+	return "#" + strconv.Itoa(orderIndex)
+}
+
+// Returns the error status of the color sections.
+func (self *Font) GetColorStatus() error {
+	if len(self.dyes) + len(self.palettes) > 255 {
+		return errors.New("can't have more than 255 dyes and palettes combined")
+	}
+	if self.getColorIndexCount() > 255 {
+		return errors.New("font colors can't exceed 255 indices")
+	}
+	for i, _ := range self.dyes {
+		if len(self.dyes[i].alphas) == 0 {
+			return errors.New("dye '" + self.dyes[i].name + "' doesn't have any alpha values")
+		}
+	}
+	for i, _ := range self.palettes {
+		if len(self.palettes[i].colors) == 0 {
+			return errors.New("palette '" + self.palettes[i].name + "' doesn't have any colors")
+		}
+	}
+	return nil
+}
+
+// Returns an error if any mapping references undefined glyphs or
+// switches, or if the number of cases doesn't match its switch. Code
+// points are checked in ascending order, so the reported error is
+// always the same for the same font.
+func (self *Font) ValidateMappings() error {
+	codePoints := make([]rune, 0, len(self.runeMapping))
+	for codePoint, _ := range self.runeMapping {
+		codePoints = append(codePoints, codePoint)
+	}
+	slices.Sort(codePoints)
+	for _, codePoint := range codePoints {
+		entry := self.runeMapping[codePoint]
+		if entry.SwitchType < 254 {
+			if int(entry.SwitchType) >= len(self.mappingSwitches) {
+				return fmt.Errorf("mapping for '%c' uses undefined switch %d", codePoint, entry.SwitchType)
+			}
+			numCases := self.computeNumSwitchCases(entry.SwitchType)
+			if len(entry.SwitchCases) != numCases {
+				return fmt.Errorf("mapping for '%c' has %d cases, but its switch expects %d", codePoint, len(entry.SwitchCases), numCases)
+			}
+		}
+		for _, group := range entry.SwitchCases {
+			for _, glyphUID := range group.Glyphs {
+				_, found := self.glyphData[glyphUID]
+				if !found { return fmt.Errorf("mapping for '%c' references an undefined glyph", codePoint) }
+			}
+		}
+	}
+	return nil
+}
+
+// Returns an error if any rewrite rule references undefined glyphs,
+// sets or conditions, or if the rewrite sets are inconsistent.
+func (self *Font) ValidateRewriteRules() error {
+	if len(self.glyphSetsOrder) != len(self.rewriteGlyphSets) {
+		return errors.New("glyph sets order is out of sync with the defined glyph sets")
+	}
+	for _, setUID := range self.glyphSetsOrder {
+		set, found := self.rewriteGlyphSets[setUID]
+		if !found { return errors.New("glyph sets order references an undefined glyph set") }
+		for _, glyphRange := range set.ranges {
+			if !self.hasGlyph(glyphRange.First) || !self.hasGlyph(glyphRange.Last) {
+				return errors.New("glyph set range references an undefined glyph")
+			}
+		}
+		for _, glyphUID := range set.list {
+			if !self.hasGlyph(glyphUID) { return errors.New("glyph set list references an undefined glyph") }
+		}
+	}
+
+	for i, _ := range self.glyphRules {
+		rule := &self.glyphRules[i]
+		if rule.condition != 255 && int(rule.condition) >= len(self.rewriteConditions) {
+			return fmt.Errorf("glyph rewrite rule #%d uses an undefined condition", i)
+		}
+		for _, glyphUID := range rule.inGlyphs {
+			if !self.hasGlyph(glyphUID) { return fmt.Errorf("glyph rewrite rule #%d input references an undefined glyph", i) }
+		}
+		for _, setUID := range rule.inGroups {
+			_, found := self.rewriteGlyphSets[setUID]
+			if !found { return fmt.Errorf("glyph rewrite rule #%d input references an undefined glyph set", i) }
+		}
+		for _, glyphUID := range rule.output {
+			if !self.hasGlyph(glyphUID) { return fmt.Errorf("glyph rewrite rule #%d output references an undefined glyph", i) }
+		}
+	}
+
+	for i, _ := range self.utf8Rules {
+		rule := &self.utf8Rules[i]
+		if rule.condition != 255 && int(rule.condition) >= len(self.rewriteConditions) {
+			return fmt.Errorf("utf8 rewrite rule #%d uses an undefined condition", i)
+		}
+		for _, setUID := range rule.inGroups {
+			_, found := self.rewriteRuneSets[setUID]
+			if !found { return fmt.Errorf("utf8 rewrite rule #%d input references an undefined rune set", i) }
+		}
+	}
+
+	return nil
+}
+
+func (self *Font) validateKerning() error {
+	for _, pairs := range [2]map[[2]uint64]*editionKerningPair{self.horzKerningPairs, self.vertKerningPairs} {
+		for key, pair := range pairs {
+			if key[0] != pair.First || key[1] != pair.Second {
+				return errors.New("kerning pair key out of sync with its glyphs")
+			}
+			if !self.hasGlyph(pair.First) || !self.hasGlyph(pair.Second) {
+				return errors.New("kerning pair references an undefined glyph")
+			}
+		}
+	}
+	return nil
+}
+
+func (self *Font) hasGlyph(glyphUID uint64) bool {
+	_, found := self.glyphData[glyphUID]
+	return found
+}
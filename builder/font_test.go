@@ -1,12 +1,20 @@
 package builder
 
 import "testing"
+import "fmt"
+import "math"
 import "bytes"
+import "strings"
+import "unicode/utf8"
 import "slices"
+import "errors"
 import "image"
 import "image/color"
+import "encoding/json"
+import "compress/gzip"
 
 import "github.com/tinne26/ggfnt"
+import "github.com/tinne26/ggfnt/rerules"
 
 func TestBasicFontBuild(t *testing.T) {
 	// see that empty font build results in ErrBuildNoGlyphs
@@ -72,6 +80,16 @@ func TestBasicFontBuild(t *testing.T) {
 	if font.Header().ID() != builderFontID {
 		t.Fatalf("expected font ID %016X, got %016X instead", builderFontID, font.Header().ID())
 	}
+	if font.Header().IDString() != builder.GetFontIDStr() {
+		t.Fatalf("expected font ID string '%s', got '%s' instead", builder.GetFontIDStr(), font.Header().IDString())
+	}
+	parsedID, err := ggfnt.ParseFontID(strings.ToLower(font.Header().IDString()))
+	if err != nil { t.Fatalf("unexpected ParseFontID() error: %s", err) }
+	if parsedID != builderFontID {
+		t.Fatalf("expected parsed font ID %016X, got %016X instead", builderFontID, parsedID)
+	}
+	_, err = ggfnt.ParseFontID("0x" + font.Header().IDString()[2 : ])
+	if err == nil { t.Fatalf("expected ParseFontID() error on '0x' prefix") }
 	if font.Header().VersionMajor() != 0 {
 		t.Fatalf("expected major version %d, got %d instead", 0, font.Header().VersionMajor())
 	}
@@ -186,3 +204,2876 @@ func TestExpectedParsing(t *testing.T) {
 func testingParseFontWithoutErrors(t *testing.T, data []byte) {
 	// ...
 }
+
+func TestNamedGlyphsOrder(t *testing.T) {
+	builder := New()
+	names := []string{"zeta", "alpha", "mu", "beta", "omega", "al"}
+	for i, name := range names {
+		mask := image.NewAlpha(image.Rect(0, -1, 1, 0))
+		mask.SetAlpha(0, -1, color.Alpha{255})
+		uid, err := builder.AddGlyph(mask)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+		err = builder.SetGlyphName(uid, name)
+		if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphName(_, \"%s\") error: %s", name, err) }
+		if i == 2 { // also add an unnamed glyph in between
+			_, err = builder.AddGlyph(mask)
+			if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+		}
+	}
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	if font.Glyphs().NamedCount() != uint16(len(names)) {
+		t.Fatalf("expected %d named glyphs, got %d", len(names), font.Glyphs().NamedCount())
+	}
+
+	// decode NamedGlyphIDs and GlyphNames directly from the font data
+	offset := font.OffsetToGlyphNames + 2
+	numNamed := uint32(len(names))
+	namesOffset := offset + numNamed*2 + numNamed*3
+	var prevEndOffset uint32
+	var gotNames []string
+	var gotIndices []ggfnt.GlyphIndex
+	for i := uint32(0); i < numNamed; i++ {
+		index := ggfnt.GlyphIndex(uint16(font.Data[offset + i*2]) | uint16(font.Data[offset + i*2 + 1]) << 8)
+		endOffsetIndex := offset + numNamed*2 + i*3
+		endOffset := uint32(font.Data[endOffsetIndex]) | uint32(font.Data[endOffsetIndex + 1]) << 8 | uint32(font.Data[endOffsetIndex + 2]) << 16
+		gotIndices = append(gotIndices, index)
+		gotNames = append(gotNames, string(font.Data[namesOffset + prevEndOffset : namesOffset + endOffset]))
+		prevEndOffset = endOffset
+	}
+
+	expectedNames := []string{"al", "alpha", "beta", "mu", "omega", "zeta"}
+	expectedIndices := []ggfnt.GlyphIndex{6, 1, 4, 2, 5, 0}
+	if !slices.Equal(gotNames, expectedNames) {
+		t.Fatalf("expected glyph names %v, got %v", expectedNames, gotNames)
+	}
+	if !slices.Equal(gotIndices, expectedIndices) {
+		t.Fatalf("expected named glyph IDs %v, got %v", expectedIndices, gotIndices)
+	}
+
+	// names must also be findable through binary search
+	for i, name := range expectedNames {
+		index := font.Glyphs().FindIndexByName(name)
+		if index != expectedIndices[i] {
+			t.Fatalf("expected FindIndexByName(\"%s\") to return %d, got %d", name, expectedIndices[i], index)
+		}
+	}
+
+	// prefix searches
+	prefixTests := []struct{ Prefix string; Indices []ggfnt.GlyphIndex }{
+		{"al", []ggfnt.GlyphIndex{6, 1}}, {"alp", []ggfnt.GlyphIndex{1}}, {"m", []ggfnt.GlyphIndex{2}},
+		{"zeta", []ggfnt.GlyphIndex{0}}, {"zetas", nil}, {"c", nil}, {"", expectedIndices},
+	}
+	for _, test := range prefixTests {
+		indices := font.Glyphs().FindIndexByNamePrefix(test.Prefix)
+		if !slices.Equal(indices, test.Indices) {
+			t.Fatalf("expected FindIndexByNamePrefix(\"%s\") to return %v, got %v", test.Prefix, test.Indices, indices)
+		}
+	}
+}
+
+func TestKerningBuild(t *testing.T) {
+	builder := New()
+	var uids []uint64
+	for i := 0; i < 3; i++ {
+		uid, err := builder.AddBlankGlyph(4)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids = append(uids, uid)
+	}
+	
+	// horizontal and vertical pairs intentionally distinct
+	builder.SetKerningPair(uids[0], uids[1], -1)
+	builder.SetKerningPair(uids[1], uids[2], 2)
+	builder.SetVertKerningPair(uids[2], uids[0], -3)
+	builder.SetVertKerningPair(uids[1], uids[2], 4)
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	if font.Kerning().NumPairs() != 2 || font.Kerning().NumVertPairs() != 2 {
+		t.Fatalf("expected 2 horz and 2 vert kerning pairs, got %d and %d", font.Kerning().NumPairs(), font.Kerning().NumVertPairs())
+	}
+	tests := []struct{ Prev, Curr ggfnt.GlyphIndex; Horz, Vert int8 }{
+		{0, 1, -1, 0}, {1, 2, 2, 4}, {2, 0, 0, -3}, {0, 2, 0, 0},
+	}
+	for _, test := range tests {
+		horz := font.Kerning().Get(test.Prev, test.Curr)
+		if horz != test.Horz {
+			t.Fatalf("expected horz kerning %d for (%d, %d), got %d", test.Horz, test.Prev, test.Curr, horz)
+		}
+		vert := font.Kerning().GetVert(test.Prev, test.Curr)
+		if vert != test.Vert {
+			t.Fatalf("expected vert kerning %d for (%d, %d), got %d", test.Vert, test.Prev, test.Curr, vert)
+		}
+	}
+}
+
+func TestSwitchSettingsWithoutOptions(t *testing.T) {
+	builder := New()
+	emptyKey, err := builder.AddSetting("empty")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	styleKey, err := builder.AddSetting("style", "regular", "bold")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+
+	_, err = builder.AddSwitch(styleKey, emptyKey)
+	if err == nil { t.Fatalf("expected FontBuilder.AddSwitch() to fail on setting without options") }
+	_, err = builder.AddSwitch(styleKey)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+}
+
+func TestConditionSettings(t *testing.T) {
+	builder := New()
+	for i := 0; i < 4; i++ {
+		_, err := builder.AddSetting(fmt.Sprintf("setting%d", i), "a", "b", "c")
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	}
+	_, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+
+	// #1 == 2
+	builder.rewriteConditions = append(builder.rewriteConditions, rewriteCondition{ data: []byte{0x62, 1} })
+	// (#0 == 1 OR #3 != #2) AND #1 > 0
+	builder.rewriteConditions = append(builder.rewriteConditions, rewriteCondition{ data: []byte{0x22, 0x02, 0x61, 0, 0x41, 3, 2, 0xC0, 1} })
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	rewrites := font.Rewrites()
+	settings := rewrites.ConditionSettings(0)
+	if !slices.Equal(settings, []ggfnt.SettingKey{1}) {
+		t.Fatalf("expected condition #0 settings [1], got %v", settings)
+	}
+	settings = rewrites.ConditionSettings(1)
+	if !slices.Equal(settings, []ggfnt.SettingKey{0, 1, 2, 3}) {
+		t.Fatalf("expected condition #1 settings [0 1 2 3], got %v", settings)
+	}
+
+	tests := []struct{ Condition uint8; Settings []uint8; Expected bool }{
+		{0, []uint8{0, 2, 0, 0}, true}, {0, []uint8{0, 1, 0, 0}, false},
+		{1, []uint8{1, 1, 0, 0}, true}, {1, []uint8{0, 1, 0, 0}, false},
+		{1, []uint8{0, 1, 0, 1}, true}, {1, []uint8{1, 0, 0, 0}, false},
+	}
+	for _, test := range tests {
+		if rewrites.EvaluateCondition(test.Condition, test.Settings) != test.Expected {
+			t.Fatalf("expected condition #%d with settings %v to evaluate to %t", test.Condition, test.Settings, test.Expected)
+		}
+	}
+}
+
+func TestVertLayoutToggle(t *testing.T) {
+	builder := New()
+	glyphMask := image.NewAlpha(image.Rect(1, -4, 4, 0))
+	glyphMask.SetAlpha(2, -2, color.Alpha{255})
+	uid, err := builder.AddGlyph(glyphMask)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+	err = builder.SetGlyphPlacement(uid, ggfnt.GlyphPlacement{ Advance: 5 })
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphPlacement() error: %s", err) }
+
+	builder.SetVertLayoutUsed(true)
+	expected := ggfnt.GlyphPlacement{ Advance: 5, TopAdvance: builder.GetAscent(), BottomAdvance: builder.GetDescent(), HorzCenter: 2 }
+	if builder.glyphData[uid].Placement != expected {
+		t.Fatalf("expected placement %v after enabling vert layout, got %v", expected, builder.glyphData[uid].Placement)
+	}
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	if font.Glyphs().Placement(0) != expected {
+		t.Fatalf("expected built placement %v, got %v", expected, font.Glyphs().Placement(0))
+	}
+
+	builder.SetVertLayoutUsed(false)
+	expected = ggfnt.GlyphPlacement{ Advance: 5 }
+	if builder.glyphData[uid].Placement != expected {
+		t.Fatalf("expected placement %v after disabling vert layout, got %v", expected, builder.glyphData[uid].Placement)
+	}
+}
+
+func TestVertLayoutMigration(t *testing.T) {
+	builder := New()
+	glyphMask := image.NewAlpha(image.Rect(0, -3, 4, 0))
+	glyphMask.SetAlpha(3, -1, color.Alpha{255})
+	uid, err := builder.AddGlyph(glyphMask)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+	blankUID, err := builder.AddBlankGlyph(6)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	placement, _ := builder.GetGlyphPlacement(uid)
+	if placement != (ggfnt.GlyphPlacement{ Advance: 4 }) {
+		t.Fatalf("expected no vertical placement without vert layout, got %v", placement)
+	}
+
+	builder.SetVertLayoutUsed(true)
+	ascent, descent := builder.GetAscent(), builder.GetDescent()
+	placement, _ = builder.GetGlyphPlacement(uid)
+	expected := ggfnt.GlyphPlacement{ Advance: 4, TopAdvance: ascent, BottomAdvance: descent, HorzCenter: 3 }
+	if placement != expected { t.Fatalf("expected placement %v after enabling vert layout, got %v", expected, placement) }
+	placement, _ = builder.GetGlyphPlacement(blankUID)
+	expected = ggfnt.GlyphPlacement{ Advance: 6, TopAdvance: ascent, BottomAdvance: descent, HorzCenter: 3 }
+	if placement != expected { t.Fatalf("expected blank placement %v after enabling vert layout, got %v", expected, placement) }
+}
+
+func TestEachAnimatedMapping(t *testing.T) {
+	builder := New()
+	var uids []uint64
+	for i := 0; i < 4; i++ {
+		uid, err := builder.AddBlankGlyph(4)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids = append(uids, uid)
+	}
+	setting, err := builder.AddSetting("animations", "on", "off")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	switchKey, err := builder.AddSwitch(setting)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+
+	mustMap := func(err error) {
+		if err != nil { t.Fatalf("unexpected mapping error: %s", err) }
+	}
+	mustMap(builder.Map('a', uids[0]))
+	mustMap(builder.MapGroup('b', ggfnt.AnimFlagLoopable, uids[0], uids[1], uids[2]))
+	mustMap(builder.MapGroup('c', ggfnt.AnimFlagSequential, uids[3], uids[1]))
+	groups := [][]uint64{ []uint64{uids[2], uids[0]}, []uint64{uids[1], uids[3]} }
+	animFlags := []ggfnt.AnimationFlags{ ggfnt.AnimFlagSplit, ggfnt.AnimFlagLoopable }
+	mustMap(builder.MapWithSwitch('d', switchKey, groups, animFlags))
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	type animation struct { CodePoint rune; Frames []ggfnt.GlyphIndex; Flags ggfnt.AnimationFlags }
+	expected := []animation{
+		{ 'b', []ggfnt.GlyphIndex{0, 1, 2}, ggfnt.AnimFlagLoopable },
+		{ 'c', []ggfnt.GlyphIndex{3, 1}, ggfnt.AnimFlagSequential },
+		{ 'd', []ggfnt.GlyphIndex{2, 0}, ggfnt.AnimFlagSplit },
+	}
+	collect := func(font *ggfnt.Font) []animation {
+		var got []animation
+		font.EachAnimatedMapping(func(codePoint rune, frames []ggfnt.GlyphIndex, flags ggfnt.AnimationFlags) {
+			got = append(got, animation{ codePoint, slices.Clone(frames), flags })
+		})
+		return got
+	}
+	checkAnimations := func(expected, got []animation) {
+		if len(got) != len(expected) {
+			t.Fatalf("expected %d animated mappings, got %d", len(expected), len(got))
+		}
+		for i, _ := range expected {
+			if got[i].CodePoint != expected[i].CodePoint || !slices.Equal(got[i].Frames, expected[i].Frames) || got[i].Flags != expected[i].Flags {
+				t.Fatalf("animated mapping #%d: expected %v, got %v", i, expected[i], got[i])
+			}
+		}
+	}
+	checkAnimations(expected, collect(font))
+
+	// switches are evaluated with the settings init values
+	err = builder.SetSettingInitValue(setting, 1)
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetSettingInitValue() error: %s", err) }
+	font, err = builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	expected[2] = animation{ 'd', []ggfnt.GlyphIndex{1, 3}, ggfnt.AnimFlagLoopable }
+	checkAnimations(expected, collect(font))
+}
+
+func TestAddGlyphWithUID(t *testing.T) {
+	builder := New()
+	const uid = 0x9E3779B97F4A7C15
+	err := builder.AddGlyphWithUID(uid, image.NewAlpha(image.Rect(0, -4, 3, 0)))
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphWithUID() error: %s", err) }
+	if !slices.Equal(builder.GlyphOrder(), []uint64{uid}) {
+		t.Fatalf("expected glyph order [%d], got %v", uint64(uid), builder.GlyphOrder())
+	}
+
+	err = builder.AddGlyphWithUID(uid, image.NewAlpha(image.Rect(0, -4, 3, 0)))
+	if err == nil { t.Fatalf("expected FontBuilder.AddGlyphWithUID() to fail on repeated UID") }
+	err = builder.AddGlyphWithUID(1, image.NewAlpha(image.Rect(0, -4, 3, 0)))
+	if err == nil { t.Fatalf("expected FontBuilder.AddGlyphWithUID() to fail on low entropy UID") }
+	if builder.GetNumGlyphs() != 1 {
+		t.Fatalf("expected 1 glyph, got %d", builder.GetNumGlyphs())
+	}
+}
+
+func TestUsedColorCount(t *testing.T) {
+	builder := New()
+	glyphMask := image.NewAlpha(image.Rect(0, -3, 3, 0))
+	glyphMask.SetAlpha(0, -1, color.Alpha{255})
+	glyphMask.SetAlpha(1, -1, color.Alpha{255})
+	glyphMask.SetAlpha(2, -2, color.Alpha{254})
+	_, err := builder.AddGlyph(glyphMask)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+	glyphMask = image.NewAlpha(image.Rect(0, -3, 3, 0))
+	glyphMask.SetAlpha(1, -2, color.Alpha{254})
+	glyphMask.SetAlpha(1, -1, color.Alpha{252})
+	_, err = builder.AddGlyph(glyphMask)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+	_, err = builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	if font.UsedColorCount() != 3 {
+		t.Fatalf("expected 3 used colors, got %d", font.UsedColorCount())
+	}
+}
+
+func TestMetricsWarnings(t *testing.T) {
+	builder := New()
+	if len(builder.MetricsWarnings()) != 0 {
+		t.Fatalf("expected no metrics warnings on default builder, got %v", builder.MetricsWarnings())
+	}
+	builder.SetAscent(6)
+	builder.SetExtraAscent(5)
+	builder.SetUppercaseAscent(4)
+	builder.SetMidlineAscent(3)
+	if builder.GetMetricsStatus() != nil {
+		t.Fatalf("unexpected metrics status error: %s", builder.GetMetricsStatus())
+	}
+	warnings := builder.MetricsWarnings()
+	if len(warnings) != 1 { // big extra ascent
+		t.Fatalf("expected 1 metrics warning, got %v", warnings)
+	}
+	builder.SetExtraAscent(3)
+	builder.SetDescent(2)
+	builder.SetExtraDescent(2)
+	warnings = builder.MetricsWarnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "extra descent") {
+		t.Fatalf("expected 1 extra descent metrics warning, got %v", warnings)
+	}
+}
+
+func TestIsGlyphMapped(t *testing.T) {
+	builder := New()
+	var uids []uint64
+	for i := 0; i < 8; i++ {
+		uid, err := builder.AddBlankGlyph(4)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids = append(uids, uid)
+	}
+	setting, err := builder.AddSetting("style", "regular", "bold")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	switchKey, err := builder.AddSwitch(setting)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+
+	err = builder.Map('a', uids[0])
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	err = builder.MapGroup('b', 0, uids[2], uids[3], uids[4])
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapGroup() error: %s", err) }
+	err = builder.MapWithSwitchSingles('c', switchKey, uids[0], uids[6])
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitchSingles() error: %s", err) }
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	expected := []bool{true, false, true, true, true, false, true, false}
+	for i, mapped := range expected {
+		if font.IsGlyphMapped(ggfnt.GlyphIndex(i)) != mapped {
+			t.Fatalf("expected IsGlyphMapped(%d) to be %t", i, mapped)
+		}
+	}
+}
+
+func TestEditionCategoriesValidation(t *testing.T) {
+	builder := New()
+	for i := 0; i < 4; i++ {
+		_, err := builder.AddBlankGlyph(4)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	}
+
+	builder.categories = []editionCategory{ {"latin", 2}, {"digits", 1} }
+	err := builder.ValidateEditionData()
+	if err != nil { t.Fatalf("unexpected ValidateEditionData() error: %s", err) }
+	builder.categories = append(builder.categories, editionCategory{"symbols", 2})
+	if builder.ValidateEditionData() == nil { t.Fatalf("expected error when categories exceed the glyph count") }
+	builder.categories = []editionCategory{ {"latin", 2}, {"latin", 1} }
+	if builder.ValidateEditionData() == nil { t.Fatalf("expected error on repeated category names") }
+	builder.categories = []editionCategory{ {"latin", 2}, {"digits", 0} }
+	if builder.ValidateEditionData() == nil { t.Fatalf("expected error on empty category") }
+}
+
+func TestPairAdvance(t *testing.T) {
+	builder := New()
+	builder.SetVertLayoutUsed(true)
+	builder.SetHorzInterspacing(1)
+	builder.SetVertInterspacing(2)
+	var uids []uint64
+	for i := 0; i < 2; i++ {
+		uid, err := builder.AddBlankGlyph(uint8(4 + i))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		err = builder.SetGlyphPlacement(uid, ggfnt.GlyphPlacement{ Advance: uint8(4 + i), TopAdvance: uint8(6 + i), BottomAdvance: uint8(2 + i) })
+		if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphPlacement() error: %s", err) }
+		uids = append(uids, uid)
+	}
+	builder.SetKerningPair(uids[0], uids[1], -2)
+	builder.SetVertKerningPair(uids[1], uids[0], -1)
+	err := builder.SetVertLineWidth(5)
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetVertLineWidth() error: %s", err) }
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	tests := []struct{ Prev, Curr ggfnt.GlyphIndex; Horz, Vert int }{
+		{0, 1, 4 + 1 - 2, 2 + 2 + 7}, {1, 0, 5 + 1, 3 + 2 + 6 - 1}, {0, 0, 4 + 1, 2 + 2 + 6},
+	}
+	for _, test := range tests {
+		horz, vert := font.PairAdvance(test.Prev, test.Curr), font.PairVertAdvance(test.Prev, test.Curr)
+		if horz != test.Horz || vert != test.Vert {
+			t.Fatalf("expected pair (%d, %d) advances (%d, %d), got (%d, %d)", test.Prev, test.Curr, test.Horz, test.Vert, horz, vert)
+		}
+	}
+}
+
+func TestMonoPairAdvance(t *testing.T) {
+	builder := New()
+	uidA, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	uidB, err := builder.AddBlankGlyph(4)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	for codePoint, uid := range map[rune]uint64{'a': uidA, 'b': uidB} {
+		err = builder.Map(codePoint, uid)
+		if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	}
+	builder.SetHorzInterspacing(1)
+	builder.SetKerningPair(uidA, uidB, -1)
+	builder.SetMonoWidth(5)
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	settings := ggfnt.NewSettingsCache(font)
+	for _, pair := range []string{"ab", "ba", "aa"} {
+		pairWidth, _ := font.MeasureText(pair, settings)
+		lastWidth, _ := font.MeasureText(pair[1 : ], settings)
+		prev, _ := font.Mapping().Utf8WithCache(rune(pair[0]), settings)
+		curr, _ := font.Mapping().Utf8WithCache(rune(pair[1]), settings)
+		advance := font.PairAdvance(prev.Select(0), curr.Select(0))
+		if advance != 6 || advance != pairWidth - lastWidth {
+			t.Fatalf("expected PairAdvance() for %q to be 6 and match MeasureText(), got %d (measured %d)", pair, advance, pairWidth - lastWidth)
+		}
+	}
+}
+
+func TestGenerateOutlinedGlyph(t *testing.T) {
+	builder := New()
+	glyphMask := image.NewAlpha(image.Rect(0, -3, 2, 0))
+	glyphMask.SetAlpha(0, -2, color.Alpha{255})
+	uid, err := builder.AddGlyph(glyphMask)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+	err = builder.SetGlyphPlacement(uid, ggfnt.GlyphPlacement{ Advance: 2 })
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphPlacement() error: %s", err) }
+
+	outlinedUID, err := builder.GenerateOutlinedGlyph(uid, 254)
+	if err != nil { t.Fatalf("unexpected FontBuilder.GenerateOutlinedGlyph() error: %s", err) }
+	outlined := builder.glyphData[outlinedUID]
+	if outlined.Placement.Advance != 4 {
+		t.Fatalf("expected outlined glyph advance 4, got %d", outlined.Placement.Advance)
+	}
+	if !outlined.Mask.Rect.Eq(image.Rect(0, -3, 3, 0)) {
+		t.Fatalf("expected outlined glyph rect (0,-3)-(3,0), got %s", outlined.Mask.Rect)
+	}
+	if outlined.Mask.AlphaAt(1, -2).A != 255 || outlined.Mask.AlphaAt(0, -3).A != 254 {
+		t.Fatalf("unexpected outlined glyph contents")
+	}
+
+	// outline exceeding the font descent
+	glyphMask = image.NewAlpha(image.Rect(0, 0, 1, int(builder.GetDescent())))
+	glyphMask.SetAlpha(0, int(builder.GetDescent()) - 1, color.Alpha{255})
+	uid, err = builder.AddGlyph(glyphMask)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+	_, err = builder.GenerateOutlinedGlyph(uid, 254)
+	if err == nil { t.Fatalf("expected FontBuilder.GenerateOutlinedGlyph() to fail when exceeding descent") }
+}
+
+func TestCheckRoundTrip(t *testing.T) {
+	builder := New()
+	uid, err := builder.AddBlankGlyph(4)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	err = builder.Map(' ', uid)
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	builder.SetKerningPair(uid, uid, -1)
+	_, err = builder.AddPalette("fx", []color.RGBA{{255, 0, 0, 255}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+	setting, err := builder.AddSetting("alt", "off", "on")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	switchKey, err := builder.AddSwitch(setting)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	err = builder.MapWithSwitchSingles('a', switchKey, uid, uid)
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitchSingles() error: %s", err) }
+	err = builder.AddGlyphRewriteRule(0, 2, 0, []uint64{uid, uid}, uid)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphRewriteRule() error: %s", err) }
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	var buffer bytes.Buffer
+	err = font.Export(&buffer)
+	if err != nil { t.Fatalf("unexpected Font.Export() error: %s", err) }
+	err = CheckRoundTrip(buffer.Bytes())
+	if err != nil { t.Fatalf("unexpected CheckRoundTrip() error: %s", err) }
+	err = CheckRoundTrip(buffer.Bytes()[ : buffer.Len() - 8])
+	if err == nil { t.Fatalf("expected CheckRoundTrip() error on truncated data") }
+}
+
+func TestAdvanceAdjustment(t *testing.T) {
+	builder := New()
+	var uids []uint64
+	for i := 0; i < 3; i++ {
+		uid, err := builder.AddBlankGlyph(4)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids = append(uids, uid)
+	}
+	err := builder.SetAdvanceAdjustment(-1, uids[0], uids[2])
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetAdvanceAdjustment() error: %s", err) }
+	err = builder.SetAdvanceAdjustment(2, uids[1])
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetAdvanceAdjustment() error: %s", err) }
+	builder.SetKerningPair(uids[0], uids[1], -1)
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	expected := []uint8{3, 6, 3}
+	for i, advance := range expected {
+		if font.Glyphs().Advance(ggfnt.GlyphIndex(i)) != advance {
+			t.Fatalf("expected glyph %d advance %d, got %d", i, advance, font.Glyphs().Advance(ggfnt.GlyphIndex(i)))
+		}
+	}
+	if font.PairAdvance(0, 1) != 3 + int(builder.GetHorzInterspacing()) - 1 {
+		t.Fatalf("unexpected pair advance %d", font.PairAdvance(0, 1))
+	}
+
+	err = builder.SetAdvanceAdjustment(-5, uids[0])
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetAdvanceAdjustment() error: %s", err) }
+	_, err = builder.Build()
+	if err == nil { t.Fatalf("expected FontBuilder.Build() error on negative adjusted advance") }
+}
+
+func TestInkBox(t *testing.T) {
+	builder := New()
+	builder.SetExtraAscent(2)
+	builder.SetExtraDescent(1)
+	_, err := builder.AddBlankGlyph(4)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	ascent, descent := font.Metrics().InkBox()
+	expectedAscent  := int(builder.GetAscent()) + 2
+	expectedDescent := int(builder.GetDescent()) + 1
+	if ascent != expectedAscent || descent != expectedDescent {
+		t.Fatalf("expected ink box (%d, %d), got (%d, %d)", expectedAscent, expectedDescent, ascent, descent)
+	}
+}
+
+func TestTruncatedSetters(t *testing.T) {
+	builder := New()
+	name := strings.Repeat("a", 254) + "é" // 256 bytes
+	err := builder.SetName(name)
+	if err == nil { t.Fatalf("expected FontBuilder.SetName() to fail on 256 bytes") }
+	err = builder.SetNameTruncated(name)
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetNameTruncated() error: %s", err) }
+	if builder.GetName() != strings.Repeat("a", 254) {
+		t.Fatalf("expected name truncated to 254 bytes, got %d bytes", len(builder.GetName()))
+	}
+
+	about := strings.Repeat("ñ", 40000) // 80000 bytes
+	err = builder.SetAboutTruncated(about)
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetAboutTruncated() error: %s", err) }
+	if len(builder.GetAbout()) != 65534 || !utf8.ValidString(builder.GetAbout()) {
+		t.Fatalf("expected valid 'about' truncated to 65534 bytes, got %d bytes", len(builder.GetAbout()))
+	}
+}
+
+func TestGlyphDebugString(t *testing.T) {
+	builder := New()
+	glyphMask := image.NewAlpha(image.Rect(0, -2, 3, 0))
+	glyphMask.SetAlpha(0, -2, color.Alpha{255})
+	glyphMask.SetAlpha(2, -2, color.Alpha{64})
+	glyphMask.SetAlpha(1, -1, color.Alpha{255})
+	_, err := builder.AddGlyph(glyphMask)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+	_, err = builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	expected := "█ ░\n █ \n"
+	if font.Glyphs().DebugString(0) != expected {
+		t.Fatalf("expected glyph debug string %q, got %q", expected, font.Glyphs().DebugString(0))
+	}
+	if font.Glyphs().DebugString(1) != "" {
+		t.Fatalf("expected empty debug string for blank glyph, got %q", font.Glyphs().DebugString(1))
+	}
+}
+
+func TestCopyMetricsFrom(t *testing.T) {
+	source := New()
+	source.SetAscent(7)
+	source.SetExtraAscent(2)
+	source.SetDescent(3)
+	source.SetUppercaseAscent(7)
+	source.SetMidlineAscent(4)
+	source.SetLineGap(2)
+	source.SetHorzInterspacing(0)
+	source.SetVertLayoutUsed(true)
+	err := source.SetVertLineWidth(6)
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetVertLineWidth() error: %s", err) }
+	_, err = source.AddBlankGlyph(4)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	font, err := source.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	builder := New()
+	err = builder.CopyMetricsFrom(font)
+	if err != nil { t.Fatalf("unexpected FontBuilder.CopyMetricsFrom() error: %s", err) }
+	_, err = builder.AddBlankGlyph(4)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	derived, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	
+	sourceMetrics := font.Data[font.OffsetToMetrics : font.OffsetToDyes]
+	derivedMetrics := derived.Data[derived.OffsetToMetrics : derived.OffsetToDyes]
+	if !bytes.Equal(sourceMetrics, derivedMetrics) {
+		t.Fatalf("expected identical metrics data, got %v and %v", sourceMetrics, derivedMetrics)
+	}
+}
+
+func TestGlyphPlacementWarnings(t *testing.T) {
+	builder := New()
+	glyphMask := image.NewAlpha(image.Rect(0, -2, 4, 0))
+	glyphMask.SetAlpha(3, -1, color.Alpha{255})
+	var uids []uint64
+	for i := 0; i < 3; i++ {
+		uid, err := builder.AddGlyph(glyphMask)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+		uids = append(uids, uid)
+	}
+	if len(builder.GlyphPlacementWarnings()) != 0 {
+		t.Fatalf("expected no placement warnings, got %v", builder.GlyphPlacementWarnings())
+	}
+
+	err := builder.SetGlyphPlacement(uids[1], ggfnt.GlyphPlacement{ Advance: 2 })
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphPlacement() error: %s", err) }
+	err = builder.SetGlyphPlacement(uids[2], ggfnt.GlyphPlacement{ Advance: 0 })
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphPlacement() error: %s", err) }
+	err = builder.SetGlyphName(uids[2], "mark")
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphName() error: %s", err) }
+	warnings := builder.GlyphPlacementWarnings()
+	expected := []string{"glyph #1 ink extends 2 pixels beyond its advance", "glyph 'mark' has ink but zero advance"}
+	if !slices.Equal(warnings, expected) {
+		t.Fatalf("expected placement warnings %q, got %q", expected, warnings)
+	}
+}
+
+func TestSettingInitValues(t *testing.T) {
+	builder := New()
+	_, err := builder.AddSetting("style", "regular", "bold")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	sizeKey, err := builder.AddSetting("size", "small", "medium", "large")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	err = builder.SetSettingInitValue(sizeKey, 3)
+	if err == nil { t.Fatalf("expected FontBuilder.SetSettingInitValue() to fail on out of range option") }
+	err = builder.SetSettingInitValue(sizeKey, 2)
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetSettingInitValue() error: %s", err) }
+	_, err = builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	var buffer bytes.Buffer
+	err = font.Export(&buffer)
+	if err != nil { t.Fatalf("unexpected Font.Export() error: %s", err) }
+	reFont, err := ggfnt.Parse(&buffer)
+	if err != nil { t.Fatalf("unexpected ggfnt.Parse() error: %s", err) }
+
+	settings := reFont.Settings()
+	if settings.GetInitValue(0) != 0 || settings.GetInitValue(sizeKey) != 2 {
+		t.Fatalf("expected init values [0 2], got [%d %d]", settings.GetInitValue(0), settings.GetInitValue(sizeKey))
+	}
+	if settings.GetNumOptions(sizeKey) != 3 {
+		t.Fatalf("expected 3 options after re-parsing, got %d", settings.GetNumOptions(sizeKey))
+	}
+	if settings.GetInitValue(2) != 0 || settings.GetNumOptions(2) != 0 {
+		t.Fatalf("expected GetInitValue() and GetNumOptions() to return 0 on invalid setting key")
+	}
+	cache := ggfnt.NewSettingsCache(reFont)
+	if cache.Get(sizeKey) != 2 {
+		t.Fatalf("expected settings cache to start with init value 2, got %d", cache.Get(sizeKey))
+	}
+	cache = settings.GetInitSettingsCache()
+	if len(cache.UnsafeSlice()) != 2 || cache.Get(0) != 0 || cache.Get(sizeKey) != 2 {
+		t.Fatalf("expected init settings cache [0 2], got %v", cache.UnsafeSlice())
+	}
+}
+
+func TestSettingOptionWords(t *testing.T) {
+	builder := New()
+	_, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	options := [][]string{ []string{"zeta", "alpha", "mu"}, []string{"omega", "beta"} }
+	for i, opts := range options {
+		_, err = builder.AddSetting(fmt.Sprintf("setting%d", i), opts...)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	}
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	for i, opts := range options {
+		for j, option := range opts {
+			name := font.Settings().GetOptionName(ggfnt.SettingKey(i), uint8(j))
+			if name != option { t.Fatalf("setting #%d option #%d: expected %q, got %q", i, j, option, name) }
+		}
+	}
+}
+
+func TestEachColorSectionValue(t *testing.T) {
+	builder := New()
+	_, err := builder.AddDye("shadow", []uint8{255, 128})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddDye() error: %s", err) }
+	_, err = builder.AddPalette("fire", []color.RGBA{{255, 0, 0, 255}, {255, 128, 0, 255}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+	_, err = builder.AddPalette("ice", []color.RGBA{{0, 0, 255, 255}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+
+	var alphas []uint8
+	err = builder.EachDyeAlpha("shadow", func(alpha, index uint8) { alphas = append(alphas, alpha) })
+	if err != nil { t.Fatalf("unexpected FontBuilder.EachDyeAlpha() error: %s", err) }
+	if !slices.Equal(alphas, []uint8{255, 128}) { t.Fatalf("expected dye alphas [255 128], got %v", alphas) }
+
+	numDyeAlphas := 0
+	for _, dye := range builder.dyes { numDyeAlphas += len(dye.alphas) }
+	var colors []color.RGBA
+	var indices []uint8
+	err = builder.EachPaletteColor("ice", func(rgba color.RGBA, index uint8) {
+		colors = append(colors, rgba)
+		indices = append(indices, index)
+	})
+	if err != nil { t.Fatalf("unexpected FontBuilder.EachPaletteColor() error: %s", err) }
+	if !slices.Equal(colors, []color.RGBA{{0, 0, 255, 255}}) { t.Fatalf("expected 'ice' colors [blue], got %v", colors) }
+	expectedIndex := uint8(255 - numDyeAlphas - 2)
+	if !slices.Equal(indices, []uint8{expectedIndex}) {
+		t.Fatalf("expected 'ice' color index %d, got %v", expectedIndex, indices)
+	}
+}
+
+func TestColorSectionNames(t *testing.T) {
+	builder := New()
+	_, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	_, err = builder.AddDye("main", []uint8{255})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddDye() error: %s", err) }
+	_, err = builder.AddDye("shadow", []uint8{128, 64})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddDye() error: %s", err) }
+	_, err = builder.AddPalette("fire", []color.RGBA{{255, 0, 0, 255}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+	_, err = builder.AddPalette("ice", []color.RGBA{{0, 0, 255, 255}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	colors := font.Color()
+	colors.EachDye(func(key ggfnt.DyeKey, name string) {
+		if colors.GetDyeName(key) != name {
+			t.Fatalf("expected dye #%d name '%s', got '%s'", key, name, colors.GetDyeName(key))
+		}
+	})
+	colors.EachPalette(func(key ggfnt.PaletteKey, name string) {
+		if colors.GetPaletteName(key) != name {
+			t.Fatalf("expected palette #%d name '%s', got '%s'", key, name, colors.GetPaletteName(key))
+		}
+	})
+	if colors.GetDyeName(1) != "shadow" || colors.GetPaletteName(1) != "ice" {
+		t.Fatalf("expected 'shadow' and 'ice', got '%s' and '%s'", colors.GetDyeName(1), colors.GetPaletteName(1))
+	}
+}
+
+func TestNewFrom(t *testing.T) {
+	builder := New()
+	var uids []uint64
+	for i := 0; i < 4; i++ {
+		mask := image.NewAlpha(image.Rect(0, -3 - i, 2 + i, 0))
+		for n := range mask.Pix { mask.Pix[n] = 255 }
+		uid, err := builder.AddGlyph(mask)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+		uids = append(uids, uid)
+	}
+	blankUID, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	err = builder.SetGlyphName(uids[1], "bee")
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphName() error: %s", err) }
+	err = builder.SetGlyphName(uids[0], "ay")
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphName() error: %s", err) }
+	styleKey, err := builder.AddSetting("style", "regular", "bold")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	_, err = builder.AddSetting("flavor", "zesty", "mellowish", "tangy")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	err = builder.SetSettingInitValue(styleKey, 1)
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetSettingInitValue() error: %s", err) }
+	switchKey, err := builder.AddSwitch(styleKey)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	_, err = builder.AddPalette("fire", []color.RGBA{{255, 0, 0, 255}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+
+	err = builder.Map(' ', blankUID)
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	err = builder.MapGroup('a', ggfnt.AnimationFlags(0), uids[0], uids[1], uids[2])
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapGroup() error: %s", err) }
+	err = builder.MapWithSwitch('b', switchKey, [][]uint64{{uids[1], uids[2]}, {uids[3], uids[0]}}, []ggfnt.AnimationFlags{0, 0})
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitch() error: %s", err) }
+	builder.SetKerningPair(uids[0], uids[1], -1)
+	builder.SetVertKerningPair(uids[1], uids[0], 2)
+
+	setUID, err := builder.CreateGlyphSet()
+	if err != nil { t.Fatalf("unexpected FontBuilder.CreateGlyphSet() error: %s", err) }
+	err = builder.AddGlyphSetRange(setUID, uids[0], uids[2])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphSetRange() error: %s", err) }
+	err = builder.AddGlyphSetListGlyph(setUID, uids[3])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphSetListGlyph() error: %s", err) }
+	err = builder.AddGlyphRewriteRule(1, 2, 0, []uint64{setUID, uids[0], uids[1]}, uids[3], uids[2])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphRewriteRule() error: %s", err) }
+	err = builder.AddSimpleUtf8RewriteRule('b', 'a', 'a')
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSimpleUtf8RewriteRule() error: %s", err) }
+	builder.rewriteConditions = append(builder.rewriteConditions, rewriteCondition{ data: []byte{0x61, 0} })
+	err = builder.AddConditionalGlyphRewriteRule(0, 0, 1, 0, []uint64{uids[2]}, uids[1])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddConditionalGlyphRewriteRule() error: %s", err) }
+	runeSetUID, err := builder.CreateRuneSet()
+	if err != nil { t.Fatalf("unexpected FontBuilder.CreateRuneSet() error: %s", err) }
+	err = builder.AddRuneSetRange(runeSetUID, '0', '9')
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddRuneSetRange() error: %s", err) }
+	err = builder.AddRuneSetListRune(runeSetUID, 'x')
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddRuneSetListRune() error: %s", err) }
+	err = builder.AddUtf8RewriteRule(0, 2, 0, []any{runeSetUID, 'a'}, 'b')
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddUtf8RewriteRule() error: %s", err) }
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	var numConditionalRules int
+	font.Rewrites().EachGlyphRule(func(_ uint16, rule ggfnt.GlyphRewriteRule) {
+		if rule.Condition() == 0 { numConditionalRules += 1 }
+	})
+	if font.Rewrites().NumUTF8Sets() != 1 || numConditionalRules != 1 {
+		t.Fatalf("expected font to include a utf8 set and a conditional glyph rule")
+	}
+	fromBuilder := NewFrom(font)
+	if len(fromBuilder.runeSetsOrder) != 1 || len(fromBuilder.rewriteRuneSets) != 1 {
+		t.Fatalf("expected NewFrom() to load exactly one rune set")
+	}
+	reFont, err := fromBuilder.Build()
+	if err != nil { t.Fatalf("unexpected NewFrom().Build() error: %s", err) }
+	if !slices.Equal(reFont.Data, font.Data) {
+		t.Fatalf("NewFrom().Build() font data changed:\n>> (original build) %v\n>> (NewFrom build) %v", font.Data, reFont.Data)
+	}
+	optionName := reFont.Settings().GetOptionName(1, 1)
+	if optionName != "mellowish" {
+		t.Fatalf("expected setting #1 option #1 to be 'mellowish', got '%s'", optionName)
+	}
+}
+
+func TestDeleteMappingSwitch(t *testing.T) {
+	builder := New()
+	uid, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	styleKey, err := builder.AddSetting("style", "regular", "bold")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	sizeKey, err := builder.AddSetting("size", "small", "large")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	styleSwitch, err := builder.AddSwitch(styleKey)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	sizeSwitch, err := builder.AddMappingSwitch(uint8(sizeKey))
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddMappingSwitch() error: %s", err) }
+	if sizeSwitch != 1 { t.Fatalf("expected FontBuilder.AddMappingSwitch() to return key 1, got %d", sizeSwitch) }
+	err = builder.MapWithSwitchSingles('a', sizeSwitch, uid, uid)
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitchSingles() error: %s", err) }
+
+	err = builder.DeleteMappingSwitch(sizeSwitch)
+	if err == nil { t.Fatalf("expected FontBuilder.DeleteMappingSwitch() to fail on switch in use") }
+	err = builder.DeleteMappingSwitch(2)
+	if err == nil { t.Fatalf("expected FontBuilder.DeleteMappingSwitch() to fail on undefined switch") }
+	err = builder.DeleteMappingSwitch(styleSwitch)
+	if err != nil { t.Fatalf("unexpected FontBuilder.DeleteMappingSwitch() error: %s", err) }
+	if builder.runeMapping['a'].SwitchType != 0 {
+		t.Fatalf("expected 'a' mapping switch to be shifted to 0, got %d", builder.runeMapping['a'].SwitchType)
+	}
+	_, err = builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+}
+
+func TestSwapMappingSwitches(t *testing.T) {
+	builder := New()
+	var uids [3]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	styleKey, err := builder.AddSetting("style", "regular", "bold")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	sizeKey, err := builder.AddSetting("size", "small", "medium", "large")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	styleSwitch, err := builder.AddSwitch(styleKey)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	sizeSwitch, err := builder.AddSwitch(sizeKey)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	err = builder.MapWithSwitchSingles('a', styleSwitch, uids[0], uids[1])
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitchSingles() error: %s", err) }
+	err = builder.MapWithSwitchSingles('b', sizeSwitch, uids[0], uids[1], uids[2])
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitchSingles() error: %s", err) }
+
+	err = builder.SwapMappingSwitches(styleSwitch, 2)
+	if err == nil { t.Fatalf("expected FontBuilder.SwapMappingSwitches() to fail on undefined switch") }
+	err = builder.SwapMappingSwitches(styleSwitch, sizeSwitch)
+	if err != nil { t.Fatalf("unexpected FontBuilder.SwapMappingSwitches() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	settings := []uint8{1, 2}
+	for codePoint, expected := range map[rune]ggfnt.GlyphIndex{'a': 1, 'b': 2} {
+		group, found := font.Mapping().Utf8(codePoint, settings)
+		if !found { t.Fatalf("expected '%c' to be mapped", codePoint) }
+		if group.Select(0) != expected {
+			t.Fatalf("expected '%c' to map to glyph %d, got %d", codePoint, expected, group.Select(0))
+		}
+	}
+}
+
+func TestMapWithSwitch(t *testing.T) {
+	builder := New()
+	var uids [4]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	key, err := builder.AddSetting("style", "regular", "animated")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	mapSwitch, err := builder.AddSwitch(key)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+
+	groups := [][]uint64{ []uint64{uids[0]}, []uint64{uids[1], uids[2], uids[3]} }
+	err = builder.MapWithSwitch('a', mapSwitch, groups, nil)
+	if err == nil { t.Fatalf("expected FontBuilder.MapWithSwitch() to fail with missing animation flags") }
+	err = builder.MapWithSwitch('a', mapSwitch, [][]uint64{ []uint64{uids[0]}, []uint64{} }, nil)
+	if err == nil { t.Fatalf("expected FontBuilder.MapWithSwitch() to fail with empty group") }
+	err = builder.MapWithSwitch('a', mapSwitch, [][]uint64{ []uint64{uids[0]}, []uint64{9999} }, nil)
+	if err == nil { t.Fatalf("expected FontBuilder.MapWithSwitch() to fail with undefined glyph") }
+	flags := []ggfnt.AnimationFlags{ 0b0000_0011 }
+	err = builder.MapWithSwitch('a', mapSwitch, groups, flags)
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitch() error: %s", err) }
+	listGroups := [][]uint64{ []uint64{uids[3], uids[1]}, []uint64{uids[2]} }
+	err = builder.MapWithSwitch('b', mapSwitch, listGroups, flags)
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitch() error: %s", err) }
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	group, found := font.Mapping().Utf8('a', []uint8{0})
+	if !found { t.Fatalf("expected 'a' to be mapped") }
+	if group.Size() != 1 || group.Select(0) != 0 {
+		t.Fatalf("expected single glyph 0 for case 0, got size %d", group.Size())
+	}
+	group, found = font.Mapping().Utf8('a', []uint8{1})
+	if !found { t.Fatalf("expected 'a' to be mapped") }
+	if group.Size() != 3 { t.Fatalf("expected group of size 3 for case 1, got %d", group.Size()) }
+	if group.AnimationFlags() != flags[0] {
+		t.Fatalf("expected animation flags %08b, got %08b", flags[0], group.AnimationFlags())
+	}
+	for i := uint8(0); i < 3; i++ {
+		if group.Select(i) != ggfnt.GlyphIndex(i + 1) {
+			t.Fatalf("expected group glyph #%d to be %d, got %d", i, i + 1, group.Select(i))
+		}
+	}
+	group, found = font.Mapping().Utf8('b', []uint8{0})
+	if !found { t.Fatalf("expected 'b' to be mapped") }
+	if group.Size() != 2 || group.Select(0) != 3 || group.Select(1) != 1 {
+		t.Fatalf("expected list group [3, 1] for case 0")
+	}
+}
+
+func TestAddUtf8RewriteRule(t *testing.T) {
+	builder := New()
+	_, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+
+	err = builder.AddUtf8RewriteRule(1, 0, 1, []any{'a', 'c'}, 'X')
+	if err == nil { t.Fatalf("expected FontBuilder.AddUtf8RewriteRule() to fail with empty body") }
+	err = builder.AddUtf8RewriteRule(1, 1, 1, []any{'a', 'b'}, 'X')
+	if err == nil { t.Fatalf("expected FontBuilder.AddUtf8RewriteRule() to fail with mismatched lengths") }
+	err = builder.AddUtf8RewriteRule(1, 1, 1, []any{'a', uint64(777), 'c'}, 'X')
+	if err == nil { t.Fatalf("expected FontBuilder.AddUtf8RewriteRule() to fail with undefined rune set") }
+	err = builder.AddUtf8RewriteRule(1, 1, 1, []any{'a', "b", 'c'}, 'X')
+	if err == nil { t.Fatalf("expected FontBuilder.AddUtf8RewriteRule() to fail with invalid input type") }
+	err = builder.AddUtf8RewriteRule(1, 1, 1, []any{'a', 'b', 'c'}, 'b')
+	if err == nil { t.Fatalf("expected FontBuilder.AddUtf8RewriteRule() to fail with no-op rule") }
+	err = builder.AddUtf8RewriteRule(1, 1, 1, []any{'a', 'b', 'c'}, 'X')
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddUtf8RewriteRule() error: %s", err) }
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	if font.Rewrites().NumUTF8Rules() != 1 { t.Fatalf("expected 1 utf8 rule") }
+	rule := font.Rewrites().GetUtf8Rule(0)
+	if rule.HeadLen() != 1 || rule.BodyLen() != 1 || rule.TailLen() != 1 || rule.OutLen() != 1 {
+		t.Fatalf("unexpected rule lengths (head %d, body %d, tail %d, out %d)", rule.HeadLen(), rule.BodyLen(), rule.TailLen(), rule.OutLen())
+	}
+
+	out, err := builder.PreviewRewrites("abc abd", nil)
+	if err != nil { t.Fatalf("unexpected FontBuilder.PreviewRewrites() error: %s", err) }
+	if out != "aXc abd" { t.Fatalf("expected \"aXc abd\", got %q", out) }
+}
+
+func TestGlyphRewriteRuleNoOp(t *testing.T) {
+	builder := New()
+	var uids []uint64
+	for i := 0; i < 2; i++ {
+		uid, err := builder.AddBlankGlyph(3)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids = append(uids, uid)
+	}
+	setUID, err := builder.CreateGlyphSet()
+	if err != nil { t.Fatalf("unexpected FontBuilder.CreateGlyphSet() error: %s", err) }
+	err = builder.AddGlyphSetRange(setUID, uids[0], uids[1])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphSetRange() error: %s", err) }
+
+	err = builder.AddGlyphRewriteRule(1, 1, 0, []uint64{uids[1], uids[0]}, uids[0])
+	if err == nil { t.Fatalf("expected FontBuilder.AddGlyphRewriteRule() to fail with no-op rule") }
+	err = builder.AddGlyphRewriteRule(0, 1, 0, []uint64{setUID}, uids[0]) // sets are never no-ops
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphRewriteRule() error: %s", err) }
+	err = builder.AddGlyphRewriteRule(0, 2, 0, []uint64{uids[0], setUID}, uids[0], uids[1])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphRewriteRule() error: %s", err) }
+}
+
+func TestAddRewriteCondition(t *testing.T) {
+	builder := New()
+	for i := 0; i < 3; i++ {
+		_, err := builder.AddSetting(fmt.Sprintf("setting%d", i), "a", "b", "c")
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	}
+	_, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+
+	_, err = builder.AddRewriteCondition("#3 == 1")
+	if err == nil { t.Fatalf("expected FontBuilder.AddRewriteCondition() to fail with undefined setting") }
+	_, err = builder.AddRewriteCondition("#0 == 1 AND")
+	if err == nil { t.Fatalf("expected FontBuilder.AddRewriteCondition() to fail with invalid definition") }
+	_, err = builder.AddRewriteCondition("#0 == 1 AND #1 == 1 OR #2 == 1")
+	if err == nil { t.Fatalf("expected FontBuilder.AddRewriteCondition() to fail with mixed AND/OR chain") }
+	_, err = builder.AddRewriteCondition("(#0 == 1))")
+	if err == nil { t.Fatalf("expected FontBuilder.AddRewriteCondition() to fail with unbalanced parentheses") }
+	definitions := []string{"#1 == 2", "#0 <= #2", "(#0 >= 1 OR #2 != 0) AND #1 < 2"}
+	for i, definition := range definitions {
+		key, err := builder.AddRewriteCondition(definition)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddRewriteCondition() error: %s", err) }
+		if int(key) != i { t.Fatalf("expected condition key %d, got %d", i, key) }
+		str, err := builder.GetRewriteConditionString(key)
+		if err != nil { t.Fatalf("unexpected FontBuilder.GetRewriteConditionString() error: %s", err) }
+		if str != definition { t.Fatalf("expected condition string %q, got %q", definition, str) }
+	}
+
+	err = builder.RemoveRewriteCondition(0)
+	if err != nil { t.Fatalf("unexpected FontBuilder.RemoveRewriteCondition() error: %s", err) }
+	_, err = builder.GetRewriteConditionString(2)
+	if err == nil { t.Fatalf("expected FontBuilder.GetRewriteConditionString() to fail after removal") }
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	rewrites := font.Rewrites()
+	tests := []struct{ Condition uint8; Settings []uint8; Expected bool }{
+		{0, []uint8{1, 0, 2}, true}, {0, []uint8{2, 0, 1}, false},
+		{1, []uint8{1, 1, 0}, true}, {1, []uint8{0, 1, 1}, true},
+		{1, []uint8{0, 1, 0}, false}, {1, []uint8{1, 2, 0}, false},
+	}
+	for _, test := range tests {
+		if rewrites.EvaluateCondition(test.Condition, test.Settings) != test.Expected {
+			t.Fatalf("expected condition #%d with settings %v to evaluate to %t", test.Condition, test.Settings, test.Expected)
+		}
+	}
+}
+
+func TestSettingsCacheInvalidation(t *testing.T) {
+	builder := New()
+	uid, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	for i := 0; i < 3; i++ {
+		_, err := builder.AddSetting(fmt.Sprintf("setting%d", i), "a", "b")
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	}
+	mapSwitch, err := builder.AddSwitch(1)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	err = builder.MapWithSwitchSingles('a', mapSwitch, uid, uid)
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitchSingles() error: %s", err) }
+	_, err = builder.AddRewriteCondition("#0 == 1")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddRewriteCondition() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	cache := ggfnt.NewSettingsCache(font)
+	cache.CacheMappingCase(mapSwitch, 0)
+	cache.CacheRewriteCondition(0, false)
+	tests := []struct{ Key ggfnt.SettingKey; Mappings bool; Conditions bool }{
+		{2, false, false}, {1, true, false}, {0, false, true},
+	}
+	for _, test := range tests {
+		mappingsAffected, conditionsAffected := cache.Set(test.Key, 1)
+		if mappingsAffected != test.Mappings || conditionsAffected != test.Conditions {
+			t.Fatalf("setting #%d expected to affect mappings/conditions %t/%t, got %t/%t", test.Key, test.Mappings, test.Conditions, mappingsAffected, conditionsAffected)
+		}
+	}
+	if _, cached := cache.GetMappingCase(mapSwitch); cached {
+		t.Fatalf("expected mapping case to be invalidated")
+	}
+	if _, cached := cache.GetRewriteCondition(0); cached {
+		t.Fatalf("expected rewrite condition to be invalidated")
+	}
+}
+
+func TestShaper(t *testing.T) {
+	builder := New()
+	var uids [3]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	for i, codePoint := range []rune{'a', 'b', 'x'} {
+		err := builder.Map(codePoint, uids[i])
+		if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	}
+	err := builder.AddSimpleUtf8RewriteRule('x', 'a', 'b')
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSimpleUtf8RewriteRule() error: %s", err) }
+	err = builder.AddGlyphRewriteRule(0, 1, 0, []uint64{uids[1]}, uids[0])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphRewriteRule() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	shaper, err := rerules.NewShaper(font)
+	if err != nil { t.Fatalf("unexpected NewShaper() error: %s", err) }
+	var controlPositions []int
+	shaper.SetControlFunc(func(codePoint rune, position int) {
+		if codePoint == '\n' { controlPositions = append(controlPositions, position) }
+	})
+	settings := ggfnt.NewSettingsCache(font)
+	glyphs, err := shaper.Shape("ab\nba", settings)
+	if err != nil { t.Fatalf("unexpected Shaper.Shape() error: %s", err) }
+	if !slices.Equal(glyphs, []ggfnt.GlyphIndex{2, 0, 0}) {
+		t.Fatalf("expected glyphs [2 0 0], got %v", glyphs)
+	}
+	if !slices.Equal(controlPositions, []int{1}) {
+		t.Fatalf("expected line break at glyph position 1, got %v", controlPositions)
+	}
+
+	_, err = shaper.Shape("az", settings)
+	if err == nil { t.Fatalf("expected Shaper.Shape() to fail with unmapped code point") }
+	glyphs, err = shaper.Shape("a", settings)
+	if err != nil { t.Fatalf("unexpected Shaper.Shape() error after failure: %s", err) }
+	if !slices.Equal(glyphs, []ggfnt.GlyphIndex{0}) { t.Fatalf("expected glyphs [0], got %v", glyphs) }
+
+	// one-shot glyph rewrites
+	in := []ggfnt.GlyphIndex{1, ggfnt.GlyphNewLine, 0, 1, ggfnt.GlyphZilch, 2}
+	glyphs, err = rerules.ApplyGlyphRewrites(font, settings, in)
+	if err != nil { t.Fatalf("unexpected ApplyGlyphRewrites() error: %s", err) }
+	expected := []ggfnt.GlyphIndex{0, ggfnt.GlyphNewLine, 0, 0, 2}
+	if !slices.Equal(glyphs, expected) { t.Fatalf("expected glyphs %v, got %v", expected, glyphs) }
+
+	text, err := rerules.ApplyUtf8Rewrites(font, settings, "abxab\nab")
+	if err != nil { t.Fatalf("unexpected ApplyUtf8Rewrites() error: %s", err) }
+	if text != "xxx\nx" { t.Fatalf("expected text \"xxx\\nx\", got %q", text) }
+
+	// streaming glyph tester
+	glyphTester, err := rerules.NewGlyphTester(font)
+	if err != nil { t.Fatalf("unexpected NewGlyphTester() error: %s", err) }
+	if glyphTester.NumRules() != 1 { t.Fatalf("expected 1 glyph rule, got %d", glyphTester.NumRules()) }
+	glyphs = glyphs[ : 0]
+	appendGlyph := func(glyphIndex ggfnt.GlyphIndex) { glyphs = append(glyphs, glyphIndex) }
+	err = glyphTester.BeginSequence(font, settings)
+	if err != nil { t.Fatalf("unexpected GlyphTester.BeginSequence() error: %s", err) }
+	for _, glyphIndex := range []ggfnt.GlyphIndex{1, 2, 1} {
+		err = glyphTester.Feed(glyphIndex, appendGlyph)
+		if err != nil { t.Fatalf("unexpected GlyphTester.Feed() error: %s", err) }
+	}
+	glyphTester.FinishSequence(appendGlyph)
+	if !slices.Equal(glyphs, []ggfnt.GlyphIndex{0, 2, 0}) { t.Fatalf("expected glyphs [0 2 0], got %v", glyphs) }
+
+	// rule iterators
+	var numGlyphRules, numUtf8Rules int
+	font.Rewrites().EachGlyphRule(func(index uint16, rule ggfnt.GlyphRewriteRule) {
+		if rule.OutLen() != 1 { t.Fatalf("expected glyph rule #%d output length 1, got %d", index, rule.OutLen()) }
+		numGlyphRules += 1
+	})
+	font.Rewrites().EachUtf8Rule(func(index uint16, rule ggfnt.Utf8RewriteRule) {
+		if rule.InLen() != 2 { t.Fatalf("expected utf8 rule #%d input length 2, got %d", index, rule.InLen()) }
+		numUtf8Rules += 1
+	})
+	if numGlyphRules != 1 || numUtf8Rules != 1 {
+		t.Fatalf("expected 1 glyph rule and 1 utf8 rule, got %d and %d", numGlyphRules, numUtf8Rules)
+	}
+
+	// strict rewrites validation
+	err = font.Rewrites().Validate(ggfnt.FmtStrict)
+	if err != nil { t.Fatalf("unexpected FontRewrites.Validate() error: %s", err) }
+	glyphRule := font.Rewrites().GetGlyphRule(0)
+	glyphRule.Data[5] = 3 // output glyph index beyond glyph count (shares font data)
+	err = font.Rewrites().Validate(ggfnt.FmtStrict)
+	if err == nil { t.Fatalf("expected FontRewrites.Validate() to fail with invalid output glyph index") }
+}
+
+func TestDumpDecisionTree(t *testing.T) {
+	builder := New()
+	var uids [2]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	err := builder.AddSimpleUtf8RewriteRule('x', 'a', 'b')
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSimpleUtf8RewriteRule() error: %s", err) }
+	err = builder.AddGlyphRewriteRule(0, 2, 0, []uint64{uids[0], uids[1]}, uids[0])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphRewriteRule() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	settings := ggfnt.NewSettingsCache(font)
+
+	glyphTester, err := rerules.NewGlyphTester(font)
+	if err != nil { t.Fatalf("unexpected rerules.NewGlyphTester() error: %s", err) }
+	utf8Tester, err := rerules.NewUtf8Tester(font)
+	if err != nil { t.Fatalf("unexpected rerules.NewUtf8Tester() error: %s", err) }
+	testers := []interface{
+		DumpDecisionTree() string
+		Resync(*ggfnt.Font, *ggfnt.SettingsCache) error
+	}{ glyphTester, utf8Tester }
+	for i, tester := range testers {
+		dump := tester.DumpDecisionTree()
+		if !strings.HasPrefix(dump, "[no condition] (pending resync)\n") {
+			t.Fatalf("tester#%d: expected pending resync tree dump, got:\n%s", i, dump)
+		}
+		err = tester.Resync(font, settings)
+		if err != nil { t.Fatalf("tester#%d: unexpected Resync() error: %s", i, err) }
+		dump = tester.DumpDecisionTree()
+		if !strings.HasPrefix(dump, "[no condition]\n[state 000]") || !strings.Contains(dump, "{match rule 0}") {
+			t.Fatalf("tester#%d: unexpected tree dump:\n%s", i, dump)
+		}
+	}
+}
+
+func TestShaperConditions(t *testing.T) {
+	builder := New()
+	var uids [3]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	for i, codePoint := range []rune{'a', 'b', 'x'} {
+		err := builder.Map(codePoint, uids[i])
+		if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	}
+	setting, err := builder.AddSetting("ligatures", "off", "on")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	condition, err := builder.AddRewriteCondition("#0 == 1")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddRewriteCondition() error: %s", err) }
+	err = builder.AddSimpleUtf8RewriteRule('x', 'a', 'b')
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSimpleUtf8RewriteRule() error: %s", err) }
+	builder.utf8Rules[0].condition = condition
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	shaper, err := rerules.NewShaper(font)
+	if err != nil { t.Fatalf("unexpected NewShaper() error: %s", err) }
+	settings := ggfnt.NewSettingsCache(font)
+	tests := []struct{ Option uint8; Glyphs []ggfnt.GlyphIndex }{
+		{0, []ggfnt.GlyphIndex{0, 1}}, {1, []ggfnt.GlyphIndex{2}}, {0, []ggfnt.GlyphIndex{0, 1}},
+	}
+	for _, test := range tests {
+		settings.Set(setting, test.Option)
+		glyphs, err := shaper.Shape("ab", settings)
+		if err != nil { t.Fatalf("unexpected Shaper.Shape() error: %s", err) }
+		if !slices.Equal(glyphs, test.Glyphs) {
+			t.Fatalf("option %d: expected glyphs %v, got %v", test.Option, test.Glyphs, glyphs)
+		}
+	}
+}
+
+func TestRasterizeMaskInto(t *testing.T) {
+	builder := New()
+	small := image.NewAlpha(image.Rect(0, -2, 2, 0))
+	small.SetAlpha(0, -2, color.Alpha{255})
+	large := image.NewAlpha(image.Rect(0, -4, 3, 0))
+	for x := 0; x < 3; x++ { large.SetAlpha(x, -4, color.Alpha{255}) }
+	large.SetAlpha(1, -1, color.Alpha{255})
+	for _, glyphMask := range []*image.Alpha{small, large} {
+		_, err := builder.AddGlyph(glyphMask)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+	}
+	_, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	var buffer image.Alpha
+	for _, glyphIndex := range []ggfnt.GlyphIndex{1, 0, 1} {
+		err := font.Glyphs().RasterizeMaskInto(glyphIndex, &buffer)
+		if err != nil { t.Fatalf("unexpected RasterizeMaskInto() error: %s", err) }
+		expected := font.Glyphs().RasterizeMask(glyphIndex)
+		if buffer.Rect != expected.Rect || !bytes.Equal(buffer.Pix, expected.Pix) {
+			t.Fatalf("glyph %d RasterizeMaskInto() result differs from RasterizeMask()", glyphIndex)
+		}
+		if font.Glyphs().Bounds(glyphIndex) != expected.Rect {
+			t.Fatalf("glyph %d Bounds() returned %v, expected %v", glyphIndex, font.Glyphs().Bounds(glyphIndex), expected.Rect)
+		}
+	}
+	err = font.Glyphs().RasterizeMaskInto(2, &buffer)
+	if err != nil { t.Fatalf("unexpected RasterizeMaskInto() error: %s", err) }
+	if !buffer.Rect.Empty() { t.Fatalf("expected empty bounds for blank glyph, got %v", buffer.Rect) }
+	if font.Glyphs().Bounds(2) != (image.Rectangle{}) {
+		t.Fatalf("expected Bounds() to return empty rect for blank glyph, got %v", font.Glyphs().Bounds(2))
+	}
+}
+
+func TestMeasureText(t *testing.T) {
+	builder := New()
+	uidA, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	uidB, err := builder.AddBlankGlyph(4)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	for codePoint, uid := range map[rune]uint64{'a': uidA, 'b': uidB} {
+		err = builder.Map(codePoint, uid)
+		if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	}
+	builder.SetHorzInterspacing(1)
+	builder.SetKerningPair(uidA, uidB, -1)
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	settings := ggfnt.NewSettingsCache(font)
+	tests := []struct{ Text string; Width int; Lines int }{
+		{"", 0, 1}, {"a", 3, 1}, {"ab", 7, 1}, {"ba", 8, 1},
+		{"ab\na", 7, 2}, {"a\nbab?\n", 12, 3},
+	}
+	for _, test := range tests {
+		width, lines := font.MeasureText(test.Text, settings)
+		if width != test.Width || lines != test.Lines {
+			t.Fatalf("MeasureText(%q) expected (%d, %d), got (%d, %d)", test.Text, test.Width, test.Lines, width, lines)
+		}
+	}
+}
+
+func TestImportBDF(t *testing.T) {
+	const bdf = `STARTFONT 2.1
+FONT -test-tiny-medium-r-normal--6-60-75-75-c-40-iso10646-1
+SIZE 6 75 75
+FONTBOUNDINGBOX 4 6 0 -1
+STARTPROPERTIES 3
+FAMILY_NAME "Tiny"
+CAP_HEIGHT 4
+X_HEIGHT 3
+ENDPROPERTIES
+CHARS 4
+STARTCHAR space
+ENCODING 32
+DWIDTH 3 0
+BBX 0 0 0 0
+BITMAP
+ENDCHAR
+STARTCHAR T
+ENCODING 84
+BBX 3 4 0 0
+BITMAP
+E0
+40
+40
+40
+ENDCHAR
+STARTCHAR tall
+ENCODING 65
+DWIDTH 4 0
+BBX 1 8 0 -1
+BITMAP
+80
+80
+80
+80
+80
+80
+80
+80
+ENDCHAR
+STARTCHAR ctrl
+ENCODING 10
+DWIDTH 4 0
+BBX 1 1 0 0
+BITMAP
+80
+ENDCHAR
+ENDFONT
+`
+	builder, warnings, err := ImportBDF(strings.NewReader(bdf))
+	if err != nil { t.Fatalf("unexpected ImportBDF() error: %s", err) }
+	if len(warnings) != 2 { t.Fatalf("expected 2 warnings, got %v", warnings) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	if font.Header().Name() != "Tiny" { t.Fatalf("expected font name \"Tiny\", got %q", font.Header().Name()) }
+	metrics := font.Metrics()
+	if metrics.Ascent() != 5 || metrics.Descent() != 1 || metrics.UppercaseAscent() != 4 || metrics.MidlineAscent() != 3 {
+		t.Fatalf("unexpected metrics (ascent %d, descent %d, uppercase %d, midline %d)", metrics.Ascent(), metrics.Descent(), metrics.UppercaseAscent(), metrics.MidlineAscent())
+	}
+	if font.Glyphs().Count() != 2 { t.Fatalf("expected 2 glyphs, got %d", font.Glyphs().Count()) }
+
+	settings := ggfnt.NewSettingsCache(font)
+	if _, found := font.Mapping().Utf8WithCache('A', settings); found {
+		t.Fatalf("expected tall glyph to be skipped")
+	}
+	group, found := font.Mapping().Utf8WithCache('T', settings)
+	if !found { t.Fatalf("expected 'T' to be mapped") }
+	glyphIndex := group.Select(0)
+	if font.Glyphs().Advance(glyphIndex) != 3 {
+		t.Fatalf("expected 'T' advance to default to BBX width 3, got %d", font.Glyphs().Advance(glyphIndex))
+	}
+	expected := "███\n █ \n █ \n █ \n"
+	if font.Glyphs().DebugString(glyphIndex) != expected {
+		t.Fatalf("unexpected 'T' mask:\n%s", font.Glyphs().DebugString(glyphIndex))
+	}
+	if font.Glyphs().Bounds(glyphIndex) != image.Rect(0, -4, 3, 0) {
+		t.Fatalf("unexpected 'T' bounds %v", font.Glyphs().Bounds(glyphIndex))
+	}
+	group, found = font.Mapping().Utf8WithCache(' ', settings)
+	if !found || font.Glyphs().Advance(group.Select(0)) != 3 {
+		t.Fatalf("expected ' ' to be mapped with advance 3")
+	}
+
+	_, _, err = ImportBDF(strings.NewReader("STARTFONT 2.1\nSTARTCHAR A\nENDCHAR\nENDFONT\n"))
+	if err == nil { t.Fatalf("expected ImportBDF() to fail without FONTBOUNDINGBOX") }
+}
+
+func TestImportGlyphSheet(t *testing.T) {
+	builder := New()
+	_, err := builder.AddPalette("fx", []color.RGBA{{255, 0, 0, 255}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+
+	// 3x1 grid of 3x4 cells, with the middle cell left empty
+	sheet := image.NewNRGBA(image.Rect(0, 0, 9, 4))
+	for y := 0; y < 4; y++ { sheet.Set(1, y, color.NRGBA{255, 255, 255, 128}) } // '|'
+	for x := 6; x < 9; x++ { sheet.Set(x, 3, color.NRGBA{255, 0, 0, 255}) } // '_'
+
+	_, err = builder.ImportGlyphSheet(sheet, 3, 4, 0, 4, []rune{'|', ' ', '_', 'x'})
+	if err == nil { t.Fatalf("expected FontBuilder.ImportGlyphSheet() to fail with more runes than cells") }
+	uids, err := builder.ImportGlyphSheet(sheet, 3, 4, 0, 4, []rune{'|', ' ', '_'})
+	if err != nil { t.Fatalf("unexpected FontBuilder.ImportGlyphSheet() error: %s", err) }
+	if len(uids) != 2 { t.Fatalf("expected 2 glyphs to be added, got %d", len(uids)) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	settings := ggfnt.NewSettingsCache(font)
+	if _, found := font.Mapping().Utf8WithCache(' ', settings); found {
+		t.Fatalf("expected empty cell to be skipped")
+	}
+	tests := []struct{ CodePoint rune; Bounds image.Rectangle; Index uint8 }{
+		{'|', image.Rect(1, -4, 2, 0), 128}, {'_', image.Rect(0, -1, 3, 0), 255},
+	}
+	for _, test := range tests {
+		group, found := font.Mapping().Utf8WithCache(test.CodePoint, settings)
+		if !found { t.Fatalf("expected '%c' to be mapped", test.CodePoint) }
+		glyphMask := font.Glyphs().RasterizeMask(group.Select(0))
+		if glyphMask.Rect != test.Bounds { t.Fatalf("expected '%c' bounds %v, got %v", test.CodePoint, test.Bounds, glyphMask.Rect) }
+		index := glyphMask.AlphaAt(test.Bounds.Min.X, test.Bounds.Min.Y).A
+		if index != test.Index { t.Fatalf("expected '%c' color index %d, got %d", test.CodePoint, test.Index, index) }
+	}
+
+	// colors must be defined on the font, and white alphas can't collide with them
+	for _, clr := range []color.NRGBA{{0, 0, 0, 255}, {255, 255, 255, 255}} {
+		sheet.Set(0, 0, clr)
+		_, err = builder.ImportGlyphSheet(sheet, 3, 4, 0, 4, []rune{'a'})
+		if err == nil { t.Fatalf("expected FontBuilder.ImportGlyphSheet() to fail with color %v", clr) }
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	builder := New()
+	uidA, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	uidB, err := builder.AddBlankGlyph(4)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	err = builder.SetGlyphName(uidB, "bee")
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphName() error: %s", err) }
+	err = builder.Map('a', uidA)
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	setting, err := builder.AddSetting("alt", "off", "on")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	switchKey, err := builder.AddSwitch(setting)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	err = builder.MapWithSwitch('b', switchKey, [][]uint64{{uidB}, {uidA, uidB}}, []ggfnt.AnimationFlags{0})
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitch() error: %s", err) }
+	_, err = builder.AddDye("main", []uint8{255, 128})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddDye() error: %s", err) }
+	_, err = builder.AddPalette("fx", []color.RGBA{{255, 0, 0, 255}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+	_, err = builder.AddRewriteCondition("#0 == 1")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddRewriteCondition() error: %s", err) }
+	err = builder.AddGlyphRewriteRule(1, 1, 0, []uint64{uidA, uidA}, uidB, uidB)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphRewriteRule() error: %s", err) }
+	err = builder.AddUtf8RewriteRule(0, 2, 0, []any{'a', 'b'}, 'b', 'a')
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddUtf8RewriteRule() error: %s", err) }
+	builder.SetKerningPair(uidA, uidB, -1)
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	var buffer bytes.Buffer
+	err = font.ExportJSON(&buffer)
+	if err != nil { t.Fatalf("unexpected Font.ExportJSON() error: %s", err) }
+	var export struct {
+		Metadata struct { NumGlyphs int `json:"numGlyphs"` } `json:"metadata"`
+		Glyphs []struct { Name string `json:"name"`; Advance int `json:"advance"` } `json:"glyphs"`
+		Colors struct {
+			Dyes []struct { Name string `json:"name"`; Alphas []int `json:"alphas"` } `json:"dyes"`
+			Palettes []struct { Colors []string `json:"colors"` } `json:"palettes"`
+		} `json:"colors"`
+		MappingSwitches [][]int `json:"mappingSwitches"`
+		Mapping []struct {
+			CodePoint rune `json:"codePoint"`
+			Switch int `json:"switch"`
+			Cases []struct { Glyphs []int `json:"glyphs"` } `json:"cases"`
+		} `json:"mapping"`
+		Rewrites struct {
+			Conditions []string `json:"conditions"`
+			GlyphRules []struct { Head []int `json:"head"`; Body []int `json:"body"`; Output []int `json:"output"` } `json:"glyphRules"`
+			Utf8Rules []struct { Body []rune `json:"body"`; Output []rune `json:"output"` } `json:"utf8Rules"`
+		} `json:"rewrites"`
+		Kerning [][3]int `json:"kerning"`
+	}
+	err = json.Unmarshal(buffer.Bytes(), &export)
+	if err != nil { t.Fatalf("unexpected json.Unmarshal() error: %s", err) }
+
+	if export.Metadata.NumGlyphs != 2 || len(export.Glyphs) != 2 { t.Fatalf("expected 2 glyphs") }
+	if export.Glyphs[1].Name != "bee" || export.Glyphs[1].Advance != 4 {
+		t.Fatalf("unexpected glyph #1 export: %+v", export.Glyphs[1])
+	}
+	if len(export.Colors.Dyes) != 1 || !slices.Equal(export.Colors.Dyes[0].Alphas, []int{255, 128}) {
+		t.Fatalf("unexpected dyes export: %+v", export.Colors.Dyes)
+	}
+	if len(export.Colors.Palettes) != 1 || !slices.Equal(export.Colors.Palettes[0].Colors, []string{"#FF0000FF"}) {
+		t.Fatalf("unexpected palettes export: %+v", export.Colors.Palettes)
+	}
+	if len(export.MappingSwitches) != 1 || !slices.Equal(export.MappingSwitches[0], []int{0}) {
+		t.Fatalf("unexpected mapping switches export: %v", export.MappingSwitches)
+	}
+	if len(export.Mapping) != 2 || export.Mapping[0].CodePoint != 'a' || export.Mapping[0].Switch != 255 {
+		t.Fatalf("unexpected mapping export: %+v", export.Mapping)
+	}
+	cases := export.Mapping[1].Cases
+	if len(cases) != 2 || !slices.Equal(cases[0].Glyphs, []int{1}) || !slices.Equal(cases[1].Glyphs, []int{0, 1}) {
+		t.Fatalf("unexpected mapping cases for 'b': %+v", cases)
+	}
+	if !slices.Equal(export.Rewrites.Conditions, []string{"#0 == 1"}) {
+		t.Fatalf("unexpected conditions export: %v", export.Rewrites.Conditions)
+	}
+	if len(export.Rewrites.GlyphRules) != 1 { t.Fatalf("expected 1 glyph rule") }
+	glyphRule := export.Rewrites.GlyphRules[0]
+	if !slices.Equal(glyphRule.Head, []int{0}) || !slices.Equal(glyphRule.Body, []int{0}) || !slices.Equal(glyphRule.Output, []int{1, 1}) {
+		t.Fatalf("unexpected glyph rule export: %+v", glyphRule)
+	}
+	if len(export.Rewrites.Utf8Rules) != 1 { t.Fatalf("expected 1 utf8 rule") }
+	utf8Rule := export.Rewrites.Utf8Rules[0]
+	if !slices.Equal(utf8Rule.Body, []rune{'a', 'b'}) || !slices.Equal(utf8Rule.Output, []rune{'b', 'a'}) {
+		t.Fatalf("unexpected utf8 rule export: %+v", utf8Rule)
+	}
+	if len(export.Kerning) != 1 || export.Kerning[0] != [3]int{0, 1, -1} {
+		t.Fatalf("unexpected kerning export: %v", export.Kerning)
+	}
+}
+
+func TestEachMapping(t *testing.T) {
+	builder := New()
+	uids := make([]uint64, 3)
+	for i := range uids {
+		var err error
+		uids[i], err = builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	}
+	setting, err := builder.AddSetting("alt", "off", "on")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	switchKey, err := builder.AddSwitch(setting)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	err = builder.Map('c', uids[2])
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	err = builder.MapWithSwitch('a', switchKey, [][]uint64{{uids[0]}, {uids[1], uids[2]}}, []ggfnt.AnimationFlags{0})
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitch() error: %s", err) }
+	err = builder.MapGroup('b', 0, uids[2], uids[0])
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapGroup() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	mapping := font.Mapping()
+	for _, settings := range [][]uint8{{0}, {1}} {
+		var codePoints []rune
+		err = mapping.EachMapping(settings, func(codePoint rune, group ggfnt.GlyphMappingGroup) error {
+			codePoints = append(codePoints, codePoint)
+			expected, found := mapping.Utf8(codePoint, settings)
+			if !found { t.Fatalf("Utf8(%q) not found", codePoint) }
+			if group.Size() != expected.Size() || group.CaseBranch() != expected.CaseBranch() {
+				t.Fatalf("settings %v, %q: EachMapping group doesn't match Utf8 group", settings, codePoint)
+			}
+			var numGlyphs uint8
+			group.EachGlyph(func(choice uint8, glyphIndex ggfnt.GlyphIndex) {
+				if choice != numGlyphs || glyphIndex != expected.Select(choice) {
+					t.Fatalf("settings %v, %q: glyph #%d mismatch", settings, codePoint, choice)
+				}
+				numGlyphs += 1
+			})
+			if numGlyphs != group.Size() { t.Fatalf("settings %v, %q: EachGlyph() visited %d glyphs", settings, codePoint, numGlyphs) }
+			return nil
+		})
+		if err != nil { t.Fatalf("unexpected FontMapping.EachMapping() error: %s", err) }
+		if !slices.Equal(codePoints, []rune{'a', 'b', 'c'}) {
+			t.Fatalf("expected code points \"abc\", got %q", string(codePoints))
+		}
+	}
+
+	for i, expected := range []string{"b", "a", "abc"} {
+		codePoints := mapping.RunesForGlyph(ggfnt.GlyphIndex(i), []uint8{1})
+		if string(codePoints) != expected {
+			t.Fatalf("RunesForGlyph(%d) expected %q, got %q", i, expected, string(codePoints))
+		}
+	}
+	if string(mapping.RunesForGlyph(1, []uint8{0})) != "" { t.Fatalf("expected no code points for glyph #1 with setting off") }
+
+	stopErr := fmt.Errorf("stop")
+	var visited int
+	err = mapping.EachMapping([]uint8{0}, func(rune, ggfnt.GlyphMappingGroup) error {
+		visited += 1
+		return stopErr
+	})
+	if err != stopErr || visited != 1 { t.Fatalf("expected EachMapping() to stop on first error") }
+}
+
+func TestCoverageRanges(t *testing.T) {
+	builder := New()
+	uid, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	for _, codePoint := range "zbxac" {
+		err = builder.Map(codePoint, uid)
+		if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	}
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	ranges := font.Mapping().CoverageRanges()
+	expected := []ggfnt.RuneRange{{First: 'a', Last: 'c'}, {First: 'x', Last: 'x'}, {First: 'z', Last: 'z'}}
+	if !slices.Equal(ranges, expected) {
+		t.Fatalf("expected coverage ranges %v, got %v", expected, ranges)
+	}
+}
+
+func TestExportWithOptions(t *testing.T) {
+	builder := New()
+	uid, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	err = builder.Map(' ', uid)
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	var defaultBuffer bytes.Buffer
+	err = font.Export(&defaultBuffer)
+	if err != nil { t.Fatalf("unexpected Font.Export() error: %s", err) }
+	var zeroBuffer bytes.Buffer
+	err = font.ExportWithOptions(&zeroBuffer, ggfnt.ExportOptions{})
+	if err != nil { t.Fatalf("unexpected Font.ExportWithOptions() error: %s", err) }
+	if !bytes.Equal(zeroBuffer.Bytes(), defaultBuffer.Bytes()) {
+		t.Fatalf("expected zero ExportOptions to use the default compression level")
+	}
+
+	for _, level := range []int{gzip.NoCompression, gzip.BestSpeed, gzip.BestCompression} {
+		var buffer bytes.Buffer
+		err = font.ExportWithOptions(&buffer, ggfnt.ExportOptions{ CompressionLevel: &level })
+		if err != nil { t.Fatalf("unexpected Font.ExportWithOptions() error: %s", err) }
+		if level == gzip.NoCompression && buffer.Len() <= defaultBuffer.Len() {
+			t.Fatalf("expected gzip.NoCompression export to be bigger than the default one")
+		}
+		reFont, err := ggfnt.Parse(&buffer)
+		if err != nil { t.Fatalf("unexpected ggfnt.Parse() error with level %d: %s", level, err) }
+		if !slices.Equal(reFont.Data, font.Data) { t.Fatalf("font data changed after export with level %d", level) }
+	}
+
+	invalidLevel := 77
+	err = font.ExportWithOptions(&bytes.Buffer{}, ggfnt.ExportOptions{ CompressionLevel: &invalidLevel })
+	if err == nil { t.Fatalf("expected Font.ExportWithOptions() to fail with invalid compression level") }
+}
+
+func TestParseInto(t *testing.T) {
+	builder := New()
+	uid, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	err = builder.Map(' ', uid)
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	var exported bytes.Buffer
+	err = font.Export(&exported)
+	if err != nil { t.Fatalf("unexpected Font.Export() error: %s", err) }
+
+	// big enough buffer
+	buffer := make([]byte, 7, 4096)
+	reFont, err := ggfnt.ParseInto(bytes.NewReader(exported.Bytes()), buffer)
+	if err != nil { t.Fatalf("unexpected ggfnt.ParseInto() error: %s", err) }
+	if !slices.Equal(reFont.Data, font.Data) { t.Fatalf("font data changed after ParseInto()") }
+	if &reFont.Data[0] != &buffer[0] { t.Fatalf("expected ParseInto() to reuse the given buffer") }
+
+	// small buffer
+	buffer = make([]byte, 0, 4)
+	reFont, err = ggfnt.ParseInto(bytes.NewReader(exported.Bytes()), buffer)
+	if err != nil { t.Fatalf("unexpected ggfnt.ParseInto() error: %s", err) }
+	if !slices.Equal(reFont.Data, font.Data) { t.Fatalf("font data changed after ParseInto() with small buffer") }
+}
+
+func TestExportRaw(t *testing.T) {
+	builder := New()
+	uid, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	err = builder.Map(' ', uid)
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	var buffer bytes.Buffer
+	err = font.ExportRaw(&buffer)
+	if err != nil { t.Fatalf("unexpected Font.ExportRaw() error: %s", err) }
+	if buffer.Len() != 6 + font.RawSize() { t.Fatalf("expected raw export to take %d bytes, got %d", 6 + font.RawSize(), buffer.Len()) }
+	rawData := slices.Clone(buffer.Bytes())
+	reFont, err := ggfnt.ParseRaw(&buffer)
+	if err != nil { t.Fatalf("unexpected ggfnt.ParseRaw() error: %s", err) }
+	if !slices.Equal(reFont.Data, font.Data) { t.Fatalf("font data changed after raw export and parse") }
+
+	// signatures can't be mixed
+	_, err = ggfnt.Parse(bytes.NewReader(rawData))
+	if err == nil { t.Fatalf("expected ggfnt.Parse() to fail on raw data") }
+	buffer.Reset()
+	err = font.Export(&buffer)
+	if err != nil { t.Fatalf("unexpected Font.Export() error: %s", err) }
+	_, err = ggfnt.ParseRaw(&buffer)
+	if err == nil { t.Fatalf("expected ggfnt.ParseRaw() to fail on gzipped data") }
+}
+
+func TestMoveGlyph(t *testing.T) {
+	builder := New()
+	uids := make([]uint64, 4)
+	for i, name := range []string{"delta", "", "alpha", "charlie"} {
+		var err error
+		uids[i], err = builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		if name == "" { continue }
+		err = builder.SetGlyphName(uids[i], name)
+		if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphName() error: %s", err) }
+	}
+	builder.SetKerningPair(uids[0], uids[3], -2)
+
+	err := builder.MoveGlyph(uids[0], 4)
+	if err == nil { t.Fatalf("expected FontBuilder.MoveGlyph() to fail with out of range index") }
+	err = builder.MoveGlyph(777, 0)
+	if err == nil { t.Fatalf("expected FontBuilder.MoveGlyph() to fail with undefined glyph") }
+	err = builder.MoveGlyph(uids[0], 3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.MoveGlyph() error: %s", err) }
+	expected := []uint64{uids[1], uids[2], uids[3], uids[0]}
+	if !slices.Equal(builder.GlyphOrder(), expected) { t.Fatalf("unexpected glyph order after MoveGlyph()") }
+
+	err = builder.SortGlyphsByName()
+	if err != nil { t.Fatalf("unexpected FontBuilder.SortGlyphsByName() error: %s", err) }
+	expected = []uint64{uids[2], uids[3], uids[0], uids[1]}
+	if !slices.Equal(builder.GlyphOrder(), expected) { t.Fatalf("unexpected glyph order after SortGlyphsByName()") }
+
+	// references must be preserved
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	if font.Glyphs().Advance(2) != 1 || font.Glyphs().FindIndexByName("delta") != 2 {
+		t.Fatalf("unexpected glyph data after reordering")
+	}
+	if font.Kerning().Get(2, 1) != -2 { t.Fatalf("expected kerning pair to follow the reordered glyphs") }
+}
+
+func TestMoveGlyphWithGlyphSetRange(t *testing.T) {
+	builder := New()
+	uids := make([]uint64, 5)
+	for i, name := range []string{"e", "d", "c", "b", "a"} {
+		var err error
+		uids[i], err = builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		err = builder.SetGlyphName(uids[i], name)
+		if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphName() error: %s", err) }
+	}
+	setUID, err := builder.CreateGlyphSet()
+	if err != nil { t.Fatalf("unexpected FontBuilder.CreateGlyphSet() error: %s", err) }
+	err = builder.AddGlyphSetRange(setUID, uids[1], uids[3])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphSetRange() error: %s", err) }
+
+	initialOrder := builder.GlyphOrder()
+	err = builder.MoveGlyph(uids[0], 2) // would add 'e' to the range
+	if err == nil { t.Fatalf("expected FontBuilder.MoveGlyph() to fail when changing a glyph set range") }
+	err = builder.MoveGlyph(uids[1], 4) // would reverse the range
+	if err == nil { t.Fatalf("expected FontBuilder.MoveGlyph() to fail when reversing a glyph set range") }
+	err = builder.SortGlyphsByName()
+	if err == nil { t.Fatalf("expected FontBuilder.SortGlyphsByName() to fail when reversing a glyph set range") }
+	if !slices.Equal(builder.GlyphOrder(), initialOrder) { t.Fatalf("expected glyph order to remain unmodified") }
+
+	// moves that don't change the range coverage are fine
+	err = builder.MoveGlyph(uids[4], 0)
+	if err != nil { t.Fatalf("unexpected FontBuilder.MoveGlyph() error: %s", err) }
+	expected := []uint64{uids[4], uids[0], uids[1], uids[2], uids[3]}
+	if !slices.Equal(builder.GlyphOrder(), expected) { t.Fatalf("unexpected glyph order after MoveGlyph()") }
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	glyphs := font.Glyphs()
+	for _, name := range []string{"d", "c", "b"} {
+		index := glyphs.FindIndexByName(name)
+		if index < 2 || index > 4 { t.Fatalf("expected glyph %q inside the glyph set range, got index %d", name, index) }
+	}
+}
+
+func TestGlyphGetters(t *testing.T) {
+	builder := New()
+	glyphMask := image.NewAlpha(image.Rect(0, -2, 2, 0))
+	glyphMask.SetAlpha(1, -1, color.Alpha{255})
+	uid, err := builder.AddGlyph(glyphMask)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+	placement := ggfnt.GlyphPlacement{ Advance: 5 }
+	err = builder.SetGlyphPlacement(uid, placement)
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphPlacement() error: %s", err) }
+
+	gotPlacement, found := builder.GetGlyphPlacement(uid)
+	if !found || gotPlacement != placement { t.Fatalf("expected placement %v, got %v (found = %t)", placement, gotPlacement, found) }
+	gotMask, found := builder.GetGlyphMask(uid)
+	if !found || gotMask != glyphMask { t.Fatalf("expected GetGlyphMask() to return the glyph mask") }
+	var numGlyphs int
+	builder.EachGlyph(func(index int, glyphUID uint64, name string, placement ggfnt.GlyphPlacement) {
+		if index != 0 || glyphUID != uid || name != "" || placement.Advance != 5 {
+			t.Fatalf("unexpected EachGlyph() values (%d, %d, %q, %v)", index, glyphUID, name, placement)
+		}
+		numGlyphs += 1
+	})
+	if numGlyphs != 1 { t.Fatalf("expected EachGlyph() to visit 1 glyph, got %d", numGlyphs) }
+	_, found = builder.GetGlyphPlacement(777)
+	if found { t.Fatalf("expected GetGlyphPlacement() to fail with undefined glyph") }
+	_, found = builder.GetGlyphMask(777)
+	if found { t.Fatalf("expected GetGlyphMask() to fail with undefined glyph") }
+}
+
+func TestGlyphMetricCollisions(t *testing.T) {
+	builder := New()
+	builder.SetAscent(4)
+	builder.SetDescent(2)
+	tall := image.NewAlpha(image.Rect(0, -4, 1, 0))
+	tall.SetAlpha(0, -4, color.Alpha{255})
+	tallUID, err := builder.AddGlyph(tall)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+	_, err = builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	if len(builder.CheckGlyphMetricCollisions()) != 0 { t.Fatalf("expected no glyph metric collisions") }
+
+	hasGlyphErrors := func() bool {
+		report := builder.Validate()
+		for _, issue := range report.Issues {
+			if issue.Severity == SeverityError && issue.Category == "glyphs" { return true }
+		}
+		return false
+	}
+
+	// lenient mode
+	builder.SetAscent(3)
+	collisions := builder.CheckGlyphMetricCollisions()
+	if !slices.Equal(collisions, []uint64{tallUID}) { t.Fatalf("expected tall glyph collision, got %v", collisions) }
+	if hasGlyphErrors() { t.Fatalf("expected glyph metric collisions to be warnings in lenient mode") }
+	builder.SetExtraAscent(1)
+	if len(builder.CheckGlyphMetricCollisions()) != 0 { t.Fatalf("expected extra ascent to fix the collision") }
+
+	// strict mode
+	builder.SetStrictGlyphMetrics(true)
+	builder.SetExtraAscent(0)
+	if !hasGlyphErrors() { t.Fatalf("expected glyph metric collisions to be errors in strict mode") }
+	builder.SetAscent(5)
+	builder.SetMonoWidth(1)
+	builder.SetDescent(0)
+	if hasGlyphErrors() { t.Fatalf("expected no glyph metric collisions after growing the metrics") }
+}
+
+func TestColorSectionEditing(t *testing.T) {
+	builder := New()
+	_, err := builder.AddDye("shade", []uint8{255, 128})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddDye() error: %s", err) }
+	_, err = builder.AddPalette("fire", []color.RGBA{{255, 0, 0, 255}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+	iceKey, err := builder.AddPalette("ice", []color.RGBA{{0, 0, 255, 255}, {0, 255, 255, 255}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+	if iceKey != 1 { t.Fatalf("expected palette key 1, got %d", iceKey) }
+	_, err = builder.AddPalette("ice", []color.RGBA{{0, 0, 0, 0}})
+	if err == nil { t.Fatalf("expected FontBuilder.AddPalette() to fail with name collision") }
+
+	err = builder.SetDyeAlphas("shade", 200)
+	if err == nil { t.Fatalf("expected FontBuilder.SetDyeAlphas() to fail with a different number of alphas") }
+	err = builder.SetDyeAlphas("shade", 200, 100)
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetDyeAlphas() error: %s", err) }
+	err = builder.SetPaletteColors("missing", color.RGBA{})
+	if err == nil { t.Fatalf("expected FontBuilder.SetPaletteColors() to fail with undefined palette") }
+	newIce := []color.RGBA{{0, 0, 128, 255}, {0, 128, 128, 255}}
+	err = builder.SetPaletteColors("ice", newIce...)
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetPaletteColors() error: %s", err) }
+
+	err = builder.SetPaletteColor(iceKey + 1, 0, color.RGBA{})
+	if err == nil { t.Fatalf("expected FontBuilder.SetPaletteColor() to fail with undefined palette") }
+	err = builder.SetPaletteColor(iceKey, 2, color.RGBA{})
+	if err == nil { t.Fatalf("expected FontBuilder.SetPaletteColor() to fail with out of range index") }
+	err = builder.SetPaletteColor(iceKey, 0, color.RGBA{200, 0, 0, 100})
+	if err == nil { t.Fatalf("expected FontBuilder.SetPaletteColor() to fail with non-premultiplied color") }
+	err = builder.SetPaletteColor(iceKey, 1, color.RGBA{0, 100, 100, 128})
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetPaletteColor() error: %s", err) }
+	newIce[1] = color.RGBA{0, 100, 100, 128}
+
+	var alphas []uint8
+	err = builder.EachDyeAlpha("shade", func(alpha, _ uint8) { alphas = append(alphas, alpha) })
+	if err != nil { t.Fatalf("unexpected FontBuilder.EachDyeAlpha() error: %s", err) }
+	if !slices.Equal(alphas, []uint8{200, 100}) { t.Fatalf("unexpected dye alphas %v", alphas) }
+	var colors []color.RGBA
+	var indices []uint8
+	err = builder.EachPaletteColor("ice", func(rgba color.RGBA, index uint8) {
+		colors = append(colors, rgba)
+		indices = append(indices, index)
+	})
+	if err != nil { t.Fatalf("unexpected FontBuilder.EachPaletteColor() error: %s", err) }
+	if !slices.Equal(colors, newIce) { t.Fatalf("unexpected palette colors %v", colors) }
+	if !slices.Equal(indices, []uint8{252, 251}) { t.Fatalf("unexpected palette color indices %v", indices) }
+}
+
+func TestPremultColorValidation(t *testing.T) {
+	builder := New()
+	_, err := builder.AddPalette("fx", []color.RGBA{{10, 20, 30, 200}, {0, 0, 0, 100}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+	_, err = builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	err = font.Color().Validate(ggfnt.FmtDefault)
+	if err != nil { t.Fatalf("unexpected FontColor.Validate() error: %s", err) }
+
+	// corrupt the second color's red channel
+	index := bytes.Index(font.Data[font.OffsetToPalettes : ], []byte{0, 0, 0, 100})
+	if index == -1 { t.Fatalf("palette color data not found") }
+	font.Data[int(font.OffsetToPalettes) + index] = 200
+	err = font.Color().Validate(ggfnt.FmtDefault)
+	if err == nil { t.Fatalf("expected FontColor.Validate() to fail with non-premultiplied color") }
+	if !strings.Contains(err.Error(), "'fx' color #1") { t.Fatalf("expected error to identify palette and color, got: %s", err) }
+}
+
+func TestResolveColor(t *testing.T) {
+	builder := New()
+	_, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	_, err = builder.AddDye("main", []uint8{255, 128})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddDye() error: %s", err) }
+	_, err = builder.AddDye("accent", []uint8{255})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddDye() error: %s", err) }
+	_, err = builder.AddPalette("fx", []color.RGBA{{255, 0, 0, 255}, {0, 0, 100, 100}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	dyeColors := []color.RGBA{{0, 255, 0, 255}}
+	tests := []struct{ Index uint8; Color color.RGBA }{
+		{0, color.RGBA{}},
+		{255, color.RGBA{0, 255, 0, 255}}, {254, color.RGBA{0, 128, 0, 128}}, // main dye
+		{253, color.RGBA{255, 255, 255, 255}}, // accent dye, no color given
+		{252, color.RGBA{255, 0, 0, 255}}, {251, color.RGBA{0, 0, 100, 100}}, // palette
+		{250, color.RGBA{250, 250, 250, 250}}, {1, color.RGBA{1, 1, 1, 1}}, // unassigned
+	}
+	colors := font.Color().ResolveColors(dyeColors)
+	for _, test := range tests {
+		rgba := font.Color().ResolveColor(test.Index, dyeColors)
+		if rgba != test.Color { t.Fatalf("ResolveColor(%d) expected %v, got %v", test.Index, test.Color, rgba) }
+		if colors[test.Index] != test.Color { t.Fatalf("ResolveColors()[%d] expected %v, got %v", test.Index, test.Color, colors[test.Index]) }
+	}
+}
+
+func TestRasterizeRGBA(t *testing.T) {
+	builder := New()
+	_, err := builder.AddDye("main", []uint8{255})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddDye() error: %s", err) }
+	_, err = builder.AddPalette("fx", []color.RGBA{{255, 0, 0, 255}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+	glyphMask := image.NewAlpha(image.Rect(0, -2, 2, 0))
+	glyphMask.SetAlpha(0, -2, color.Alpha{255})
+	glyphMask.SetAlpha(1, -1, color.Alpha{254})
+	_, err = builder.AddGlyph(glyphMask)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+	_, err = builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	resolver := font.Color().NewColorResolver([]color.RGBA{{0, 0, 255, 255}})
+	rgba := font.Glyphs().RasterizeRGBA(0, resolver)
+	if rgba.Rect != image.Rect(0, -2, 2, 0) { t.Fatalf("unexpected bounds %v", rgba.Rect) }
+	tests := []struct{ X, Y int; Color color.RGBA }{
+		{0, -2, color.RGBA{0, 0, 255, 255}}, {1, -1, color.RGBA{255, 0, 0, 255}},
+		{1, -2, color.RGBA{}}, {0, -1, color.RGBA{}},
+	}
+	for _, test := range tests {
+		clr := rgba.RGBAAt(test.X, test.Y)
+		if clr != test.Color { t.Fatalf("(%d, %d) expected %v, got %v", test.X, test.Y, test.Color, clr) }
+	}
+
+	rgba = font.Glyphs().RasterizeRGBA(1, resolver)
+	if !rgba.Rect.Empty() { t.Fatalf("expected empty bounds, got %v", rgba.Rect) }
+}
+
+func TestVersionDatesConsistency(t *testing.T) {
+	builder := New()
+	_, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+
+	first := ggfnt.Date{ Year: 2020, Month: 1, Day: 1 }
+	major := ggfnt.Date{ Year: 2021, Month: 6 }
+	minor := ggfnt.Date{ Year: 2021, Month: 6, Day: 15 }
+	err = builder.SetVersionDates(first, major, minor)
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetVersionDates() error: %s", err) }
+	err = builder.SetVersionDates(minor, major, first)
+	if err == nil { t.Fatalf("expected FontBuilder.SetVersionDates() to fail on unordered dates") }
+	if builder.GetFirstVerDate() != first || builder.GetMinorVerDate() != minor {
+		t.Fatalf("expected dates to remain unchanged after failed FontBuilder.SetVersionDates()")
+	}
+
+	err = builder.SetMajorVerDate(ggfnt.Date{ Year: 2019 })
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetMajorVerDate() error: %s", err) }
+	if builder.CheckDateConsistency() == nil { t.Fatalf("expected FontBuilder.CheckDateConsistency() error") }
+	_, err = builder.Build()
+	if err == nil { t.Fatalf("expected FontBuilder.Build() to fail with inconsistent version dates") }
+
+	err = builder.SetMajorVerDate(ggfnt.Date{})
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetMajorVerDate() error: %s", err) }
+	_, err = builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error with undefined major version date: %s", err) }
+}
+
+func TestGlyphRewriteRuleString(t *testing.T) {
+	builder := New()
+	var uids [4]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	err := builder.SetGlyphName(uids[0], "a")
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphName() error: %s", err) }
+	err = builder.SetGlyphName(uids[1], "b")
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphName() error: %s", err) }
+	setUID, err := builder.CreateGlyphSet()
+	if err != nil { t.Fatalf("unexpected FontBuilder.CreateGlyphSet() error: %s", err) }
+	err = builder.AddGlyphSetListGlyph(setUID, uids[2])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphSetListGlyph() error: %s", err) }
+	err = builder.AddGlyphRewriteRule(1, 2, 1, []uint64{uids[0], uids[1], setUID, uids[3]}, uids[3], uids[0])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphRewriteRule() error: %s", err) }
+	err = builder.AddGlyphRewriteRule(0, 1, 0, []uint64{uids[1]}, uids[2])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphRewriteRule() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	expected := []string{"a | b set#0 -> #3 a [#3]", "b -> #2"}
+	font.Rewrites().EachGlyphRule(func(index uint16, rule ggfnt.GlyphRewriteRule) {
+		str := rule.String(font)
+		if str != expected[index] { t.Fatalf("expected glyph rule #%d string '%s', got '%s'", index, expected[index], str) }
+	})
+
+	rule := font.Rewrites().GetGlyphRule(0)
+	tests := []struct{ Seq []ggfnt.GlyphIndex; Matches bool }{
+		{[]ggfnt.GlyphIndex{0, 1, 2, 3}, true},
+		{[]ggfnt.GlyphIndex{0, 1, 2, 3, 0}, true},
+		{[]ggfnt.GlyphIndex{0, 1, 3, 3}, false},
+		{[]ggfnt.GlyphIndex{1, 1, 2, 3}, false},
+		{[]ggfnt.GlyphIndex{0, 1, 2}, false},
+	}
+	for _, test := range tests {
+		if rule.Matches(font, test.Seq) != test.Matches {
+			t.Fatalf("expected GlyphRewriteRule.Matches(%v) to be %t", test.Seq, test.Matches)
+		}
+	}
+}
+
+func TestGlyphRewriteSetContains(t *testing.T) {
+	builder := New()
+	var uids [6]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	glyphSetUID, err := builder.CreateGlyphSet()
+	if err != nil { t.Fatalf("unexpected FontBuilder.CreateGlyphSet() error: %s", err) }
+	err = builder.AddGlyphSetRange(glyphSetUID, uids[1], uids[3])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphSetRange() error: %s", err) }
+	err = builder.AddGlyphSetListGlyph(glyphSetUID, uids[5])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphSetListGlyph() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	glyphSet := font.Rewrites().GetGlyphSet(0)
+	if glyphSet.Size() != 4 { t.Fatalf("expected glyph set size 4, got %d", glyphSet.Size()) }
+	for glyphIndex, expected := range []bool{false, true, true, true, false, true} {
+		if glyphSet.Contains(ggfnt.GlyphIndex(glyphIndex)) != expected {
+			t.Fatalf("expected GlyphRewriteSet.Contains(%d) to be %t", glyphIndex, expected)
+		}
+	}
+}
+
+func TestRuneSets(t *testing.T) {
+	builder := New()
+	_, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	unusedSetUID, err := builder.CreateRuneSet()
+	if err != nil { t.Fatalf("unexpected FontBuilder.CreateRuneSet() error: %s", err) }
+	setUID, err := builder.CreateRuneSet()
+	if err != nil { t.Fatalf("unexpected FontBuilder.CreateRuneSet() error: %s", err) }
+	if !builder.RemoveRuneSet(unusedSetUID) { t.Fatalf("expected FontBuilder.RemoveRuneSet() to succeed") }
+	err = builder.AddRuneSetRange(setUID, '0', '9')
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddRuneSetRange() error: %s", err) }
+	for _, codePoint := range []rune{'x', 'y'} {
+		err = builder.AddRuneSetListRune(setUID, codePoint)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddRuneSetListRune() error: %s", err) }
+	}
+	if !builder.RemoveRuneSetListRune(setUID, 'y') { t.Fatalf("expected FontBuilder.RemoveRuneSetListRune() to succeed") }
+	err = builder.AddUtf8RewriteRule(0, 2, 0, []any{'#', setUID}, 'N')
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddUtf8RewriteRule() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	// check set data
+	if font.Rewrites().NumUTF8Sets() != 1 { t.Fatalf("expected 1 utf8 set, got %d", font.Rewrites().NumUTF8Sets()) }
+	set := font.Rewrites().GetUtf8Set(0)
+	if set.Size() != 11 { t.Fatalf("expected utf8 set size 11, got %d", set.Size()) }
+	var ranges [][2]rune
+	var list []rune
+	_ = set.EachRange(func(start, end rune) error { ranges = append(ranges, [2]rune{start, end}) ; return nil })
+	_ = set.EachListRune(func(codePoint rune) error { list = append(list, codePoint) ; return nil })
+	if !slices.Equal(ranges, [][2]rune{{'0', '9'}}) { t.Fatalf("expected utf8 set ranges [0-9], got %v", ranges) }
+	if !slices.Equal(list, []rune{'x'}) { t.Fatalf("expected utf8 set list [x], got %q", list) }
+	err = font.Rewrites().Validate(ggfnt.FmtStrict)
+	if err != nil { t.Fatalf("unexpected FontRewrites.Validate() error: %s", err) }
+
+	// apply rule
+	text, err := rerules.ApplyUtf8Rewrites(font, ggfnt.NewSettingsCache(font), "#1 #x #y")
+	if err != nil { t.Fatalf("unexpected ApplyUtf8Rewrites() error: %s", err) }
+	if text != "N N #y" { t.Fatalf("expected text \"N N #y\", got %q", text) }
+
+	// round trip
+	rebuilt, err := NewFrom(font).Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error after NewFrom(): %s", err) }
+	if !bytes.Equal(rebuilt.Data, font.Data) { t.Fatalf("expected NewFrom() round trip to preserve font data") }
+}
+
+func TestConditionalGlyphRewriteRule(t *testing.T) {
+	builder := New()
+	var uids [2]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	err := builder.AddConditionalGlyphRewriteRule(0, 0, 1, 0, []uint64{uids[0]}, uids[1])
+	if err == nil { t.Fatalf("expected FontBuilder.AddConditionalGlyphRewriteRule() error with undefined condition") }
+	settingKey, err := builder.AddSetting("alt", "off", "on")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	conditionKey, err := builder.AddRewriteCondition("#0 == 1")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddRewriteCondition() error: %s", err) }
+	err = builder.AddConditionalGlyphRewriteRule(conditionKey, 0, 1, 0, []uint64{uids[0]}, uids[1])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddConditionalGlyphRewriteRule() error: %s", err) }
+	err = builder.AddGlyphRewriteRule(0, 0, 0, nil, uids[1])
+	if err == nil { t.Fatalf("expected FontBuilder.AddGlyphRewriteRule() error with empty body") }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	rule := font.Rewrites().GetGlyphRule(0)
+	if rule.Condition() != conditionKey { t.Fatalf("expected rule condition %d, got %d", conditionKey, rule.Condition()) }
+	settings := ggfnt.NewSettingsCache(font)
+	for option, expected := range []ggfnt.GlyphIndex{0, 1} {
+		settings.Set(settingKey, uint8(option))
+		glyphs, err := rerules.ApplyGlyphRewrites(font, settings, []ggfnt.GlyphIndex{0})
+		if err != nil { t.Fatalf("unexpected ApplyGlyphRewrites() error: %s", err) }
+		if !slices.Equal(glyphs, []ggfnt.GlyphIndex{expected}) {
+			t.Fatalf("with setting option %d, expected glyphs [%d], got %v", option, expected, glyphs)
+		}
+	}
+}
+
+func TestSettingsTotalCombinations(t *testing.T) {
+	builder := New()
+	_, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	if font.Settings().TotalCombinations() != 1 {
+		t.Fatalf("expected 1 combination without settings, got %d", font.Settings().TotalCombinations())
+	}
+
+	_, err = builder.AddSetting("empty")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	_, err = builder.AddSetting("style", "regular", "bold", "italic")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	_, err = builder.AddSetting("size", "small", "large")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	font, err = builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	if font.Settings().TotalCombinations() != 6 {
+		t.Fatalf("expected 6 combinations, got %d", font.Settings().TotalCombinations())
+	}
+	for _, mode := range []ggfnt.FmtValidation{ggfnt.FmtDefault, ggfnt.FmtStrict} {
+		err = font.Settings().Validate(mode)
+		if err != nil { t.Fatalf("unexpected FontSettings.Validate() error with option-less setting: %s", err) }
+	}
+
+	// saturation
+	options := make([]string, 250)
+	for i := range options { options[i] = fmt.Sprintf("opt%d", i) }
+	for i := 0; i < 9; i++ {
+		_, err = builder.AddSetting(fmt.Sprintf("big%d", i), options...)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	}
+	font, err = builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	if font.Settings().TotalCombinations() != math.MaxUint64 {
+		t.Fatalf("expected saturated combinations, got %d", font.Settings().TotalCombinations())
+	}
+}
+
+func TestSwitchCaseCount(t *testing.T) {
+	builder := New()
+	_, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	styleKey, err := builder.AddSetting("style", "regular", "bold", "italic")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	sizeKey, err := builder.AddSetting("size", "small", "large")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	styleSwitch, err := builder.AddSwitch(styleKey)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	bothSwitch, err := builder.AddSwitch(styleKey, sizeKey)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	mapping := font.Mapping()
+	tests := []struct{ Switch uint8; Cases int }{ {styleSwitch, 3}, {bothSwitch, 6}, {254, 1}, {255, 1} }
+	for _, test := range tests {
+		if mapping.SwitchCaseCount(test.Switch) != test.Cases {
+			t.Fatalf("expected switch #%d to have %d cases, got %d", test.Switch, test.Cases, mapping.SwitchCaseCount(test.Switch))
+		}
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	builder := New()
+	var uids [2]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	err := builder.AddGlyphRewriteRule(0, 1, 0, []uint64{uids[0]}, uids[1])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphRewriteRule() error: %s", err) }
+	builder.SetKerningPair(uids[0], uids[1], -1)
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	errs := font.ValidateAll(ggfnt.FmtStrict)
+	if errs != nil { t.Fatalf("unexpected Font.ValidateAll() errors: %v", errs) }
+
+	// corrupt rewrite rule output and kerning pair (data is shared)
+	rule := font.Rewrites().GetGlyphRule(0)
+	rule.Data[5] = 9
+	font.Data[font.OffsetToHorzKernings + 3 + 2] = 9 // prev glyph index low byte
+	errs = font.ValidateAll(ggfnt.FmtStrict)
+	if len(errs) != 2 { t.Fatalf("expected 2 Font.ValidateAll() errors, got %v", errs) }
+	if !strings.HasPrefix(errs[0].Error(), "rewrites: ") || !strings.HasPrefix(errs[1].Error(), "kerning: ") {
+		t.Fatalf("unexpected Font.ValidateAll() errors: %v", errs)
+	}
+	if font.Validate(ggfnt.FmtStrict) == nil { t.Fatalf("expected Font.Validate() error") }
+}
+
+func TestParseErrorContext(t *testing.T) {
+	builder := New()
+	uid, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	err = builder.Map(' ', uid)
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	var buffer bytes.Buffer
+	err = font.ExportRaw(&buffer)
+	if err != nil { t.Fatalf("unexpected Font.ExportRaw() error: %s", err) }
+	rawData := buffer.Bytes()
+
+	tests := []struct{ Data []byte; Section string; Offset int }{
+		{ append([]byte{'x'}, rawData[1 : ]...), "signature", 0 },
+		{ rawData[ : len(rawData) - 1], "kerning", font.RawSize() - 3 },
+		{ append(slices.Clone(rawData), 0), "EOF", font.RawSize() },
+	}
+	for i, test := range tests {
+		_, err = ggfnt.ParseRaw(bytes.NewReader(test.Data))
+		var parseErr *ggfnt.ParseError
+		if !errors.As(err, &parseErr) { t.Fatalf("test#%d: expected *ggfnt.ParseError, got %v", i, err) }
+		if parseErr.Section != test.Section || parseErr.Offset != test.Offset {
+			t.Fatalf("test#%d: expected error at %s (%d), got %s (%d)", i, test.Section, test.Offset, parseErr.Section, parseErr.Offset)
+		}
+		if !strings.HasPrefix(err.Error(), "ggfnt parsing error at byte ") {
+			t.Fatalf("test#%d: unexpected error message '%s'", i, err.Error())
+		}
+	}
+}
+
+func TestRenderSpecimen(t *testing.T) {
+	builder := New()
+	glyphMask := image.NewAlpha(image.Rect(0, -2, 2, 0))
+	glyphMask.SetAlpha(0, -2, color.Alpha{255})
+	glyphMask.SetAlpha(1, -1, color.Alpha{1})
+	for i := 0; i < 17; i++ { // two rows
+		_, err := builder.AddGlyph(glyphMask)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+	}
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	palette := []color.RGBA{{}, {255, 0, 0, 255}}
+	img := ggfnt.RenderSpecimen(font, palette)
+	bounds := img.Bounds()
+	if bounds.Min != (image.Point{}) || bounds.Dx() % 16 != 0 || bounds.Dy() % 2 != 0 {
+		t.Fatalf("expected specimen with 16 columns and 2 rows, got bounds %v", bounds)
+	}
+	cellWidth, cellHeight := bounds.Dx()/16, bounds.Dy()/2
+	ascent, _ := font.Metrics().InkBox()
+	for _, cell := range []image.Point{{0, 0}, {0, 1}} {
+		// glyph pixels, below the label
+		originX := cell.X*cellWidth + 2
+		originY := cell.Y*cellHeight + 2*2 + 5 + ascent
+		red, white := color.RGBA{255, 0, 0, 255}, color.RGBA{255, 255, 255, 255}
+		if img.At(originX + 1, originY - 1) != red || img.At(originX, originY - 2) != white {
+			t.Fatalf("cell %v: unexpected glyph colors %v, %v", cell, img.At(originX + 1, originY - 1), img.At(originX, originY - 2))
+		}
+	}
+
+	// label pixels, "0" top row is fully set
+	labelColor := color.RGBA{128, 128, 128, 255}
+	for x := 2; x < 2 + 3; x++ {
+		if img.At(x, 2) != labelColor { t.Fatalf("expected label pixel at (%d, 2)", x) }
+	}
+
+	// monospaced fonts use the mono width for the cells
+	builder.SetMonoWidth(9)
+	font, err = builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	bounds = ggfnt.RenderSpecimen(font, palette).Bounds()
+	if bounds.Dx() != 16*(9 + 2*2) { t.Fatalf("expected mono cells of width %d, got bounds %v", 9 + 2*2, bounds) }
+}
+
+func TestAddColoredGlyphsFromGrid(t *testing.T) {
+	builder := New()
+	_, err := builder.AddDye("main", []uint8{255})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddDye() error: %s", err) }
+	_, err = builder.AddPalette("fx", []color.RGBA{{255, 0, 0, 255}})
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddPalette() error: %s", err) }
+
+	// 2x1 grid of 2x2 cells
+	palette := color.Palette{color.RGBA{}, color.RGBA{255, 0, 0, 255}, color.RGBA{255, 255, 255, 255}}
+	sheet := image.NewPaletted(image.Rect(0, 0, 4, 2), palette)
+	sheet.SetColorIndex(0, 0, 2)
+	sheet.SetColorIndex(3, 1, 1)
+	uids, err := builder.AddColoredGlyphsFromGrid(sheet, 2, 2, 0, 2)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddColoredGlyphsFromGrid() error: %s", err) }
+	if len(uids) != 2 { t.Fatalf("expected 2 glyphs to be added, got %d", len(uids)) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	tests := []struct{ Glyph ggfnt.GlyphIndex; X, Y int; Index uint8 }{
+		{0, 0, -2, 255}, {1, 1, -1, 254},
+	}
+	for _, test := range tests {
+		glyphMask := font.Glyphs().RasterizeMask(test.Glyph)
+		if glyphMask.Rect != image.Rect(test.X, test.Y, test.X + 1, test.Y + 1) {
+			t.Fatalf("glyph %d: unexpected mask bounds %v", test.Glyph, glyphMask.Rect)
+		}
+		if glyphMask.AlphaAt(test.X, test.Y).A != test.Index {
+			t.Fatalf("glyph %d: expected color index %d, got %d", test.Glyph, test.Index, glyphMask.AlphaAt(test.X, test.Y).A)
+		}
+	}
+
+	// palette colors must be defined on the font
+	sheet.Palette = append(sheet.Palette, color.RGBA{0, 255, 0, 255})
+	_, err = builder.AddColoredGlyphsFromGrid(sheet, 2, 2, 0, 2)
+	if err == nil { t.Fatalf("expected FontBuilder.AddColoredGlyphsFromGrid() to fail with unknown palette color") }
+}
+
+func TestVerifyFile(t *testing.T) {
+	builder := New()
+	uid, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	err = builder.Map(' ', uid)
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	var buffer bytes.Buffer
+	err = font.Export(&buffer)
+	if err != nil { t.Fatalf("unexpected Font.Export() error: %s", err) }
+	data := buffer.Bytes()
+
+	err = ggfnt.VerifyFile(bytes.NewReader(data))
+	if err != nil { t.Fatalf("unexpected ggfnt.VerifyFile() error: %s", err) }
+	err = ggfnt.VerifyFile(bytes.NewReader(data[ : len(data) - 4]))
+	if err == nil { t.Fatalf("expected ggfnt.VerifyFile() to fail on truncated data") }
+	corrupted := slices.Clone(data)
+	corrupted[len(corrupted) - 5] ^= 0xFF // gzip crc32
+	err = ggfnt.VerifyFile(bytes.NewReader(corrupted))
+	if err == nil { t.Fatalf("expected ggfnt.VerifyFile() to fail on bad checksum") }
+	err = ggfnt.VerifyFile(bytes.NewReader(append([]byte{'x'}, data[1 : ]...)))
+	if err == nil { t.Fatalf("expected ggfnt.VerifyFile() to fail on bad signature") }
+}
+
+func TestKerningByName(t *testing.T) {
+	builder := New()
+	var uids [2]uint64
+	for i, name := range []string{"A", "V"} {
+		uid, err := builder.AddBlankGlyph(4)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		err = builder.SetGlyphName(uid, name)
+		if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphName() error: %s", err) }
+		uids[i] = uid
+	}
+
+	err := builder.SetKerningByName("A", "V", -2)
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetKerningByName() error: %s", err) }
+	kerning, found := builder.GetKerningPair(uids[0], uids[1])
+	if !found || kerning != -2 { t.Fatalf("expected kerning -2 for 'A' 'V', got %d (found = %t)", kerning, found) }
+	kerning, found = builder.GetKerningByName("A", "V")
+	if !found || kerning != -2 { t.Fatalf("expected GetKerningByName() kerning -2, got %d (found = %t)", kerning, found) }
+	if _, found = builder.GetKerningByName("V", "A"); found { t.Fatalf("expected no kerning for 'V' 'A'") }
+	if _, found = builder.GetKerningByName("A", "W"); found { t.Fatalf("expected no kerning for unknown glyph 'W'") }
+	err = builder.SetKerningByName("W", "A", 1)
+	if err == nil { t.Fatalf("expected FontBuilder.SetKerningByName() to fail with unknown glyph name") }
+}
+
+func TestAddGlyphSharingMask(t *testing.T) {
+	builder := New()
+	glyphMask := image.NewAlpha(image.Rect(0, -2, 2, 0))
+	glyphMask.SetAlpha(1, -2, color.Alpha{255})
+	uid, err := builder.AddGlyph(glyphMask)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+	placement := ggfnt.GlyphPlacement{ Advance: 0, TopAdvance: 1, BottomAdvance: 1, HorzCenter: 1 }
+	sharedUID, err := builder.AddGlyphSharingMask(uid, placement)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphSharingMask() error: %s", err) }
+	_, err = builder.AddGlyphSharingMask(sharedUID + 1, placement)
+	if err == nil { t.Fatalf("expected FontBuilder.AddGlyphSharingMask() to fail with unknown glyph") }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	if font.Glyphs().Count() != 2 { t.Fatalf("expected 2 glyphs, got %d", font.Glyphs().Count()) }
+	original, shared := font.Glyphs().RasterizeMask(0), font.Glyphs().RasterizeMask(1)
+	if original.Rect != shared.Rect || !slices.Equal(original.Pix, shared.Pix) {
+		t.Fatalf("expected shared glyph mask to match the original")
+	}
+	if font.Glyphs().Advance(0) != 2 || font.Glyphs().Advance(1) != 0 {
+		t.Fatalf("expected advances 2 and 0, got %d and %d", font.Glyphs().Advance(0), font.Glyphs().Advance(1))
+	}
+}
+
+func TestRuneSetLimits(t *testing.T) {
+	builder := New()
+	_, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	setUID, err := builder.CreateRuneSet()
+	if err != nil { t.Fatalf("unexpected FontBuilder.CreateRuneSet() error: %s", err) }
+	for i := rune(0); i < 255; i++ {
+		err = builder.AddRuneSetRange(setUID, 'A' + i*4, 'A' + i*4 + 2)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddRuneSetRange() error: %s", err) }
+		err = builder.AddRuneSetListRune(setUID, 'A' + i*4 + 3)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddRuneSetListRune() error: %s", err) }
+	}
+	err = builder.AddRuneSetRange(setUID, 'a', 'z')
+	if err == nil { t.Fatalf("expected FontBuilder.AddRuneSetRange() to fail with more than 255 ranges") }
+	err = builder.AddRuneSetListRune(setUID, '#')
+	if err == nil { t.Fatalf("expected FontBuilder.AddRuneSetListRune() to fail with more than 255 runes") }
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	if font.Rewrites().NumUTF8Sets() != 1 { t.Fatalf("expected 1 utf8 set, got %d", font.Rewrites().NumUTF8Sets()) }
+	set := font.Rewrites().GetUtf8Set(0)
+	if set.Size() != 255*4 { t.Fatalf("expected utf8 set size %d, got %d", 255*4, set.Size()) }
+}
+
+func TestDeduplicateGlyphSets(t *testing.T) {
+	builder := New()
+	var uids [4]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	var setUIDs [3]uint64
+	for i := range setUIDs {
+		setUID, err := builder.CreateGlyphSet()
+		if err != nil { t.Fatalf("unexpected FontBuilder.CreateGlyphSet() error: %s", err) }
+		setUIDs[i] = setUID
+	}
+	err := builder.AddGlyphSetRange(setUIDs[0], uids[1], uids[2])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphSetRange() error: %s", err) }
+	for _, uid := range []uint64{uids[2], uids[1]} { // same membership as set #0
+		err = builder.AddGlyphSetListGlyph(setUIDs[1], uid)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphSetListGlyph() error: %s", err) }
+	}
+	err = builder.AddGlyphSetListGlyph(setUIDs[2], uids[3])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphSetListGlyph() error: %s", err) }
+	err = builder.AddGlyphRewriteRule(0, 2, 0, []uint64{setUIDs[1], setUIDs[2]}, uids[0])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphRewriteRule() error: %s", err) }
+
+	if builder.DeduplicateGlyphSets() != 1 { t.Fatalf("expected one glyph set to be removed") }
+	if !slices.Equal(builder.glyphSetsOrder, []uint64{setUIDs[0], setUIDs[2]}) {
+		t.Fatalf("expected glyph sets #0 and #2 to survive")
+	}
+	if !slices.Equal(builder.glyphRules[0].inGroups, []uint64{setUIDs[0], setUIDs[2]}) {
+		t.Fatalf("expected glyph rule to reference the surviving glyph set")
+	}
+	if builder.DeduplicateGlyphSets() != 0 { t.Fatalf("expected no more glyph sets to be removed") }
+
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	if font.Rewrites().NumGlyphSets() != 2 { t.Fatalf("expected 2 glyph sets, got %d", font.Rewrites().NumGlyphSets()) }
+	glyphs, err := rerules.ApplyGlyphRewrites(font, ggfnt.NewSettingsCache(font), []ggfnt.GlyphIndex{1, 3, 2, 3})
+	if err != nil { t.Fatalf("unexpected ApplyGlyphRewrites() error: %s", err) }
+	if !slices.Equal(glyphs, []ggfnt.GlyphIndex{0, 0}) { t.Fatalf("expected glyphs [0 0], got %v", glyphs) }
+}
+
+func TestValidationReport(t *testing.T) {
+	builder := New()
+	report := builder.Validate()
+	if report.HasErrors() { t.Fatalf("unexpected validation errors: %v", report.Issues) }
+	if !slices.ContainsFunc(report.Issues, func(issue ValidationIssue) bool { return issue.Category == "glyphs" }) {
+		t.Fatalf("expected a glyphs warning for a font without glyphs, got %v", report.Issues)
+	}
+
+	uid, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	builder.SetKerningPair(uid, uid + 1, -1) // unknown glyph
+	err = builder.SetFirstVerDate(ggfnt.Date{ Year: 2024 })
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetFirstVerDate() error: %s", err) }
+	err = builder.SetMinorVerDate(ggfnt.Date{ Year: 2023 })
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetMinorVerDate() error: %s", err) }
+
+	report = builder.Validate()
+	if !report.HasErrors() { t.Fatalf("expected validation errors") }
+	var errCategories []string
+	for _, issue := range report.Issues {
+		if issue.Severity == SeverityError { errCategories = append(errCategories, issue.Category) }
+	}
+	if !slices.Equal(errCategories, []string{"header", "kerning"}) {
+		t.Fatalf("expected header and kerning errors, got %v", report.Issues)
+	}
+}
+
+func TestMapRun(t *testing.T) {
+	builder := New()
+	var uids [3]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	setting, err := builder.AddSetting("alt", "off", "on")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	switchKey, err := builder.AddSwitch(setting)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	err = builder.Map('a', uids[0])
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	err = builder.MapWithSwitchSingles('b', switchKey, uids[1], uids[2])
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitchSingles() error: %s", err) }
+	err = builder.MapGroup('c', 0, uids[2], uids[0])
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapGroup() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	settings := ggfnt.NewSettingsCache(font)
+	for _, option := range []uint8{0, 1} {
+		settings.Set(setting, option)
+		runes := []rune("abbcaz")
+		out := make([]ggfnt.GlyphMappingGroup, 8)
+		n := font.Mapping().MapRun(runes, settings, out)
+		if n != 5 { t.Fatalf("expected 5 code points mapped before 'z', got %d", n) }
+		for i := 0; i < n; i++ {
+			expected, found := font.Mapping().Utf8WithCache(runes[i], settings)
+			if !found { t.Fatalf("Utf8WithCache(%q) not found", runes[i]) }
+			if out[i].Size() != expected.Size() || out[i].Select(0) != expected.Select(0) {
+				t.Fatalf("option %d, %q: MapRun group doesn't match Utf8WithCache group", option, runes[i])
+			}
+		}
+		if font.Mapping().MapRun(runes, settings, out[ : 2]) != 2 {
+			t.Fatalf("expected MapRun to stop when out is full")
+		}
+	}
+}
+
+func TestKerningConflicts(t *testing.T) {
+	builder := New()
+	var uids [3]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(4)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	builder.kerningClasses = append(builder.kerningClasses, editionKerningClass{ Name: "round", Value: -2 })
+	builder.SetKerningPair(uids[0], uids[1], -1)
+	builder.SetVertKerningPair(uids[1], uids[2], 1)
+	builder.horzKerningPairs[[2]uint64{uids[2], uids[0]}] = &editionKerningPair{ First: uids[2], Second: uids[0], Class: 1 }
+	if len(builder.KerningConflicts()) != 0 { t.Fatalf("expected no kerning conflicts") }
+
+	builder.horzKerningPairs[[2]uint64{uids[0], uids[1]}].Class = 1
+	builder.vertKerningPairs[[2]uint64{uids[1], uids[2]}].Class = 1
+	expected := []KerningConflict{
+		{ First: uids[0], Second: uids[1], Vertical: false, Value: -1, ClassValue: -2 },
+		{ First: uids[1], Second: uids[2], Vertical: true, Value: 1, ClassValue: -2 },
+	}
+	conflicts := builder.KerningConflicts()
+	if !slices.Equal(conflicts, expected) { t.Fatalf("expected kerning conflicts %v, got %v", expected, conflicts) }
+	kerning, _ := builder.GetKerningPair(uids[0], uids[1])
+	if kerning != -2 { t.Fatalf("expected class kerning value -2 to take precedence, got %d", kerning) }
+}
+
+func TestAutoPlaceGlyph(t *testing.T) {
+	builder := New()
+	builder.SetVertLayoutUsed(true)
+	glyphMask := image.NewAlpha(image.Rect(0, -2, 3, 0))
+	glyphMask.SetAlpha(1, -2, color.Alpha{255})
+	glyphMask.SetAlpha(2, -1, color.Alpha{255})
+	uid, err := builder.AddGlyph(glyphMask)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+
+	// advances with spacing beyond the ink must be preserved
+	err = builder.SetGlyphPlacement(uid, ggfnt.GlyphPlacement{ Advance: 5 })
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphPlacement() error: %s", err) }
+	err = builder.AutoPlaceGlyph(uid)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AutoPlaceGlyph() error: %s", err) }
+	placement, _ := builder.GetGlyphPlacement(uid)
+	expected := ggfnt.GlyphPlacement{ Advance: 5, TopAdvance: builder.GetAscent(), BottomAdvance: builder.GetDescent(), HorzCenter: 2 }
+	if placement != expected { t.Fatalf("expected placement %+v, got %+v", expected, placement) }
+
+	// zero advances are set to the ink's right edge
+	err = builder.SetGlyphPlacement(uid, ggfnt.GlyphPlacement{})
+	if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphPlacement() error: %s", err) }
+	err = builder.AutoPlaceGlyph(uid)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AutoPlaceGlyph() error: %s", err) }
+	placement, _ = builder.GetGlyphPlacement(uid)
+	if placement.Advance != 3 { t.Fatalf("expected advance 3, got %d", placement.Advance) }
+}
+
+func TestGlyphsForRunes(t *testing.T) {
+	builder := New()
+	var uids [6]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	setting, err := builder.AddSetting("alt", "off", "on")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	switchKey, err := builder.AddSwitch(setting)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	err = builder.Map('a', uids[0])
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	groups := [][]uint64{ []uint64{uids[1], uids[2], uids[3]}, []uint64{uids[5], uids[4]} } // range, list
+	animFlags := []ggfnt.AnimationFlags{ ggfnt.AnimFlagLoopable, ggfnt.AnimFlagSequential }
+	err = builder.MapWithSwitch('b', switchKey, groups, animFlags)
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitch() error: %s", err) }
+	err = builder.MapGroup('c', 0, uids[4], uids[5])
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapGroup() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	tests := []struct{ Runes string; Settings []uint8; Glyphs []ggfnt.GlyphIndex }{
+		{"a", nil, []ggfnt.GlyphIndex{0}},
+		{"b", nil, []ggfnt.GlyphIndex{1, 2, 3, 4, 5}},
+		{"b", []uint8{0}, []ggfnt.GlyphIndex{1, 2, 3}},
+		{"ab", []uint8{1}, []ggfnt.GlyphIndex{0, 4, 5}},
+		{"cz", []uint8{0}, []ggfnt.GlyphIndex{4, 5}},
+		{"", nil, nil},
+	}
+	for _, test := range tests {
+		var glyphs []ggfnt.GlyphIndex
+		for glyphIndex, _ := range font.GlyphsForRunes([]rune(test.Runes), test.Settings) {
+			glyphs = append(glyphs, glyphIndex)
+		}
+		slices.Sort(glyphs)
+		if !slices.Equal(glyphs, test.Glyphs) {
+			t.Fatalf("GlyphsForRunes(%q, %v) expected %v, got %v", test.Runes, test.Settings, test.Glyphs, glyphs)
+		}
+	}
+}
+
+func TestApplyMetadataJSONDates(t *testing.T) {
+	builder := New()
+	err := builder.ApplyMetadataJSON([]byte(`{"name": "Dates", "firstVersionDate": "2024-00-00", "minorVersionDate": "0000-00-00"}`))
+	if err != nil { t.Fatalf("unexpected FontBuilder.ApplyMetadataJSON() error: %s", err) }
+	if builder.firstVersionDate != (ggfnt.Date{ Year: 2024 }) || builder.minorVersionDate != (ggfnt.Date{}) {
+		t.Fatalf("unexpected version dates %s and %s", builder.firstVersionDate.ISOString(), builder.minorVersionDate.ISOString())
+	}
+
+	majorVersionDate := builder.majorVersionDate
+	for _, date := range []string{"2024-00-05", "0000-03-00", "0000-00-01", "2023-02-29", "2024-13-01", "2024-1-01"} {
+		err = builder.ApplyMetadataJSON([]byte(`{"name": "Changed", "majorVersionDate": "` + date + `"}`))
+		if err == nil { t.Fatalf("expected FontBuilder.ApplyMetadataJSON() to fail with invalid date '%s'", date) }
+		if builder.fontName != "Dates" || builder.majorVersionDate != majorVersionDate {
+			t.Fatalf("expected header to be restored after failing with date '%s'", date)
+		}
+	}
+}
+
+func TestStrictGlyphsValidation(t *testing.T) {
+	builder := New()
+	ascent := int(builder.GetAscent())
+	glyphMask := image.NewAlpha(image.Rect(0, -ascent, 2, 0))
+	glyphMask.SetAlpha(0, -ascent, color.Alpha{255})
+	for _, name := range []string{"a", "b"} {
+		uid, err := builder.AddGlyph(glyphMask)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+		err = builder.SetGlyphName(uid, name)
+		if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphName() error: %s", err) }
+	}
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	err = font.Glyphs().Validate(ggfnt.FmtStrict)
+	if err != nil { t.Fatalf("unexpected FontGlyphs.Validate() error: %s", err) }
+
+	namedGlyphsIndex := int(font.OffsetToGlyphNames) + 2
+	namesIndex := namedGlyphsIndex + 2*2 + 2*3
+	tests := []struct{ Index int; Value uint8; Reason string }{
+		{ namesIndex, 'c', "unsorted names" },
+		{ namedGlyphsIndex, 9, "out of range glyph index" },
+		{ int(font.OffsetToMetrics) + 4, uint8(ascent - 1), "mask taller than ascent" },
+	}
+	for _, test := range tests {
+		prevValue := font.Data[test.Index]
+		font.Data[test.Index] = test.Value
+		err = font.Glyphs().Validate(ggfnt.FmtStrict)
+		if err == nil { t.Fatalf("expected FontGlyphs.Validate() to fail with %s", test.Reason) }
+		if font.Glyphs().Validate(ggfnt.FmtDefault) != nil {
+			t.Fatalf("expected %s to be detected only on strict validation", test.Reason)
+		}
+		font.Data[test.Index] = prevValue
+	}
+}
+
+func TestParseOldFormatVersion(t *testing.T) {
+	builder := New()
+	uid, err := builder.AddBlankGlyph(3)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	err = builder.Map(' ', uid)
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	var buffer bytes.Buffer
+	err = font.ExportRaw(&buffer)
+	if err != nil { t.Fatalf("unexpected Font.ExportRaw() error: %s", err) }
+	rawData := buffer.Bytes()
+
+	rawData[6] = uint8(ggfnt.FormatVersion - 1) // FormatVersion, right after the signature
+	_, err = ggfnt.ParseRaw(bytes.NewReader(rawData))
+	if err == nil || !strings.Contains(err.Error(), "no longer supported") {
+		t.Fatalf("expected ggfnt.ParseRaw() to reject older format versions, got %v", err)
+	}
+}
+
+func TestValidateMappingsOrder(t *testing.T) {
+	builder := New()
+	for codePoint := 'z'; codePoint >= 'a'; codePoint-- {
+		builder.runeMapping[codePoint] = mappingEntry{ SwitchType: 7 } // undefined switch
+	}
+	for i := 0; i < 8; i++ {
+		err := builder.ValidateMappings()
+		if err == nil { t.Fatalf("expected FontBuilder.ValidateMappings() to fail with undefined switch") }
+		if !strings.Contains(err.Error(), "'a'") {
+			t.Fatalf("expected error for the lowest code point 'a', got: %s", err)
+		}
+	}
+}
+
+func TestMappingSkipsRangeGroups(t *testing.T) {
+	builder := New()
+	var uids [5]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	setting, err := builder.AddSetting("alt", "off", "on", "extra")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	switchKey, err := builder.AddSwitch(setting)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	groups := [][]uint64{ []uint64{uids[0], uids[1], uids[2]}, []uint64{uids[4], uids[3]}, []uint64{uids[3]} } // range, list, single
+	animFlags := []ggfnt.AnimationFlags{ ggfnt.AnimFlagLoopable, ggfnt.AnimFlagSequential }
+	err = builder.MapWithSwitch('a', switchKey, groups, animFlags)
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitch() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	mapping := font.Mapping()
+	settings := ggfnt.NewSettingsCache(font)
+	for option, expected := range [][]ggfnt.GlyphIndex{{0, 1, 2}, {4, 3}, {3}} {
+		settings.Set(setting, uint8(option))
+		for i := 0; i < 2; i++ {
+			var group ggfnt.GlyphMappingGroup
+			var found bool
+			if i == 0 {
+				group, found = mapping.Utf8('a', settings.UnsafeSlice())
+			} else {
+				group, found = mapping.Utf8WithCache('a', settings)
+			}
+			if !found { t.Fatalf("option %d: expected 'a' to be mapped", option) }
+			var glyphs []ggfnt.GlyphIndex
+			for n := uint8(0); n < group.Size(); n++ { glyphs = append(glyphs, group.Select(n)) }
+			if !slices.Equal(glyphs, expected) {
+				t.Fatalf("option %d (cached = %t): expected glyphs %v, got %v", option, i == 1, expected, glyphs)
+			}
+		}
+	}
+}
+
+func TestGlyphSetRangeValidation(t *testing.T) {
+	builder := New()
+	var uids [3]uint64
+	for i := range uids {
+		uid, err := builder.AddBlankGlyph(uint8(i + 1))
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+		uids[i] = uid
+	}
+	setUID, err := builder.CreateGlyphSet()
+	if err != nil { t.Fatalf("unexpected FontBuilder.CreateGlyphSet() error: %s", err) }
+	err = builder.AddGlyphSetRange(setUID, uids[0], uids[2])
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyphSetRange() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	err = font.Rewrites().Validate(ggfnt.FmtStrict)
+	if err != nil { t.Fatalf("unexpected FontRewrites.Validate() error: %s", err) }
+	glyphSet := font.Rewrites().GetGlyphSet(0)
+	tests := []struct{ First uint16; Length uint8 }{
+		{1, 2}, // last beyond glyph count
+		{uint16(ggfnt.GlyphMissing), 0},
+		{uint16(ggfnt.GlyphMissing) - 10, 10},
+		{uint16(ggfnt.GlyphMissing), 2}, // other control glyphs
+	}
+	for _, test := range tests {
+		glyphSet.Data[1], glyphSet.Data[2] = uint8(test.First), uint8(test.First >> 8) // shares font data
+		glyphSet.Data[3] = test.Length
+		err = font.Rewrites().Validate(ggfnt.FmtStrict)
+		if err == nil { t.Fatalf("expected FontRewrites.Validate() to fail with range (%d, +%d)", test.First, test.Length) }
+	}
+}
+
+func TestEvaluateSwitch(t *testing.T) {
+	builder := New()
+	_, err := builder.AddBlankGlyph(1)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	first, err := builder.AddSetting("first", "a", "b", "c")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	second, err := builder.AddSetting("second", "x", "y")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	singleKey, err := builder.AddSwitch(second)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	doubleKey, err := builder.AddSwitch(first, second)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	font, err := builder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	mapping := font.Mapping()
+	for a := uint8(0); a < 3; a++ {
+		for b := uint8(0); b < 2; b++ {
+			settings := []uint8{a, b}
+			if mapping.EvaluateSwitch(uint8(singleKey), settings) != b {
+				t.Fatalf("settings %v: expected single setting switch case %d", settings, b)
+			}
+			switchCase := mapping.EvaluateSwitch(uint8(doubleKey), settings)
+			if switchCase != a*2 + b { t.Fatalf("settings %v: expected switch case %d, got %d", settings, a*2 + b, switchCase) }
+		}
+	}
+}
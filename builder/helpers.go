@@ -11,6 +11,12 @@ func startsWith(definition string, start, end int, expr string) bool {
 	return true
 }
 
+// end is inclusive
+func skipSpaces(definition string, start, end int) int {
+	for start <= end && definition[start] == ' ' { start += 1 }
+	return start
+}
+
 // end is inclusive
 func trimSpaces(definition string, start, end int) (int, int) {
 	var changed bool = true
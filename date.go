@@ -2,6 +2,8 @@ package ggfnt
 
 import "fmt"
 import "time"
+import "cmp"
+import "errors"
 
 import "github.com/tinne26/ggfnt/internal"
 
@@ -16,6 +18,24 @@ func CurrentDate() Date {
 	return Date{ Year: uint16(year), Month: uint8(month), Day: uint8(day) }
 }
 
+// Converts a [time.Time] to a date, discarding the time of the day.
+// Years outside the [1, 65535] range can't be represented, so the
+// returned date will be completely undefined in those cases.
+func DateFromTime(t time.Time) Date {
+	year, month, day := t.Date()
+	if year < 1 || year > 65535 { return Date{} }
+	return Date{ Year: uint16(year), Month: uint8(month), Day: uint8(day) }
+}
+
+// Converts the date to a [time.Time] at midnight UTC. Missing months
+// and days are treated as January and 1st, respectively. If the year
+// is missing, the zero time is returned.
+func (self *Date) Time() time.Time {
+	if self.Year == 0 { return time.Time{} }
+	month, day := max(self.Month, 1), max(self.Day, 1)
+	return time.Date(int(self.Year), time.Month(month), int(day), 0, 0, 0, 0, time.UTC)
+}
+
 func (self *Date) appendTo(data []byte) []byte {
 	return append(internal.AppendUint16LE(data, self.Year), self.Month, self.Day)
 }
@@ -26,7 +46,7 @@ func (self *Date) IsValid() bool {
 	if self.Month == 0 && self.Day != 0 { return false }
 	if self.Year == 0 && (self.Month != 0 || self.Day != 0) { return false }
 	if self.Month > 12 { return false }
-	if self.Day > self.monthDays() { return false }
+	if self.Month != 0 && self.Day > self.monthDays() { return false }
 	return true
 }
 
@@ -72,6 +92,45 @@ func (self *Date) String() string {
 	return fmt.Sprintf("%d %s %04d", self.Day, self.MonthName(), self.Year)
 }
 
+// Returns the date in ISO "YYYY-MM-DD" format, with zeros for missing
+// parts (e.g. "1999-00-00" if only the year is known). Unlike [Date.String](),
+// this is meant for machine-readable output.
+func (self *Date) ISOString() string {
+	return fmt.Sprintf("%04d-%02d-%02d", self.Year, self.Month, self.Day)
+}
+
+// Returns -1 if the date is before other, +1 if it's after and 0 if
+// both dates are the same. Missing parts compare as zero, so partial
+// dates go before any complete dates within the same year or month.
+func (self *Date) Compare(other Date) int {
+	if self.Year != other.Year { return cmp.Compare(self.Year, other.Year) }
+	if self.Month != other.Month { return cmp.Compare(self.Month, other.Month) }
+	return cmp.Compare(self.Day, other.Day)
+}
+
+// Returns whether the date is strictly before other. See [Date.Compare]().
+func (self *Date) Before(other Date) bool { return self.Compare(other) < 0 }
+
+// Returns whether the date is strictly after other. See [Date.Compare]().
+func (self *Date) After(other Date) bool { return self.Compare(other) > 0 }
+
+// Returns an error if the given font version dates are not ordered as
+// first <= major <= minor. Undefined dates are not checked.
+func CheckVersionDatesOrder(first, major, minor Date) error {
+	dates := [3]Date{first, major, minor}
+	names := [3]string{"first", "major", "minor"}
+	for i := 0; i < 3; i++ {
+		if !dates[i].HasYear() { continue }
+		for j := i + 1; j < 3; j++ {
+			if !dates[j].HasYear() { continue }
+			if dates[i].After(dates[j]) {
+				return errors.New(names[i] + " version date can't be after the " + names[j] + " version date")
+			}
+		}
+	}
+	return nil
+}
+
 var monthNames = []string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
 func (self *Date) MonthName() string {
 	if self.Month == 0 || self.Month > 12 { return "????" }
@@ -1,6 +1,7 @@
 package ggfnt
 
 import "testing"
+import "time"
 
 func TestLeapYears(t *testing.T) {
 	tests := []struct{ Year uint16; IsLeap bool }{
@@ -17,3 +18,73 @@ func TestLeapYears(t *testing.T) {
 		}
 	}
 }
+
+func TestDateTimeConversions(t *testing.T) {
+	tests := []Date{
+		{2024, 2, 29}, {1, 1, 1}, {65535, 12, 31}, {1999, 12, 31}, {2000, 1, 1},
+	}
+	for _, date := range tests {
+		back := DateFromTime(date.Time())
+		if back != date {
+			t.Fatalf("expected %s after time conversion round trip, got %s", date.String(), back.String())
+		}
+	}
+
+	// partial and undefined dates
+	partial := Date{ Year: 2021, Month: 3 }
+	if DateFromTime(partial.Time()) != (Date{2021, 3, 1}) {
+		t.Fatalf("expected missing day to be converted to the 1st")
+	}
+	partial = Date{ Year: 2021 }
+	if DateFromTime(partial.Time()) != (Date{2021, 1, 1}) {
+		t.Fatalf("expected missing month and day to be converted to 1 January")
+	}
+	var unknown Date
+	if !unknown.Time().IsZero() {
+		t.Fatalf("expected undefined date to be converted to the zero time")
+	}
+
+	// out of range years
+	if DateFromTime(time.Date(0, 5, 5, 0, 0, 0, 0, time.UTC)) != (Date{}) {
+		t.Fatalf("expected year 0 to result in an undefined date")
+	}
+	if DateFromTime(time.Date(65536, 1, 1, 0, 0, 0, 0, time.UTC)) != (Date{}) {
+		t.Fatalf("expected year 65536 to result in an undefined date")
+	}
+}
+
+func TestDateCompare(t *testing.T) {
+	tests := []struct{ A, B Date; Result int }{
+		{Date{2024, 2, 29}, Date{2024, 2, 29}, 0},
+		{Date{2023, 12, 31}, Date{2024, 1, 1}, -1},
+		{Date{2024, 3, 1}, Date{2024, 2, 29}, 1},
+		{Date{2024, 0, 0}, Date{2024, 1, 1}, -1},
+		{Date{}, Date{1, 1, 1}, -1},
+	}
+	for _, test := range tests {
+		if test.A.Compare(test.B) != test.Result {
+			t.Fatalf("expected %s compared to %s to be %d", test.A.ISOString(), test.B.ISOString(), test.Result)
+		}
+		if test.A.Before(test.B) != (test.Result < 0) || test.A.After(test.B) != (test.Result > 0) {
+			t.Fatalf("inconsistent Before()/After() for %s and %s", test.A.ISOString(), test.B.ISOString())
+		}
+	}
+
+	date := Date{ Year: 1999, Month: 7 }
+	if date.ISOString() != "1999-07-00" {
+		t.Fatalf("expected ISO string '1999-07-00', got '%s'", date.ISOString())
+	}
+}
+
+func TestCheckVersionDatesOrder(t *testing.T) {
+	tests := []struct{ First, Major, Minor Date; Valid bool }{
+		{Date{2020, 1, 1}, Date{2021, 1, 1}, Date{2021, 1, 1}, true},
+		{Date{2020, 1, 1}, Date{}, Date{2019, 1, 1}, false},
+		{Date{}, Date{2022, 1, 1}, Date{2021, 1, 1}, false},
+		{Date{2022, 1, 1}, Date{}, Date{}, true},
+	}
+	for i, test := range tests {
+		err := CheckVersionDatesOrder(test.First, test.Major, test.Minor)
+		if (err == nil) != test.Valid { t.Fatalf("test #%d: expected valid = %t, got error %v", i, test.Valid, err) }
+	}
+}
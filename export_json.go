@@ -0,0 +1,314 @@
+package ggfnt
+
+import "io"
+import "fmt"
+import "strings"
+import "image/color"
+import "encoding/json"
+
+import "github.com/tinne26/ggfnt/internal"
+
+// JSON schema for [Font.ExportJSON](). Like [jsonMetadata], field
+// names are part of the stable schema and must not be changed.
+type jsonExport struct {
+	Metadata jsonMetadata `json:"metadata"`
+	SettingInitValues []int `json:"settingInitValues"`
+	Glyphs []jsonGlyph `json:"glyphs"`
+	Colors jsonColors `json:"colors"`
+	MappingSwitches [][]int `json:"mappingSwitches"` // setting keys
+	Mapping []jsonMappingEntry `json:"mapping"`
+	Rewrites jsonRewrites `json:"rewrites"`
+	Kerning [][3]int `json:"kerning"`
+	VertKerning [][3]int `json:"vertKerning"`
+}
+
+type jsonGlyph struct {
+	Index GlyphIndex `json:"index"`
+	Name string `json:"name,omitempty"`
+	Advance uint8 `json:"advance"`
+	TopAdvance *uint8 `json:"topAdvance,omitempty"`
+	BottomAdvance *uint8 `json:"bottomAdvance,omitempty"`
+	HorzCenter *uint8 `json:"horzCenter,omitempty"`
+	Bounds [4]int `json:"bounds"` // [minX, minY, maxX, maxY], relative to the origin
+}
+
+type jsonColors struct {
+	Dyes []jsonDye `json:"dyes"`
+	Palettes []jsonPalette `json:"palettes"`
+}
+
+type jsonDye struct {
+	Name string `json:"name"`
+	Alphas []int `json:"alphas"` // not []uint8, which would be encoded as base64
+}
+
+type jsonPalette struct {
+	Name string `json:"name"`
+	Colors []string `json:"colors"` // "#RRGGBBAA"
+}
+
+type jsonMappingEntry struct {
+	CodePoint rune `json:"codePoint"`
+	Switch uint8 `json:"switch"` // 255 for direct mappings, 254 for single groups
+	Cases []jsonMappingCase `json:"cases"`
+}
+
+type jsonMappingCase struct {
+	Glyphs []GlyphIndex `json:"glyphs"`
+	AnimationFlags AnimationFlags `json:"animationFlags"`
+}
+
+type jsonRewrites struct {
+	Conditions []string `json:"conditions"`
+	GlyphSets []jsonSet `json:"glyphSets"`
+	Utf8Sets []jsonSet `json:"utf8Sets"`
+	GlyphRules []jsonRule `json:"glyphRules"`
+	Utf8Rules []jsonRule `json:"utf8Rules"`
+}
+
+type jsonSet struct {
+	Ranges [][2]int32 `json:"ranges"` // inclusive [first, last]
+	List []int32 `json:"list"`
+}
+
+// Rule input elements are glyph indices or code points, except
+// for sets, which are exported as {"set": index} objects.
+type jsonRule struct {
+	Condition *uint8 `json:"condition,omitempty"`
+	Head []any `json:"head"`
+	Body []any `json:"body"`
+	Tail []any `json:"tail"`
+	Output []int32 `json:"output"`
+}
+
+type jsonSetRef struct {
+	Set uint8 `json:"set"`
+}
+
+// Writes a JSON document describing the whole font structure: the same
+// metadata as [Font.MetadataJSON](), setting init values, glyph names,
+// placements and bounds, dye alphas and palette colors, mapping switches
+// and the full mapping table, rewrite conditions, sets and rules, and
+// kerning pairs.
+//
+// Glyph masks are not included. Glyphs are always referenced by index,
+// and all lists are written in the same order as in the font data, so
+// the output is stable and suitable for diffing between font versions.
+func (self *Font) ExportJSON(w io.Writer) error {
+	export := jsonExport{
+		Metadata: self.jsonMetadata(),
+		SettingInitValues: []int{},
+		Glyphs: []jsonGlyph{},
+		Colors: jsonColors{ Dyes: []jsonDye{}, Palettes: []jsonPalette{} },
+		MappingSwitches: [][]int{},
+		Mapping: []jsonMappingEntry{},
+		Rewrites: jsonRewrites{
+			Conditions: []string{},
+			GlyphSets: []jsonSet{},
+			Utf8Sets: []jsonSet{},
+			GlyphRules: []jsonRule{},
+			Utf8Rules: []jsonRule{},
+		},
+		Kerning: [][3]int{},
+		VertKerning: [][3]int{},
+	}
+	settings := self.Settings()
+	for key := SettingKey(0); uint8(key) < settings.Count(); key++ {
+		export.SettingInitValues = append(export.SettingInitValues, int(settings.GetInitValue(key)))
+	}
+	self.exportJSONGlyphs(&export)
+	self.exportJSONColors(&export)
+	self.exportJSONMapping(&export)
+	self.exportJSONRewrites(&export)
+	self.Kerning().EachPair(func(prev, curr GlyphIndex, kern int8) {
+		export.Kerning = append(export.Kerning, [3]int{int(prev), int(curr), int(kern)})
+	})
+	self.Kerning().EachVertPair(func(prev, curr GlyphIndex, kern int8) {
+		export.VertKerning = append(export.VertKerning, [3]int{int(prev), int(curr), int(kern)})
+	})
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(&export)
+}
+
+func (self *Font) exportJSONGlyphs(export *jsonExport) {
+	glyphs := self.Glyphs()
+	numGlyphs := glyphs.Count()
+	hasVertLayout := self.Metrics().HasVertLayout()
+	export.Glyphs = make([]jsonGlyph, 0, numGlyphs)
+	for i := uint16(0); i < numGlyphs; i++ {
+		glyphIndex := GlyphIndex(i)
+		placement := glyphs.Placement(glyphIndex)
+		bounds := glyphs.Bounds(glyphIndex)
+		glyph := jsonGlyph{
+			Index: glyphIndex,
+			Advance: placement.Advance,
+			Bounds: [4]int{bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Max.Y},
+		}
+		if hasVertLayout {
+			glyph.TopAdvance = &placement.TopAdvance
+			glyph.BottomAdvance = &placement.BottomAdvance
+			glyph.HorzCenter = &placement.HorzCenter
+		}
+		export.Glyphs = append(export.Glyphs, glyph)
+	}
+	glyphs.EachNamed(func(glyphIndex GlyphIndex, name string) {
+		export.Glyphs[glyphIndex].Name = strings.Clone(name)
+	})
+}
+
+func (self *Font) exportJSONColors(export *jsonExport) {
+	colors := self.Color()
+	colors.EachDye(func(key DyeKey, name string) {
+		dye := jsonDye{ Name: strings.Clone(name), Alphas: []int{} }
+		colors.EachDyeAlpha(key, func(alpha uint8) {
+			dye.Alphas = append(dye.Alphas, int(alpha))
+		})
+		export.Colors.Dyes = append(export.Colors.Dyes, dye)
+	})
+	colors.EachPalette(func(key PaletteKey, name string) {
+		palette := jsonPalette{ Name: strings.Clone(name), Colors: []string{} }
+		colors.EachPaletteColor(key, func(rgba color.RGBA) {
+			hex := fmt.Sprintf("#%02X%02X%02X%02X", rgba.R, rgba.G, rgba.B, rgba.A)
+			palette.Colors = append(palette.Colors, hex)
+		})
+		export.Colors.Palettes = append(export.Colors.Palettes, palette)
+	})
+}
+
+func (self *Font) exportJSONMapping(export *jsonExport) {
+	mapping := self.Mapping()
+
+	// switches
+	numSwitchTypes := uint32(mapping.NumSwitchTypes())
+	offsetToSwitchesData := self.OffsetToMappingSwitches + 1 + (numSwitchTypes << 1)
+	var startOffset uint32
+	for i := uint32(0); i < numSwitchTypes; i++ {
+		endOffset := uint32(internal.DecodeUint16LE(self.Data[self.OffsetToMappingSwitches + 1 + (i << 1) : ]))
+		settings := make([]int, 0, endOffset - startOffset)
+		for offset := startOffset; offset < endOffset; offset++ {
+			settings = append(settings, int(self.Data[offsetToSwitchesData + offset]))
+		}
+		export.MappingSwitches = append(export.MappingSwitches, settings)
+		startOffset = endOffset
+	}
+
+	// mapping entries (the code points index is sorted)
+	numEntries := uint32(mapping.NumEntries())
+	offsetToSearchIndex := self.OffsetToMapping + 2
+	export.Mapping = make([]jsonMappingEntry, 0, numEntries)
+	for i := uint32(0); i < numEntries; i++ {
+		codePoint := rune(int32(internal.DecodeUint32LE(self.Data[offsetToSearchIndex + (i << 2) : ])))
+		startOffset, endOffset := mapping.entryDataBounds(i)
+		entry := jsonMappingEntry{ CodePoint: codePoint, Switch: self.Data[startOffset], Cases: []jsonMappingCase{} }
+		startOffset += 1
+		if entry.Switch == 255 {
+			group := GlyphMappingGroup{ font: self, offset: startOffset, switchType: 255, directMapping: true }
+			entry.Cases = append(entry.Cases, exportJSONMappingCase(&group))
+		} else {
+			for caseBranch := uint8(0); startOffset < endOffset; caseBranch++ {
+				group := GlyphMappingGroup{ font: self, offset: startOffset, switchType: entry.Switch, caseBranch: caseBranch }
+				entry.Cases = append(entry.Cases, exportJSONMappingCase(&group))
+				startOffset = internal.SkipMappingGroups(self.Data, startOffset, 1)
+			}
+			if startOffset != endOffset { panic(invalidFontData) }
+		}
+		export.Mapping = append(export.Mapping, entry)
+	}
+}
+
+func exportJSONMappingCase(group *GlyphMappingGroup) jsonMappingCase {
+	size := group.Size()
+	mappingCase := jsonMappingCase{ Glyphs: make([]GlyphIndex, 0, size), AnimationFlags: group.AnimationFlags() }
+	group.EachGlyph(func(_ uint8, glyphIndex GlyphIndex) {
+		mappingCase.Glyphs = append(mappingCase.Glyphs, glyphIndex)
+	})
+	return mappingCase
+}
+
+func (self *Font) exportJSONRewrites(export *jsonExport) {
+	rewrites := self.Rewrites()
+	for i := uint8(0); i < rewrites.NumConditions(); i++ {
+		export.Rewrites.Conditions = append(export.Rewrites.Conditions, rewrites.ConditionString(i))
+	}
+
+	// sets
+	for i := uint8(0); i < rewrites.NumGlyphSets(); i++ {
+		glyphSet := rewrites.GetGlyphSet(i)
+		set := jsonSet{ Ranges: [][2]int32{}, List: []int32{} }
+		_ = glyphSet.EachRange(func(glyphRange GlyphRange) error {
+			set.Ranges = append(set.Ranges, [2]int32{int32(glyphRange.First), int32(glyphRange.Last)})
+			return nil
+		})
+		_ = glyphSet.EachListGlyph(func(glyphIndex GlyphIndex) error {
+			set.List = append(set.List, int32(glyphIndex))
+			return nil
+		})
+		export.Rewrites.GlyphSets = append(export.Rewrites.GlyphSets, set)
+	}
+	for i := uint8(0); i < rewrites.NumUTF8Sets(); i++ {
+		utf8Set := rewrites.GetUtf8Set(i)
+		set := jsonSet{ Ranges: [][2]int32{}, List: []int32{} }
+		_ = utf8Set.EachRange(func(start, end rune) error {
+			set.Ranges = append(set.Ranges, [2]int32{start, end})
+			return nil
+		})
+		_ = utf8Set.EachListRune(func(codePoint rune) error {
+			set.List = append(set.List, codePoint)
+			return nil
+		})
+		export.Rewrites.Utf8Sets = append(export.Rewrites.Utf8Sets, set)
+	}
+
+	// rules
+	for i := uint16(0); i < rewrites.NumGlyphRules(); i++ {
+		glyphRule := rewrites.GetGlyphRule(i)
+		rule := newJSONRule(glyphRule.Condition())
+		var n int
+		glyphRule.EachIn(func(elem GlyphIndex, isSet bool) {
+			rule.appendIn(n, glyphRule.HeadLen(), glyphRule.BodyLen(), exportJSONRuleElem(int32(elem), isSet))
+			n += 1
+		})
+		glyphRule.EachOut(func(glyphIndex GlyphIndex) {
+			rule.Output = append(rule.Output, int32(glyphIndex))
+		})
+		export.Rewrites.GlyphRules = append(export.Rewrites.GlyphRules, rule)
+	}
+	for i := uint16(0); i < rewrites.NumUTF8Rules(); i++ {
+		utf8Rule := rewrites.GetUtf8Rule(i)
+		rule := newJSONRule(utf8Rule.Condition())
+		var n int
+		utf8Rule.EachIn(func(elem rune, isSet bool) {
+			rule.appendIn(n, utf8Rule.HeadLen(), utf8Rule.BodyLen(), exportJSONRuleElem(elem, isSet))
+			n += 1
+		})
+		utf8Rule.EachOut(func(codePoint rune) {
+			rule.Output = append(rule.Output, codePoint)
+		})
+		export.Rewrites.Utf8Rules = append(export.Rewrites.Utf8Rules, rule)
+	}
+}
+
+func newJSONRule(condition uint8) jsonRule {
+	rule := jsonRule{ Head: []any{}, Body: []any{}, Tail: []any{}, Output: []int32{} }
+	if condition != 255 { rule.Condition = &condition }
+	return rule
+}
+
+// Appends the nth rule input element to the head, body or tail.
+func (self *jsonRule) appendIn(n int, headLen, bodyLen uint8, elem any) {
+	switch {
+	case n < int(headLen):
+		self.Head = append(self.Head, elem)
+	case n < int(headLen) + int(bodyLen):
+		self.Body = append(self.Body, elem)
+	default:
+		self.Tail = append(self.Tail, elem)
+	}
+}
+
+func exportJSONRuleElem(elem int32, isSet bool) any {
+	if isSet { return jsonSetRef{ Set: uint8(elem) } }
+	return elem
+}
@@ -7,6 +7,11 @@ import "image"
 import "image/color"
 import "compress/gzip"
 import "unsafe"
+import "strings"
+import "strconv"
+import "math"
+import "math/bits"
+import "unicode/utf8"
 
 import "github.com/tinne26/ggfnt/internal"
 import "github.com/tinne26/ggfnt/mask"
@@ -29,18 +34,58 @@ const invalidFontData = "invalid font data" // used for panics
 
 // --- general methods ---
 
+// Options for [Font.ExportWithOptions]().
+type ExportOptions struct {
+	// The gzip compression level, from [gzip.HuffmanOnly] to
+	// [gzip.BestCompression], including [gzip.NoCompression].
+	// If nil, [gzip.DefaultCompression] is used.
+	CompressionLevel *int
+}
+
+// Returns the gzip compression level to use for the options.
+func (self *ExportOptions) gzipLevel() int {
+	if self.CompressionLevel == nil { return gzip.DefaultCompression }
+	return *self.CompressionLevel
+}
+
+// Exports the font into a .ggfnt file or data blob, using the
+// default gzip compression level.
 func (self *Font) Export(writer io.Writer) error {
+	return self.ExportWithOptions(writer, ExportOptions{})
+}
+
+// Like [Font.Export](), but allowing the gzip compression level to be
+// configured. Use [gzip.BestCompression] for distribution or
+// [gzip.NoCompression] for faster exports during development.
+func (self *Font) ExportWithOptions(writer io.Writer, opts ExportOptions) error {
+	gzipWriter, err := gzip.NewWriterLevel(writer, opts.gzipLevel())
+	if err != nil { return err }
+
 	n, err := writer.Write([]byte{'t', 'g', 'g', 'f', 'n', 't'})
 	if err != nil { return err }
 	if n != 6 { return errors.New("short write") }
 
-	gzipWriter := gzip.NewWriter(writer)
 	n, err = gzipWriter.Write(self.Data)
 	if err != nil { return err }
 	if n != len(self.Data) { return errors.New("short write") }
 	return gzipWriter.Close()
 }
 
+// Exports the font data without gzip compression, preceded by the
+// 'rggfnt' signature instead of the usual 'tggfnt' one. Raw data is
+// bigger but faster to load, which is useful when the font is already
+// being compressed by an asset pipeline. Use [ParseRaw]() to load it.
+func (self *Font) ExportRaw(writer io.Writer) error {
+	n, err := writer.Write([]byte{'r', 'g', 'g', 'f', 'n', 't'})
+	if err != nil { return err }
+	if n != 6 { return errors.New("short write") }
+
+	n, err = writer.Write(self.Data)
+	if err != nil { return err }
+	if n != len(self.Data) { return errors.New("short write") }
+	return nil
+}
+
 func (self *Font) RawSize() int {
 	return len(self.Data)
 }
@@ -68,12 +113,34 @@ func (self *Font) Validate(mode FmtValidation) error {
 	if err != nil { return err }
 	err = self.Mapping().Validate(mode)
 	if err != nil { return err }
+	err = self.Rewrites().Validate(mode)
+	if err != nil { return err }
 	err = self.Kerning().Validate(mode)
 	if err != nil { return err }
 
 	return nil
 }
 
+// Like [Font.Validate](), but running the validation of every section
+// instead of stopping at the first error. Errors are prefixed with the
+// name of the section that produced them. Returns nil if no errors are
+// found.
+func (self *Font) ValidateAll(mode FmtValidation) []error {
+	var errs []error
+	var check = func(section string, err error) {
+		if err != nil { errs = append(errs, fmt.Errorf("%s: %w", section, err)) }
+	}
+	check("header", self.Header().Validate(mode))
+	check("metrics", self.Metrics().Validate(mode))
+	check("glyphs", self.Glyphs().Validate(mode))
+	check("color", self.Color().Validate(mode))
+	check("settings", self.Settings().Validate(mode))
+	check("mapping", self.Mapping().Validate(mode))
+	check("rewrites", self.Rewrites().Validate(mode))
+	check("kerning", self.Kerning().Validate(mode))
+	return errs
+}
+
 // --- data section gateways ---
 
 func (self *Font) Header() *FontHeader { return (*FontHeader)(self) }
@@ -94,6 +161,21 @@ func (self *FontHeader) FormatVersion() uint32 {
 func (self *FontHeader) ID() uint64 {
 	return internal.DecodeUint64LE(self.Data[4 : 12])
 }
+
+// Returns the font ID formatted as 16 uppercase hex characters. See
+// also [ParseFontID]().
+func (self *FontHeader) IDString() string {
+	return fmt.Sprintf("%016X", self.ID())
+}
+
+// Parses a font ID formatted as in [FontHeader.IDString](). Lowercase
+// hex characters are also accepted, but the length must be exactly 16.
+func ParseFontID(s string) (uint64, error) {
+	if len(s) != 16 { return 0, errors.New("font ID string must be exactly 16 hex characters long") }
+	id, err := strconv.ParseUint(s, 16, 64)
+	if err != nil { return 0, errors.New("invalid font ID string '" + s + "'") }
+	return id, nil
+}
 func (self *FontHeader) VersionMajor() uint16 {
 	return internal.DecodeUint16LE(self.Data[12 : 14])
 }
@@ -138,7 +220,11 @@ func (self *FontHeader) About() string {
 
 func (self *FontHeader) Validate(mode FmtValidation) error {
 	// default checks
-	if self.FormatVersion() != FormatVersion { return errors.New("invalid FormatVersion") }
+	formatVersion := self.FormatVersion()
+	if formatVersion < FormatVersion {
+		return fmt.Errorf("FormatVersion %d is no longer supported, the font must be rebuilt", formatVersion)
+	}
+	if formatVersion != FormatVersion { return errors.New("invalid FormatVersion") }
 	if internal.LazyEntropyUint64(self.ID()) < internal.MinEntropyID {
 		return errors.New("font ID entropy too low")
 	}
@@ -146,7 +232,12 @@ func (self *FontHeader) Validate(mode FmtValidation) error {
 
 	// strict checks
 	if mode == FmtStrict {
-		panic("unimplemented")
+		first, major, minor := self.FirstVersionDate(), self.MajorVersionDate(), self.MinorVersionDate()
+		if !first.IsValid() || !major.IsValid() || !minor.IsValid() {
+			return errors.New("invalid version date")
+		}
+		err := CheckVersionDatesOrder(first, major, minor)
+		if err != nil { return err }
 	}
 
 	return nil
@@ -170,6 +261,16 @@ func (self *FontMetrics) MonoWidth() uint8 {
 func (self *FontMetrics) LineHeight() int {
 	return int(self.Ascent()) + int(self.Descent()) + int(self.LineGap())
 }
+// Returns the maximum vertical extent that glyphs may occupy above and
+// below the baseline, including the extra ascent and extra descent
+// (ascent + extra ascent, descent + extra descent). Unlike [FontMetrics.LineHeight](),
+// which is based on the basic font size, this is what renderers need
+// when allocating per-line buffers if they want to avoid clipping.
+func (self *FontMetrics) InkBox() (ascent, descent int) {
+	ascent  = int(self.Ascent()) + int(self.ExtraAscent())
+	descent = int(self.Descent()) + int(self.ExtraDescent())
+	return ascent, descent
+}
 func (self *FontMetrics) Ascent() uint8 {
 	return self.Data[self.OffsetToMetrics + 4]
 }
@@ -227,10 +328,7 @@ func (self *FontMetrics) Validate(mode FmtValidation) error {
 		return errors.New("VertInterspacing set without HasVertLayout")
 	}
 
-	// strict checks
-	if mode == FmtStrict {
-		panic("unimplemented")
-	}
+	// strict checks (none yet)
 
 	return nil
 }
@@ -294,6 +392,24 @@ func (self *FontColor) EachDye(fn func(DyeKey, string)) {
 	}
 }
 
+// Returns the name of the given dye. Notice: the string is an
+// unsafe.String, so don't store it indefinitely.
+func (self *FontColor) GetDyeName(key DyeKey) string {
+	numDyes := uint32(self.NumDyes())
+	if uint32(key) >= numDyes { panic("invalid dye key") }
+	offsetToDyeNameEnds := self.OffsetToDyes + 1 + numDyes + uint32(self.NumDyeIndices())
+	offsetToDyeNames := offsetToDyeNameEnds + (numDyes << 1)
+
+	offsetToDyeNameEnd := offsetToDyeNameEnds + (uint32(key) << 1)
+	nameEndOffset := uint32(internal.DecodeUint16LE(self.Data[offsetToDyeNameEnd : ]))
+	var nameStartOffset uint32 = 0
+	if key > 0 {
+		nameStartOffset = uint32(internal.DecodeUint16LE(self.Data[offsetToDyeNameEnd - 2 : ]))
+	}
+	if nameEndOffset <= nameStartOffset { panic(invalidFontData) }
+	return unsafe.String(&self.Data[offsetToDyeNames + nameStartOffset], nameEndOffset - nameStartOffset)
+}
+
 func (self *FontColor) NumDyeAlphas(key DyeKey) uint8 {
 	numDyes := self.NumDyes()
 	if uint8(key) >= numDyes { panic("invalid dye key") }
@@ -342,6 +458,24 @@ func (self *FontColor) EachPalette(fn func(PaletteKey, string)) {
 	}
 }
 
+// Returns the name of the given palette. Notice: the string is an
+// unsafe.String, so don't store it indefinitely.
+func (self *FontColor) GetPaletteName(key PaletteKey) string {
+	numPalettes := uint32(self.NumPalettes())
+	if uint32(key) >= numPalettes { panic("invalid palette key") }
+	offsetToPaletteNameEnds := self.OffsetToPalettes + 1 + numPalettes + (uint32(self.NumPaletteIndices()) << 2)
+	offsetToPaletteNames := offsetToPaletteNameEnds + (numPalettes << 1)
+
+	offsetToPaletteNameEnd := offsetToPaletteNameEnds + (uint32(key) << 1)
+	nameEndOffset := uint32(internal.DecodeUint16LE(self.Data[offsetToPaletteNameEnd : ]))
+	var nameStartOffset uint32 = 0
+	if key > 0 {
+		nameStartOffset = uint32(internal.DecodeUint16LE(self.Data[offsetToPaletteNameEnd - 2 : ]))
+	}
+	if nameEndOffset <= nameStartOffset { panic(invalidFontData) }
+	return unsafe.String(&self.Data[offsetToPaletteNames + nameStartOffset], nameEndOffset - nameStartOffset)
+}
+
 func (self *FontColor) NumPaletteColors(key PaletteKey) uint8 {
 	numPalettes := self.NumPalettes()
 	if uint8(key) >= numPalettes { panic("invalid palette key") }
@@ -393,6 +527,92 @@ func (self *FontColor) GoColorPalette() color.Palette {
 	return color.Palette(paletteColors)
 }
 
+// Resolves a glyph mask color index to its final RGBA color. Color
+// indices are assigned from 255 downwards: first the alphas of each
+// dye, in order, and then the colors of each palette. Index 0 is
+// always transparent.
+//
+// Dye alphas are applied to the premultiplied color given for their
+// dye in dyeColors, which is indexed by [DyeKey]. If a dye has no color
+// in dyeColors, white is used. Indices not assigned to any color section
+// are resolved as white with the index as its alpha.
+func (self *FontColor) ResolveColor(index uint8, dyeColors []color.RGBA) color.RGBA {
+	if index == 0 { return color.RGBA{} }
+	relIndex := 255 - index
+	numDyeIndices := self.NumDyeIndices()
+	if relIndex < numDyeIndices {
+		numDyes := self.NumDyes()
+		var key uint8
+		for relIndex >= self.Data[self.OffsetToDyes + 1 + uint32(key)] { key += 1 }
+		if key >= numDyes { panic(invalidFontData) }
+		alpha := self.Data[self.OffsetToDyes + 1 + uint32(numDyes) + uint32(relIndex)]
+		dyeColor := color.RGBA{255, 255, 255, 255}
+		if int(key) < len(dyeColors) { dyeColor = dyeColors[key] }
+		return scaleRGBA(dyeColor, alpha)
+	}
+
+	relIndex -= numDyeIndices
+	if relIndex < self.NumPaletteIndices() {
+		colorDataOffset := self.OffsetToPalettes + 1 + uint32(self.NumPalettes()) + (uint32(relIndex) << 2)
+		return color.RGBA{
+			R: self.Data[colorDataOffset + 0],
+			G: self.Data[colorDataOffset + 1],
+			B: self.Data[colorDataOffset + 2],
+			A: self.Data[colorDataOffset + 3],
+		}
+	}
+	return color.RGBA{ index, index, index, index }
+}
+
+// Returns the result of [FontColor.ResolveColor]() for all the 256
+// color indices, which can be used as a palette for [NewPaletteResolver]().
+func (self *FontColor) ResolveColors(dyeColors []color.RGBA) []color.RGBA {
+	colors := make([]color.RGBA, 256)
+	for i := 1; i < 256; i++ {
+		colors[i] = self.ResolveColor(uint8(i), dyeColors)
+	}
+	return colors
+}
+
+// A function mapping glyph mask color indices to RGBA colors, used by
+// [FontGlyphs.RasterizeRGBA](). Index 0 is never passed to the resolver,
+// as it's always transparent.
+type ColorResolver func(index uint8) color.RGBA
+
+// Returns a [ColorResolver] that applies [FontColor.ResolveColor]() with
+// the given dye colors. Colors are precomputed, so the resolver stays
+// valid even if dyeColors is modified afterwards.
+func (self *FontColor) NewColorResolver(dyeColors []color.RGBA) ColorResolver {
+	colors := self.ResolveColors(dyeColors)
+	return func(index uint8) color.RGBA { return colors[index] }
+}
+
+// Returns a [ColorResolver] that maps color indices directly to the
+// colors of the given palette, like the result of [FontColor.ResolveColors]().
+// Indices beyond the end of the palette (or all of them, if the palette
+// is nil) are resolved as white with the index as its alpha.
+func NewPaletteResolver(palette []color.RGBA) ColorResolver {
+	return func(index uint8) color.RGBA {
+		if int(index) < len(palette) { return palette[index] }
+		return color.RGBA{ index, index, index, index }
+	}
+}
+
+// Scales a premultiplied color by the given alpha.
+func scaleRGBA(rgba color.RGBA, alpha uint8) color.RGBA {
+	scale := func(channel uint8) uint8 {
+		return uint8((uint16(channel)*uint16(alpha) + 127)/255)
+	}
+	return color.RGBA{ scale(rgba.R), scale(rgba.G), scale(rgba.B), scale(rgba.A) }
+}
+
+// Returns whether the given color is a valid alpha-premultiplied
+// color, with none of its RGB components exceeding the alpha.
+// Palette colors must always be premultiplied.
+func IsPremultRGBA(rgba color.RGBA) bool {
+	return rgba.R <= rgba.A && rgba.G <= rgba.A && rgba.B <= rgba.A
+}
+
 func (self *FontColor) Validate(mode FmtValidation) error {
 	// default checks
 	numDyes     := uint32(self.NumDyes())
@@ -432,14 +652,25 @@ func (self *FontColor) Validate(mode FmtValidation) error {
 	// 	prevSectionEnd = sectionStart
 	// }
 
-	// verify paletted RGBA values (premult alpha checks)
-	// TODO ...
-
-	// strict checks
-	if mode == FmtStrict {
-		panic("unimplemented")
+	// verify paletted RGBA values (premult alpha checks). notice that
+	// dye alphas don't need any specific order, so they are not checked
+	for n := uint8(0); n < uint8(numPalettes); n++ {
+		var colorIndex int = -1
+		var current int
+		self.EachPaletteColor(PaletteKey(n), func(rgba color.RGBA) {
+			if colorIndex == -1 && !IsPremultRGBA(rgba) { colorIndex = current }
+			current += 1
+		})
+		if colorIndex != -1 {
+			return fmt.Errorf(
+				"palette '%s' color #%d has RGB values exceeding its alpha (colors must be premultiplied)",
+				self.GetPaletteName(PaletteKey(n)), colorIndex,
+			)
+		}
 	}
 
+	// strict checks (none yet)
+
 	return nil
 }
 
@@ -484,6 +715,44 @@ func (self *FontGlyphs) FindIndexByName(name string) GlyphIndex {
 	return GlyphIndex(internal.DecodeUint16LE(self.Data[idOffset : idOffset + 2]))
 }
 
+// Returns the indices of all the glyphs whose names start with the given
+// prefix, sorted by name. An empty prefix matches all named glyphs.
+func (self *FontGlyphs) FindIndexByNamePrefix(prefix string) []GlyphIndex {
+	// binary search the first name >= prefix
+	numEntries := uint32(self.NamedCount())
+	minIndex, maxIndex := uint32(0), numEntries
+	for minIndex < maxIndex {
+		midIndex := (minIndex + maxIndex) >> 1
+		value := self.getNthGlyphName(midIndex, numEntries)
+		if bytesSmallerThanStr(value, prefix) {
+			minIndex = midIndex + 1
+		} else {
+			maxIndex = midIndex
+		}
+	}
+
+	// collect glyphs forward while the prefix holds
+	var indices []GlyphIndex
+	for i := minIndex; i < numEntries; i++ {
+		value := self.getNthGlyphName(i, numEntries)
+		if len(value) < len(prefix) || !bytesEqStr(value[ : len(prefix)], prefix) { break }
+		idOffset := self.OffsetToGlyphNames + 2 + (i << 1)
+		indices = append(indices, GlyphIndex(internal.DecodeUint16LE(self.Data[idOffset : idOffset + 2])))
+	}
+	return indices
+}
+
+// Calls the given function for each named glyph, in name order.
+// Notice: the string is an unsafe.String, so don't store it indefinitely.
+func (self *FontGlyphs) EachNamed(fn func(glyphIndex GlyphIndex, name string)) {
+	numNamedGlyphs := uint32(self.NamedCount())
+	for i := uint32(0); i < numNamedGlyphs; i++ {
+		name := self.getNthGlyphName(i, numNamedGlyphs)
+		glyphIndex := internal.DecodeUint16LE(self.Data[self.OffsetToGlyphNames + 2 + (i << 1) : ])
+		fn(GlyphIndex(glyphIndex), unsafe.String(&name[0], len(name)))
+	}
+}
+
 func (self *FontGlyphs) getNthGlyphName(nth uint32, numNamedGlyphs uint32) []byte {
 	endOffsetsIndex := self.OffsetToGlyphNames + 2 + (numNamedGlyphs << 1)
 	glyphNameEndOffsetIndex := endOffsetsIndex + (nth << 1) + nth
@@ -496,11 +765,11 @@ func (self *FontGlyphs) getNthGlyphName(nth uint32, numNamedGlyphs uint32) []byt
 
 func bytesSmallerThanStr(bytes []byte, str string) bool {
 	for i := 0; i < len(bytes); i++ {
-		if i >= len(str) { return true }
+		if i >= len(str) { return false } // str is a prefix of bytes
+		if bytes[i] < str[i] { return true  }
 		if bytes[i] > str[i] { return false }
 	}
-	if len(bytes) == len(str) { return false } // equal
-	return true // smaller
+	return len(bytes) < len(str) // equal or prefix
 }
 
 func bytesEqStr(bytes []byte, str string) bool {
@@ -512,18 +781,114 @@ func bytesEqStr(bytes []byte, str string) bool {
 }
 
 func (self *FontGlyphs) RasterizeMask(glyphIndex GlyphIndex) *image.Alpha {
+	glyphMask, err := self.rasterizeMaskInto(nil, glyphIndex)
+	if err != nil { panic(err) }
+	return glyphMask
+}
+
+// Like [FontGlyphs.RasterizeMask](), but resolving each color index
+// to its final color through the given resolver. See also
+// [FontColor.NewColorResolver](). Empty glyphs return an empty image.
+func (self *FontGlyphs) RasterizeRGBA(glyphIndex GlyphIndex, resolver ColorResolver) *image.RGBA {
+	glyphMask := self.RasterizeMask(glyphIndex)
+	if glyphMask == nil { return image.NewRGBA(image.Rectangle{}) }
+	
+	rgba := image.NewRGBA(glyphMask.Rect)
+	for y := glyphMask.Rect.Min.Y; y < glyphMask.Rect.Max.Y; y++ {
+		for x := glyphMask.Rect.Min.X; x < glyphMask.Rect.Max.X; x++ {
+			index := glyphMask.AlphaAt(x, y).A
+			if index == 0 { continue }
+			rgba.SetRGBA(x, y, resolver(index))
+		}
+	}
+	return rgba
+}
+
+// Like [FontGlyphs.RasterizeMask](), but writing the result to dst and
+// reusing its pixel data when it has enough capacity. Empty glyphs leave
+// dst with empty bounds. Returns an error if the glyph data is invalid.
+func (self *FontGlyphs) RasterizeMaskInto(glyphIndex GlyphIndex, dst *image.Alpha) error {
+	glyphMask, err := self.rasterizeMaskInto(dst, glyphIndex)
+	if err != nil { return err }
+	if glyphMask == nil {
+		dst.Pix = dst.Pix[ : 0]
+		dst.Stride = 0
+		dst.Rect = image.Rectangle{}
+	} else if glyphMask != dst {
+		*dst = *glyphMask
+	}
+	return nil
+}
+
+// Like [FontGlyphs.RasterizeMask](), but reusing the buffer as in [mask.RasterizeInto]().
+func (self *FontGlyphs) rasterizeMaskInto(buffer *image.Alpha, glyphIndex GlyphIndex) (*image.Alpha, error) {
+	return mask.RasterizeInto(buffer, self.getGlyphRasterOps(glyphIndex))
+}
+
+// Returns the bounds of the glyph mask without rasterizing it. The
+// result is the same as RasterizeMask(glyphIndex).Bounds(), with empty
+// glyphs returning an empty rectangle.
+func (self *FontGlyphs) Bounds(glyphIndex GlyphIndex) image.Rectangle {
+	rect, err := mask.RasterOpsRect(self.getGlyphRasterOps(glyphIndex))
+	if err != nil { panic(err) }
+	return rect
+}
+
+func (self *FontGlyphs) getGlyphRasterOps(glyphIndex GlyphIndex) []byte {
+	self.checkGlyphIndex(glyphIndex)
 	startOffset, endOffset := self.getGlyphDataOffsets(glyphIndex)
 	if self.hasVertLayout() { startOffset += 4 } else { startOffset += 1 }
 	numGlyphs := uint32(self.Count())
 	offsetToMasksData := self.OffsetToGlyphMasks + (numGlyphs << 1) + numGlyphs
-	glyphMask, err := mask.Rasterize(self.Data[offsetToMasksData + startOffset : offsetToMasksData + endOffset])
-	if err != nil { panic(err) }
-	return glyphMask
+	return self.Data[offsetToMasksData + startOffset : offsetToMasksData + endOffset]
+}
+
+// Returns the glyph mask as text, one line per row, for quick terminal
+// inspection. Empty pixels are rendered as spaces, pixels with values
+// >= 128 as '█', and any other non-zero pixels as '░'. Only the mask
+// bounds are rendered, so an empty glyph results in an empty string.
+func (self *FontGlyphs) DebugString(glyphIndex GlyphIndex) string {
+	glyphMask := self.RasterizeMask(glyphIndex)
+	if glyphMask == nil { return "" }
+
+	var builder strings.Builder
+	for y := glyphMask.Rect.Min.Y; y < glyphMask.Rect.Max.Y; y++ {
+		for x := glyphMask.Rect.Min.X; x < glyphMask.Rect.Max.X; x++ {
+			value := glyphMask.AlphaAt(x, y).A
+			switch {
+			case value == 0  : builder.WriteRune(' ')
+			case value >= 128: builder.WriteRune('█')
+			default:
+				builder.WriteRune('░')
+			}
+		}
+		builder.WriteRune('\n')
+	}
+	return builder.String()
 }
 
-func (self *FontGlyphs) Advance(glyphIndex GlyphIndex) uint8 {
+// Rasterizes each glyph mask in index order and passes it to the given
+// function. Empty masks are passed as nil.
+//
+// The same backing buffer is reused between calls whenever possible,
+// so masks are only valid until the function returns. If you need to
+// retain a mask, copy it or use [FontGlyphs.RasterizeMask]() instead.
+func (self *FontGlyphs) EachMask(fn func(index GlyphIndex, mask *image.Alpha)) {
+	var buffer *image.Alpha
 	numGlyphs := self.Count()
-	if uint16(glyphIndex) >= numGlyphs { panic("glyphIndex out of range") }  // discretionary assertion
+	offsetToMasksData := self.OffsetToGlyphMasks + (uint32(numGlyphs) << 1) + uint32(numGlyphs)
+	for i := uint16(0); i < numGlyphs; i++ {
+		startOffset, endOffset := self.getGlyphDataOffsets(GlyphIndex(i))
+		if self.hasVertLayout() { startOffset += 4 } else { startOffset += 1 }
+		glyphMask, err := mask.RasterizeInto(buffer, self.Data[offsetToMasksData + startOffset : offsetToMasksData + endOffset])
+		if err != nil { panic(err) }
+		if glyphMask != nil { buffer = glyphMask }
+		fn(GlyphIndex(i), glyphMask)
+	}
+}
+
+func (self *FontGlyphs) Advance(glyphIndex GlyphIndex) uint8 {
+	numGlyphs := self.checkGlyphIndex(glyphIndex)
 	
 	glyphDataStartOffset := self.getGlyphDataStartOffset(glyphIndex)
 	numGlyphs32 := uint32(numGlyphs)
@@ -531,8 +896,7 @@ func (self *FontGlyphs) Advance(glyphIndex GlyphIndex) uint8 {
 }
 
 func (self *FontGlyphs) Placement(glyphIndex GlyphIndex) GlyphPlacement {
-	numGlyphs := self.Count()
-	if uint16(glyphIndex) >= numGlyphs { panic("glyphIndex out of range") } // discretionary assertion
+	numGlyphs := self.checkGlyphIndex(glyphIndex)
 
 	glyphDataStartOffset := self.getGlyphDataStartOffset(glyphIndex)
 
@@ -548,6 +912,14 @@ func (self *FontGlyphs) Placement(glyphIndex GlyphIndex) GlyphPlacement {
 	return placement
 }
 
+// Panics if the glyph index is out of range. Returns the number
+// of glyphs in the font for convenience.
+func (self *FontGlyphs) checkGlyphIndex(glyphIndex GlyphIndex) uint16 {
+	numGlyphs := self.Count()
+	if uint16(glyphIndex) >= numGlyphs { panic("glyphIndex out of range") }
+	return numGlyphs
+}
+
 func (self *FontGlyphs) getGlyphDataOffsets(glyphIndex GlyphIndex) (uint32, uint32) {
 	index := uint32(glyphIndex)
 	index = (index << 1) + index
@@ -574,9 +946,80 @@ func (self *FontGlyphs) Validate(mode FmtValidation) error {
 
 	// strict checks
 	if mode == FmtStrict {
-		panic("unimplemented")
+		err := self.validateNamedGlyphs()
+		if err != nil { return err }
+		err = self.validateGlyphMasks()
+		if err != nil { return err }
+	}
+
+	return nil
+}
+
+func (self *FontGlyphs) validateNamedGlyphs() error {
+	numGlyphs := uint32(self.Count())
+	numNamedGlyphs := uint32(self.NamedCount())
+	if numNamedGlyphs == 0 { return nil }
+
+	// check glyph IDs and name end offsets
+	endOffsetsIndex := self.OffsetToGlyphNames + 2 + (numNamedGlyphs << 1)
+	namesIndex := endOffsetsIndex + (numNamedGlyphs << 1) + numNamedGlyphs
+	if int(namesIndex) > len(self.Data) { return errors.New("named glyphs data exceeds font data") }
+	var prevEndOffset uint32
+	for i := uint32(0); i < numNamedGlyphs; i++ {
+		glyphIndex := internal.DecodeUint16LE(self.Data[self.OffsetToGlyphNames + 2 + (i << 1) : ])
+		if uint32(glyphIndex) >= numGlyphs {
+			return fmt.Errorf("named glyph #%d references glyph index %d, beyond glyph count", i, glyphIndex)
+		}
+		endOffset := internal.DecodeUint24LE(self.Data[endOffsetsIndex + (i << 1) + i : ])
+		if endOffset <= prevEndOffset { return errors.New("glyph name end offsets must be strictly increasing") }
+		prevEndOffset = endOffset
 	}
+	if int(namesIndex + prevEndOffset) > len(self.Data) { return errors.New("glyph names exceed font data") }
+
+	// check names and their order
+	var prevName []byte
+	for i := uint32(0); i < numNamedGlyphs; i++ {
+		name := self.getNthGlyphName(i, numNamedGlyphs)
+		err := internal.ValidateBasicName(string(name))
+		if err != nil { return fmt.Errorf("named glyph #%d: %w", i, err) }
+		if i > 0 && !bytesSmallerThanStr(prevName, string(name)) {
+			return errors.New("glyph names must be sorted and unique")
+		}
+		prevName = name
+	}
+	return nil
+}
 
+func (self *FontGlyphs) validateGlyphMasks() error {
+	numGlyphs := uint32(self.Count())
+	placementSize := uint32(1)
+	if self.hasVertLayout() { placementSize = 4 }
+	offsetToMasksData := self.OffsetToGlyphMasks + (numGlyphs << 1) + numGlyphs
+	if int(offsetToMasksData) > len(self.Data) { return errors.New("glyph offsets exceed font data") }
+
+	// check data offsets first
+	var prevEndOffset uint32
+	for i := uint32(0); i < numGlyphs; i++ {
+		endOffset := internal.DecodeUint24LE(self.Data[self.OffsetToGlyphMasks + (i << 1) + i : ])
+		if endOffset < prevEndOffset + placementSize {
+			return fmt.Errorf("glyph %d data offsets are not strictly increasing or leave no room for placement", i)
+		}
+		prevEndOffset = endOffset
+	}
+	if int(offsetToMasksData + prevEndOffset) > len(self.Data) { return errors.New("glyph masks exceed font data") }
+
+	// check that the masks can be decoded and respect the metrics
+	metrics := (*FontMetrics)(self)
+	maxAscent, maxDescent := metrics.InkBox()
+	for i := uint32(0); i < numGlyphs; i++ {
+		startOffset, endOffset := self.getGlyphDataOffsets(GlyphIndex(i))
+		startOffset += placementSize
+		glyphMask, err := mask.Rasterize(self.Data[offsetToMasksData + startOffset : offsetToMasksData + endOffset])
+		if err != nil { return fmt.Errorf("glyph %d mask: %w", i, err) }
+		if glyphMask == nil { continue }
+		if -glyphMask.Rect.Min.Y > maxAscent { return fmt.Errorf("glyph %d exceeds font ascent", i) }
+		if glyphMask.Rect.Max.Y > maxDescent { return fmt.Errorf("glyph %d exceeds font descent", i) }
+	}
 	return nil
 }
 
@@ -617,7 +1060,22 @@ func (self *FontSettings) Count() uint8 {
 	return self.Data[self.OffsetToSettingNames]
 }
 //func (self *FontSettings) FindKeyByName(name string) SettingKey { panic("unimplemented") }
-//func (self *FontSettings) GetInitValue(key SettingKey) uint8 { panic("unimplemented") }
+
+// Returns the init value of the given setting, which is the index of the
+// option that the setting must take by default (e.g., 0 for the first
+// option in the setting's options list). Like [FontSettings.GetNumOptions](),
+// it returns 0 if the key is not valid.
+func (self *FontSettings) GetInitValue(key SettingKey) uint8 {
+	numSettings := uint32(self.Count())
+	if uint32(key) >= numSettings { return 0 }
+	return self.Data[self.OffsetToSettingDefinitions - numSettings + uint32(key)]
+}
+
+// Returns a new [SettingsCache] with every setting set to its init value.
+// Same as [NewSettingsCache](), provided for convenience.
+func (self *FontSettings) GetInitSettingsCache() *SettingsCache {
+	return NewSettingsCache((*Font)(self))
+}
 func (self *FontSettings) GetNumOptions(key SettingKey) uint8 {
 	if uint8(key) >= self.Count() { return 0 }
 
@@ -627,12 +1085,28 @@ func (self *FontSettings) GetNumOptions(key SettingKey) uint8 {
 	if key > 0 {
 		startOffset = internal.DecodeUint16LE(self.Data[keyEndOffsetIndex - 2 : ])
 	}
-	if endOffset <= startOffset { panic(invalidFontData) }
+	if endOffset < startOffset { panic(invalidFontData) }
 	numOpts := endOffset - startOffset
 	if numOpts > 255 { panic(invalidFontData) }
 	return uint8(numOpts)
 }
 
+// Returns the total number of setting combinations, which is the
+// product of the number of options of each setting. Settings without
+// options count as a single value. The result saturates to [math.MaxUint64]
+// on overflow, which is only possible with many settings.
+func (self *FontSettings) TotalCombinations() uint64 {
+	var total uint64 = 1
+	numSettings := self.Count()
+	for key := uint8(0); key < numSettings; key++ {
+		numOptions := uint64(max(self.GetNumOptions(SettingKey(key)), 1))
+		hi, lo := bits.Mul64(total, numOptions)
+		if hi != 0 { return math.MaxUint64 }
+		total = lo
+	}
+	return total
+}
+
 func (self *FontSettings) GetOptionName(key SettingKey, option uint8) string {
 	// the first part is basically the same as GetNumOptions
 	numSettings := uint32(self.Count())
@@ -674,17 +1148,22 @@ func (self *FontSettings) EachOption(key SettingKey, each func(optionIndex uint8
 
 func (self *FontSettings) Validate(mode FmtValidation) error {
 	// default checks
-	// ...
+	numSettings := self.Count()
+	for i := uint8(0); i < numSettings; i++ {
+		// settings without options are valid, but their init value must be 0
+		numOptions := max(self.GetNumOptions(SettingKey(i)), 1)
+		if self.GetInitValue(SettingKey(i)) >= numOptions {
+			return fmt.Errorf("setting #%d init value exceeds its number of options", i)
+		}
+	}
 
 	// strict checks
 	if mode == FmtStrict {
 		// TODO:
-		// - go through var defs and ensure init value is in range
 		// - make sure every setting is not repeated and is within numVars
 		// - make sure every setting name is correct
 		// - make sure every setting name comes in order? nah.
 		// - make sure the offsets to names are correct
-		panic("unimplemented")
 	}
 
 	return nil
@@ -702,12 +1181,13 @@ type GlyphMappingGroup struct {
 }
 func (self *GlyphMappingGroup) Size() uint8 {
 	if self.directMapping { return 1 }
-	return (0b0111_1111 & self.font.Data[self.offset + 0]) + 1
+	group := internal.DecodeMappingGroup(self.font.Data, self.offset)
+	return group.Size
 }
 func (self *GlyphMappingGroup) AnimationFlags() AnimationFlags {
 	if self.directMapping { return 0 }
-	if (0b0111_1111 & self.font.Data[self.offset + 0]) == 0 { return 0 }
-	return AnimationFlags(self.font.Data[self.offset + 2])
+	group := internal.DecodeMappingGroup(self.font.Data, self.offset)
+	return AnimationFlags(group.AnimFlags)
 }
 func (self *GlyphMappingGroup) CaseBranch() uint8 {
 	return self.caseBranch
@@ -722,18 +1202,18 @@ func (self *GlyphMappingGroup) Select(choice uint8) GlyphIndex {
 	}
 
 	// general case
-	info := self.font.Data[self.offset + 0]
-	size := (info & 0b0111_1111) + 1
-	if choice >= size { panic("choice outside valid range") } // discretionary assertion
-
-	// no animation case (single glyph)
-	if size == 1 { return GlyphIndex(internal.DecodeUint16LE(self.font.Data[self.offset + 1 : ])) }
-
-	// multi-glyph case (group)
-	if (info & 0b1000_0000) != 0 { // range case
-		return GlyphIndex(internal.DecodeUint16LE(self.font.Data[self.offset + 2 : ]) + uint16(choice))
-	} else {
-		return GlyphIndex(internal.DecodeUint16LE(self.font.Data[self.offset + 2 + (uint32(choice) << 2) : ]))
+	group := internal.DecodeMappingGroup(self.font.Data, self.offset)
+	if choice >= group.Size { panic("choice outside valid range") } // discretionary assertion
+	return GlyphIndex(group.Glyph(self.font.Data, choice))
+}
+
+// Iterates all the glyphs in the group, with choices going from 0 to
+// [GlyphMappingGroup.Size]() - 1. The glyphs are the same that would be
+// returned by [GlyphMappingGroup.Select]().
+func (self *GlyphMappingGroup) EachGlyph(fn func(choice uint8, glyphIndex GlyphIndex)) {
+	size := self.Size()
+	for choice := uint8(0); choice < size; choice++ {
+		fn(choice, self.Select(choice))
 	}
 }
 
@@ -746,28 +1226,44 @@ func (self *FontMapping) NumSwitchTypes() uint8 {
 	return self.Data[self.OffsetToMappingSwitches + 0]
 }
 
-func (self *FontMapping) EvaluateSwitch(switchKey uint8, settings []uint8) uint8 {
+// Returns the number of cases of the given switch, which is the product
+// of the number of options of each of its settings. The special switch
+// keys 254 and 255 (single group and direct mappings) always have one case.
+func (self *FontMapping) SwitchCaseCount(switchKey uint8) int {
+	if switchKey >= 254 { return 1 }
+	start, end := self.switchSettingsBounds(switchKey)
+	numCases := 1
+	for i := start; i < end; i++ {
+		numCases *= int((*FontSettings)(self).GetNumOptions(SettingKey(self.Data[i])))
+	}
+	return numCases
+}
+
+// Returns the absolute start and end offsets of the setting keys for
+// the given switch.
+func (self *FontMapping) switchSettingsBounds(switchKey uint8) (uint32, uint32) {
 	numSwitchTypes := self.NumSwitchTypes()
 	if switchKey >= numSwitchTypes { panic("invalid switch key") }
-
 	switchEndOffsetIndex := self.OffsetToMappingSwitches + 1 + (uint32(switchKey) << 1)
-	endOffset := internal.DecodeUint16LE(self.Data[switchEndOffsetIndex : ])
-	var startOffset uint16 = 0
+	endOffset := uint32(internal.DecodeUint16LE(self.Data[switchEndOffsetIndex : ]))
+	var startOffset uint32
 	if switchKey > 0 {
-		startOffset = internal.DecodeUint16LE(self.Data[switchEndOffsetIndex - 2 : ])
+		startOffset = uint32(internal.DecodeUint16LE(self.Data[switchEndOffsetIndex - 2 : ]))
 	}
 	if endOffset <= startOffset { panic(invalidFontData) }
-	
 	offsetToMappingSwitchesData := self.OffsetToMappingSwitches + 1 + (uint32(numSwitchTypes) << 1)
+	return offsetToMappingSwitchesData + startOffset, offsetToMappingSwitchesData + endOffset
+}
+
+func (self *FontMapping) EvaluateSwitch(switchKey uint8, settings []uint8) uint8 {
+	start, end := self.switchSettingsBounds(switchKey)
 	var caseCombinations uint8 = 1
 	var result uint8
-	endOffset -= 1 // we will evaluate from last to first
-	for {
-		settingKey := SettingKey(self.Data[offsetToMappingSwitchesData + uint32(endOffset)])
+	for i := end - 1; ; i-- { // evaluate from last to first
+		settingKey := SettingKey(self.Data[i])
 		result += settings[settingKey]*caseCombinations
-		if endOffset == startOffset { break }
-		caseCombinations *= (*FontSettings)(self).GetNumOptions(SettingKey(settingKey))
-		endOffset -= 1
+		if i == start { break }
+		caseCombinations *= (*FontSettings)(self).GetNumOptions(settingKey)
 	}
 	return result
 }
@@ -832,22 +1328,155 @@ func (self *FontMapping) Utf8WithCache(codePoint rune, settingsCache *SettingsCa
 	}
 
 	group := GlyphMappingGroup{ font: (*Font)(self), switchType: switchType, caseBranch: targetSwitchCase }
-	for targetSwitchCase > 0 {
-		groupInfo := self.Data[offsetToMappingData + int(startOffset)]
-		groupSize := (groupInfo & 0b0111_1111) + 1
-		groupDefinedAsRange := ((groupSize & 0b1000_0000) != 0)
-		if groupDefinedAsRange {
-			startOffset += 3 // 1 byte group size, 2 bytes base glyph
+	group.offset = internal.SkipMappingGroups(self.Data, uint32(offsetToMappingData) + startOffset, targetSwitchCase)
+	if group.offset >= uint32(offsetToMappingData) + endOffset { panic(invalidFontData) } // discretionary assertion
+	return group, true
+}
+
+// Batch version of [FontMapping.Utf8WithCache]() for runs of text that share
+// the same settings. Code points are mapped in order and written to out until
+// either runes or out are exhausted, or an unmapped code point is found. The
+// returned value is the number of code points mapped, so if it's smaller than
+// min(len(runes), len(out)), runes[n] is a code point without a mapping.
+//
+// Section offsets, switch case evaluations and repeated code points are
+// reused between the elements of the run.
+func (self *FontMapping) MapRun(runes []rune, settingsCache *SettingsCache, out []GlyphMappingGroup) int {
+	var resolvedCases [256]uint8 // 0 if unresolved, case + 1 otherwise
+	n := min(len(runes), len(out))
+	for i := 0; i < n; i++ {
+		// repeated code point case
+		if i > 0 && runes[i] == runes[i - 1] {
+			out[i] = out[i - 1]
+			continue
+		}
+
+		startOffset, endOffset, found := self.findCodePointData(runes[i])
+		if !found { return i }
+		switchType := self.Data[startOffset]
+		startOffset += 1
+		if switchType == 255 {
+			out[i] = GlyphMappingGroup{
+				font: (*Font)(self),
+				offset: startOffset,
+				switchType: switchType,
+				directMapping: true,
+			}
+			continue
+		}
+
+		// resolve switch case
+		var targetSwitchCase uint8
+		if switchType != 254 {
+			if resolvedCases[switchType] == 0 {
+				switchCase, cached := settingsCache.GetMappingCase(switchType)
+				if !cached {
+					switchCase = self.EvaluateSwitch(switchType, settingsCache.UnsafeSlice())
+					settingsCache.CacheMappingCase(switchType, switchCase)
+				}
+				resolvedCases[switchType] = switchCase + 1
+			}
+			targetSwitchCase = resolvedCases[switchType] - 1
+		}
+
+		// walk the switch staircase
+		group := GlyphMappingGroup{ font: (*Font)(self), switchType: switchType, caseBranch: targetSwitchCase }
+		group.offset = internal.SkipMappingGroups(self.Data, startOffset, targetSwitchCase)
+		if group.offset >= endOffset { panic(invalidFontData) } // discretionary assertion
+		out[i] = group
+	}
+	return n
+}
+
+// An inclusive range of code points.
+type RuneRange struct {
+	First rune // included
+	Last  rune // included
+}
+
+func (self *RuneRange) Contains(codePoint rune) bool {
+	return codePoint >= self.First && codePoint <= self.Last
+}
+
+// Returns the mapped code points as a list of non-overlapping ranges
+// in ascending order, with consecutive code points merged together.
+func (self *FontMapping) CoverageRanges() []RuneRange {
+	var ranges []RuneRange
+	numEntries := uint32(self.NumEntries())
+	offsetToSearchIndex := self.OffsetToMapping + 2
+	for i := uint32(0); i < numEntries; i++ {
+		codePoint := rune(int32(internal.DecodeUint32LE(self.Data[offsetToSearchIndex + (i << 2) : ])))
+		last := len(ranges) - 1
+		if last >= 0 && ranges[last].Last + 1 == codePoint {
+			ranges[last].Last = codePoint
 		} else {
-			startOffset += 1 + (uint32(groupSize) << 1)
+			ranges = append(ranges, RuneRange{ First: codePoint, Last: codePoint })
+		}
+	}
+	return ranges
+}
+
+// Iterates all the code points in the mapping table in increasing order,
+// invoking fn with the glyph mapping group each one resolves to for the
+// given settings, exactly as [FontMapping.Utf8]() would. If fn returns an
+// error, the iteration stops and the error is returned.
+func (self *FontMapping) EachMapping(settings []uint8, fn func(codePoint rune, group GlyphMappingGroup) error) error {
+	var resolvedCases [256]uint8 // 0 if unresolved, case + 1 otherwise
+	numEntries := uint32(self.NumEntries())
+	offsetToSearchIndex := self.OffsetToMapping + 2
+	for i := uint32(0); i < numEntries; i++ {
+		codePoint := rune(int32(internal.DecodeUint32LE(self.Data[offsetToSearchIndex + (i << 2) : ])))
+		startOffset, endOffset := self.entryDataBounds(i)
+		switchType := self.Data[startOffset]
+		startOffset += 1
+
+		// basic case: inconditional mapping
+		if switchType == 255 {
+			group := GlyphMappingGroup{
+				font: (*Font)(self),
+				offset: startOffset,
+				switchType: switchType,
+				directMapping: true,
+			}
+			err := fn(codePoint, group)
+			if err != nil { return err }
+			continue
 		}
-		if groupSize > 1 { startOffset += 1 } // anim flag skip
-		targetSwitchCase -= 1
 
-		if startOffset >= endOffset { panic(invalidFontData) } // discretionary assertion
+		// resolve switch case
+		var targetSwitchCase uint8
+		if switchType != 254 {
+			if resolvedCases[switchType] == 0 {
+				resolvedCases[switchType] = self.EvaluateSwitch(switchType, settings) + 1
+			}
+			targetSwitchCase = resolvedCases[switchType] - 1
+		}
+
+		// walk the switch staircase
+		group := GlyphMappingGroup{ font: (*Font)(self), switchType: switchType, caseBranch: targetSwitchCase }
+		group.offset = internal.SkipMappingGroups(self.Data, startOffset, targetSwitchCase)
+		if group.offset >= endOffset { panic(invalidFontData) } // discretionary assertion
+		err := fn(codePoint, group)
+		if err != nil { return err }
 	}
-	group.offset = uint32(offsetToMappingData) + startOffset
-	return group, true
+	return nil
+}
+
+// Returns all the code points whose mapping group contains the given glyph
+// for the given settings, in increasing order. This scans the whole mapping
+// table with [FontMapping.EachMapping](), so it's O(n) on the number of
+// mapped code points and not meant to be used on hot paths.
+func (self *FontMapping) RunesForGlyph(glyphIndex GlyphIndex, settings []uint8) []rune {
+	var codePoints []rune
+	_ = self.EachMapping(settings, func(codePoint rune, group GlyphMappingGroup) error {
+		for i := uint8(0); i < group.Size(); i++ {
+			if group.Select(i) != glyphIndex { continue }
+			codePoints = append(codePoints, codePoint)
+			break
+		}
+		return nil
+	})
+	return codePoints
 }
 
 // Notice: line breaks and other control codes shouldn't be requested here,
@@ -903,21 +1532,8 @@ func (self *FontMapping) Utf8(codePoint rune, settings []uint8) (GlyphMappingGro
 	}
 
 	group := GlyphMappingGroup{ font: (*Font)(self), switchType: switchType, caseBranch: targetSwitchCase }
-	for targetSwitchCase > 0 {
-		groupInfo := self.Data[offsetToMappingData + int(startOffset)]
-		groupSize := (groupInfo & 0b0111_1111) + 1
-		groupDefinedAsRange := ((groupSize & 0b1000_0000) != 0)
-		if groupDefinedAsRange {
-			startOffset += 3 // 1 byte group size, 2 bytes base glyph
-		} else {
-			startOffset += 1 + (uint32(groupSize) << 1)
-		}
-		if groupSize > 1 { startOffset += 1 } // anim flag skip
-		targetSwitchCase -= 1
-
-		if startOffset >= endOffset { panic(invalidFontData) } // discretionary assertion
-	}
-	group.offset = uint32(offsetToMappingData) + startOffset
+	group.offset = internal.SkipMappingGroups(self.Data, uint32(offsetToMappingData) + startOffset, targetSwitchCase)
+	if group.offset >= uint32(offsetToMappingData) + endOffset { panic(invalidFontData) } // discretionary assertion
 	return group, true
 }
 
@@ -925,19 +1541,236 @@ func (self *FontMapping) Ascii(codePoint byte, settings []uint8) (GlyphMappingGr
 	return self.Utf8(rune(codePoint), settings)
 }
 
+// Returns the set of glyphs reachable from the given code points, including
+// all the frames of animated groups. If settings is nil, all switch cases
+// are considered. Otherwise, only the cases selected by the given settings
+// are included. Code points without a mapping are ignored.
+//
+// Glyphs reachable only through rewrite rules are not included.
+func (self *Font) GlyphsForRunes(runes []rune, settings []uint8) map[GlyphIndex]struct{} {
+	glyphs := make(map[GlyphIndex]struct{})
+	mapping := self.Mapping()
+	for _, codePoint := range runes {
+		startOffset, endOffset, found := mapping.findCodePointData(codePoint)
+		if !found { continue }
+
+		switchType := self.Data[startOffset]
+		startOffset += 1
+		if switchType == 255 { // inconditional mapping
+			glyphs[GlyphIndex(internal.DecodeUint16LE(self.Data[startOffset : ]))] = struct{}{}
+			continue
+		}
+
+		var targetCase int = -1 // all cases
+		if settings != nil && switchType != 254 {
+			targetCase = int(mapping.EvaluateSwitch(switchType, settings))
+		}
+		for caseIndex := 0; startOffset < endOffset; caseIndex++ {
+			group := internal.DecodeMappingGroup(self.Data, startOffset)
+			startOffset = group.EndOffset()
+			if targetCase != -1 && targetCase != caseIndex { continue }
+			for n := uint8(0); n < group.Size; n++ {
+				glyphs[GlyphIndex(group.Glyph(self.Data, n))] = struct{}{}
+			}
+		}
+		if startOffset != endOffset { panic(invalidFontData) } // discretionary assertion
+	}
+	return glyphs
+}
+
+// Calls the given function for each code point whose default mapping
+// (the switch case selected with all settings at their init values) is
+// a multi-glyph group, passing its frames and animation flags. Code
+// points are visited in ascending order. The frames slice is reused
+// between calls, so it must be copied if it needs to be retained.
+func (self *Font) EachAnimatedMapping(fn func(codePoint rune, frames []GlyphIndex, flags AnimationFlags)) {
+	var frames []GlyphIndex
+	numSettings := self.Settings().Count()
+	settings := make([]uint8, numSettings)
+	for i := uint8(0); i < numSettings; i++ {
+		settings[i] = self.Settings().GetInitValue(SettingKey(i))
+	}
+
+	mapping := self.Mapping()
+	numEntries := uint32(mapping.NumEntries())
+	offsetToSearchIndex := self.OffsetToMapping + 2
+	for i := uint32(0); i < numEntries; i++ {
+		startOffset, _ := mapping.entryDataBounds(i)
+		switchType := self.Data[startOffset]
+		if switchType == 255 { continue } // inconditional single glyph mapping
+
+		startOffset += 1
+		if switchType != 254 {
+			targetCase := mapping.EvaluateSwitch(switchType, settings)
+			startOffset = internal.SkipMappingGroups(self.Data, startOffset, targetCase)
+		}
+		group := internal.DecodeMappingGroup(self.Data, startOffset)
+		if group.Size == 1 { continue }
+		frames = frames[ : 0]
+		for n := uint8(0); n < group.Size; n++ {
+			frames = append(frames, GlyphIndex(group.Glyph(self.Data, n)))
+		}
+		codePoint := rune(int32(internal.DecodeUint32LE(self.Data[offsetToSearchIndex + (i << 2) : ])))
+		fn(codePoint, frames, AnimationFlags(group.AnimFlags))
+	}
+}
+
+// Returns whether any code point maps to the given glyph, on any of
+// its switch cases. Glyphs that are only reachable through rewrite
+// rules are not considered mapped.
+func (self *Font) IsGlyphMapped(glyphIndex GlyphIndex) bool {
+	mapping := self.Mapping()
+	numEntries := uint32(mapping.NumEntries())
+	target := uint16(glyphIndex)
+	for i := uint32(0); i < numEntries; i++ {
+		startOffset, endOffset := mapping.entryDataBounds(i)
+		switchType := self.Data[startOffset]
+		startOffset += 1
+		if switchType == 255 { // inconditional mapping
+			if internal.DecodeUint16LE(self.Data[startOffset : ]) == target { return true }
+			continue
+		}
+
+		for startOffset < endOffset {
+			group := internal.DecodeMappingGroup(self.Data, startOffset)
+			startOffset = group.EndOffset()
+			for n := uint8(0); n < group.Size; n++ {
+				if group.Glyph(self.Data, n) == target { return true }
+			}
+		}
+		if startOffset != endOffset { panic(invalidFontData) } // discretionary assertion
+	}
+	return false
+}
+
+// Returns the required code points that aren't mapped by the font, in the
+// same order they first appear, without duplicates. Control codes below ' '
+// (space) are skipped, as they can't be mapped and should be handled by the
+// renderer instead. Invalid code points, like surrogate halves, are always
+// reported as missing. No normalization is performed, so decomposed
+// sequences with combining marks require each of the runes to be mapped.
+func (self *Font) CoversRunes(required []rune) []rune {
+	var missing []rune
+	var reported map[rune]struct{} // only allocated if anything is missing
+	mapping := self.Mapping()
+	for _, codePoint := range required {
+		if codePoint >= 0 && codePoint < ' ' { continue }
+		if utf8.ValidRune(codePoint) {
+			_, _, found := mapping.findCodePointData(codePoint)
+			if found { continue }
+		}
+		if reported == nil { reported = make(map[rune]struct{}) }
+		_, alreadyReported := reported[codePoint]
+		if alreadyReported { continue }
+		reported[codePoint] = struct{}{}
+		missing = append(missing, codePoint)
+	}
+	return missing
+}
+
+// Returns the number of distinct color indices referenced by the glyph
+// masks, excluding 0 (transparent). Unlike [FontColor.Count](), which
+// reports the declared number of colors, this reports the colors that
+// are actually used. This requires rasterizing all the glyphs, so it's
+// relatively expensive.
+func (self *Font) UsedColorCount() int {
+	var used [256]bool
+	self.Glyphs().EachMask(func(_ GlyphIndex, glyphMask *image.Alpha) {
+		if glyphMask == nil { return }
+		for _, colorIndex := range glyphMask.Pix {
+			used[colorIndex] = true
+		}
+	})
+
+	var count int
+	for colorIndex := 1; colorIndex < 256; colorIndex++ {
+		if used[colorIndex] { count += 1 }
+	}
+	return count
+}
+
+// Returns the absolute data offsets for the mapping of the given code point,
+// starting at the switch type byte.
+func (self *FontMapping) findCodePointData(codePoint rune) (uint32, uint32, bool) {
+	target := uint32(int32(codePoint))
+	numEntries := self.NumEntries()
+	offsetToSearchIndex := self.OffsetToMapping + 2
+	minIndex, maxIndex := uint32(0), uint32(numEntries)
+	for minIndex < maxIndex {
+		midIndex := (minIndex + maxIndex) >> 1
+		value := internal.DecodeUint32LE(self.Data[offsetToSearchIndex + (midIndex << 2) : ])
+		if value < target {
+			minIndex = midIndex + 1
+		} else {
+			maxIndex = midIndex
+		}
+	}
+	if minIndex >= uint32(numEntries) { return 0, 0, false }
+	value := internal.DecodeUint32LE(self.Data[offsetToSearchIndex + (minIndex << 2) : ])
+	if value != target { return 0, 0, false }
+
+	startOffset, endOffset := self.entryDataBounds(minIndex)
+	return startOffset, endOffset, true
+}
+
+// Returns the absolute data offsets for the mapping entry at the given
+// index of the code points search index, starting at the switch type byte.
+func (self *FontMapping) entryDataBounds(entryIndex uint32) (uint32, uint32) {
+	numEntries := uint32(self.NumEntries())
+	offsetToMappingEndOffsets := self.OffsetToMapping + 2 + (numEntries << 2)
+	codePointEndOffsetIndex := offsetToMappingEndOffsets + entryIndex + (entryIndex << 1)
+	endOffset := internal.DecodeUint24LE(self.Data[codePointEndOffsetIndex : ])
+	var startOffset uint32
+	if entryIndex > 0 {
+		startOffset = internal.DecodeUint24LE(self.Data[codePointEndOffsetIndex - 3 : ])
+	}
+	if endOffset <= startOffset { panic(invalidFontData) }
+	offsetToMappingData := offsetToMappingEndOffsets + numEntries + (numEntries << 1)
+	return offsetToMappingData + startOffset, offsetToMappingData + endOffset
+}
+
 func (self *FontMapping) Validate(mode FmtValidation) error {
 	// default checks
-	// ...
+	err := self.validateSwitches()
+	if err != nil { return err }
 
-	// strict checks
-	if mode == FmtStrict {
-		// TODO: 
-		panic("unimplemented")
-	}
+	// strict checks (none yet)
 
 	return nil
 }
 
+func (self *FontMapping) validateSwitches() error {
+	numSwitchTypes := self.NumSwitchTypes()
+	if numSwitchTypes > 254 { return errors.New("can't have more than 254 mapping switches") }
+	numSettings := (*FontSettings)(self).Count()
+	var prevEndOffset uint16
+	for switchKey := uint8(0); switchKey < numSwitchTypes; switchKey++ {
+		endOffsetIndex := self.OffsetToMappingSwitches + 1 + (uint32(switchKey) << 1)
+		endOffset := internal.DecodeUint16LE(self.Data[endOffsetIndex : ])
+		if endOffset <= prevEndOffset {
+			return fmt.Errorf("mapping switch #%d doesn't have any settings", switchKey)
+		}
+		prevEndOffset = endOffset
+
+		start, end := self.switchSettingsBounds(switchKey)
+		if int(end) > len(self.Data) { return errors.New("mapping switches data exceeds font data") }
+		numCases := 1
+		for i := start; i < end; i++ {
+			settingKey := SettingKey(self.Data[i])
+			if uint8(settingKey) >= numSettings {
+				return fmt.Errorf("mapping switch #%d references undefined setting #%d", switchKey, settingKey)
+			}
+			numOptions := int((*FontSettings)(self).GetNumOptions(settingKey))
+			if numOptions == 0 {
+				return fmt.Errorf("mapping switch #%d references setting #%d, which has no options", switchKey, settingKey)
+			}
+			numCases *= numOptions
+			if numCases > 254 { return fmt.Errorf("mapping switch #%d has more than 254 cases", switchKey) }
+		}
+	}
+	return nil
+}
+
 // --- rewrite rules section ---
 
 type FontRewrites Font
@@ -955,27 +1788,34 @@ func (self *FontRewrites) NumUTF8Rules() uint16 {
 }
 
 func (self *FontRewrites) EvaluateCondition(conditionKey uint8, settings []uint8) bool {
+	dataIndex, maxDataIndex := self.conditionDataBounds(conditionKey)
+	endDataIndex, satisfied := self.evalConditionSubexpr(dataIndex, maxDataIndex, settings)
+	if endDataIndex != maxDataIndex { panic(brokenCode) }
+	return satisfied
+
+	// - 0b000X_XXXX: `OR` condition group. The X's indicate the number of terms in the expression (can't be < 2).
+	// - 0b001X_XXXX: `AND` condition group. The X's indicate the number of terms in the expression (can't be < 2).
+}
+
+// Returns the start and end data indices of the given condition. The
+// end index is exclusive, which is what the subexpression evaluation
+// functions expect as maxDataIndex once all the terms are consumed.
+func (self *FontRewrites) conditionDataBounds(conditionKey uint8) (uint32, uint32) {
 	numConditions := self.NumConditions()
 	if conditionKey >= numConditions { panic("invalid condition key") }
 
+	endOffsetIndex := self.OffsetToRewriteConditions + 1 + (uint32(conditionKey) << 1)
+	endOffset := internal.DecodeUint16LE(self.Data[endOffsetIndex : ])
 	var startOffset uint16 = 0
-	endOffset := internal.DecodeUint16LE(self.Data[self.OffsetToRewriteConditions + 1 + uint32(conditionKey) : ])
 	if conditionKey > 0 {
-		startOffset = internal.DecodeUint16LE(self.Data[self.OffsetToRewriteConditions + uint32(conditionKey) : ])
+		startOffset = internal.DecodeUint16LE(self.Data[endOffsetIndex - 2 : ])
 	}
 	if endOffset <= startOffset { panic(invalidFontData) }
-	endOffset -= 1 // we will evaluate from last to first
 
 	offsetToRewriteConditionsData := self.OffsetToRewriteConditions + 1 + (uint32(numConditions) << 1)
-	maxDataIndex := offsetToRewriteConditionsData + uint32(endOffset)
-	if int(maxDataIndex) >= len(self.Data) { panic(invalidFontData) } // discretionary assertion
-	dataIndex := offsetToRewriteConditionsData + uint32(startOffset)
-	endDataIndex, satisfied := self.evalConditionSubexpr(dataIndex, maxDataIndex, settings)
-	if endDataIndex != maxDataIndex { panic(brokenCode) }
-	return satisfied
-
-	// - 0b000X_XXXX: `OR` condition group. The X's indicate the number of terms in the expression (can't be < 2).
-	// - 0b001X_XXXX: `AND` condition group. The X's indicate the number of terms in the expression (can't be < 2).
+	endDataIndex := offsetToRewriteConditionsData + uint32(endOffset)
+	if int(endDataIndex) > len(self.Data) { panic(invalidFontData) } // discretionary assertion
+	return offsetToRewriteConditionsData + uint32(startOffset), endDataIndex
 }
 
 // Returns the new dataIndex and the result.
@@ -993,9 +1833,8 @@ func (self *FontRewrites) evalConditionSubexpr(dataIndex, maxDataIndex uint32, s
 			if dataIndex > maxDataIndex { panic(invalidFontData) } // discretionary assertion
 			if satisfied {
 				numSettings := uint8(min(len(settings), 255))
-				for i < numTerms { // could be optimized in some cases, but it's a pain
+				for i += 1; i < numTerms; i++ { // could be optimized in some cases, but it's a pain
 					dataIndex = self.skipConditionSubexpr(dataIndex, maxDataIndex, numSettings)
-					i += 1
 				}
 				return dataIndex, true
 			}
@@ -1010,20 +1849,20 @@ func (self *FontRewrites) evalConditionSubexpr(dataIndex, maxDataIndex uint32, s
 			if dataIndex > maxDataIndex { panic(invalidFontData) } // discretionary assertion
 			if !satisfied {
 				numSettings := uint8(min(len(settings), 255))
-				for i < numTerms { // could be optimized in some cases, but it's a pain
+				for i += 1; i < numTerms; i++ { // could be optimized in some cases, but it's a pain
 					dataIndex = self.skipConditionSubexpr(dataIndex, maxDataIndex, numSettings)
-					i += 1
 				}
 				return dataIndex, false
 			}
 		}
 		return dataIndex, true
 	case 0b0100_0000: // comparison
-		setting := self.Data[dataIndex + 1]
-		if int(setting) > len(settings) { panic(invalidFontData) } // discretionary assertion
+		settingKey := self.Data[dataIndex + 1]
+		if int(settingKey) >= len(settings) { panic(invalidFontData) } // discretionary assertion
+		setting := settings[settingKey]
 		operand := self.Data[dataIndex + 2]
 		if (ctrl & 0b0001_0000) == 0 { // comparing two settings
-			if int(operand) > len(settings) { panic(invalidFontData) } // discretionary assertion
+			if int(operand) >= len(settings) { panic(invalidFontData) } // discretionary assertion
 			operand = settings[operand]
 		}
 		switch (ctrl & 0b0000_1111) {
@@ -1114,6 +1953,58 @@ func (self *FontRewrites) skipConditionSubexpr(dataIndex, maxDataIndex uint32, n
 	}
 }
 
+// Returns the distinct settings referenced by the given condition,
+// sorted in ascending order.
+func (self *FontRewrites) ConditionSettings(conditionKey uint8) []SettingKey {
+	dataIndex, maxDataIndex := self.conditionDataBounds(conditionKey)
+	var referenced [256]bool
+	endDataIndex := self.collectConditionSettings(dataIndex, maxDataIndex, &referenced)
+	if endDataIndex != maxDataIndex { panic(brokenCode) }
+
+	var settings []SettingKey
+	for i, found := range referenced {
+		if found { settings = append(settings, SettingKey(i)) }
+	}
+	return settings
+}
+
+// Returns a human-friendly representation of the given condition, like
+// "#0 == 1 AND (#1 > 0 OR #2 != #3)". This is the same format used to
+// define conditions on the builder.
+func (self *FontRewrites) ConditionString(conditionKey uint8) string {
+	dataIndex, maxDataIndex := self.conditionDataBounds(conditionKey)
+	return string(internal.AppendConditionString(nil, self.Data[dataIndex : maxDataIndex]))
+}
+
+// Same structure as skipConditionSubexpr, but marking referenced settings.
+func (self *FontRewrites) collectConditionSettings(dataIndex, maxDataIndex uint32, referenced *[256]bool) uint32 {
+	if dataIndex > maxDataIndex { panic(invalidFontData) }
+
+	ctrl := self.Data[dataIndex]
+	switch ctrl & 0b1110_0000 {
+	case 0b0000_0000, 0b0010_0000: // OR, AND
+		dataIndex += 1
+		numTerms := (ctrl & 0b0001_1111)
+		for i := uint8(0); i < numTerms; i++ {
+			dataIndex = self.collectConditionSettings(dataIndex, maxDataIndex, referenced)
+			if dataIndex > maxDataIndex { panic(invalidFontData) } // discretionary assertion
+		}
+		return dataIndex
+	case 0b0100_0000: // comparison
+		if (ctrl & 0b0000_1111) > 0b101 { panic(invalidFontData) }
+		referenced[self.Data[dataIndex + 1]] = true
+		if (ctrl & 0b0001_0000) == 0 { // comparing two settings
+			referenced[self.Data[dataIndex + 2]] = true
+		}
+		return dataIndex + 3
+	case 0b0110_0000, 0b1000_0000, 0b1010_0000, 0b1100_0000: // quick comparisons
+		referenced[self.Data[dataIndex + 1]] = true
+		return dataIndex + 2
+	default: // undefined control mode
+		panic(invalidFontData)
+	}
+}
+
 type GlyphRewriteRule internal.RawBlock
 // TODO: a validation method would be nice
 func (self *GlyphRewriteRule) Condition() uint8 { return self.Data[0] } // 255 means no condition
@@ -1124,10 +2015,23 @@ func (self *GlyphRewriteRule) InLen() uint8 { return self.Data[1] + self.Data[2]
 func (self *GlyphRewriteRule) OutLen() uint8 { return self.Data[4] } // sequence size
 func (self *GlyphRewriteRule) EachOut(each func(GlyphIndex)) {
 	outSize := int(self.Data[4])
-	for i := 5; i < 5 + outSize; i += 2 {
+	for i := 5; i < 5 + (outSize << 1); i += 2 {
 		each(GlyphIndex(internal.DecodeUint16LE(self.Data[i : ])))
 	}
 }
+
+// Iterates the input elements of the rule, going through the head, body
+// and tail in order. If isSet is true, the element is the index of a
+// glyph set (see [FontRewrites.GetGlyphSet]()) instead of a glyph index.
+func (self *GlyphRewriteRule) EachIn(each func(elem GlyphIndex, isSet bool)) {
+	eachRuleInput(self.Data, 5 + (int(self.Data[4]) << 1), 2, func(elem []byte, set uint8, isSet bool) {
+		if isSet {
+			each(GlyphIndex(set), true)
+		} else {
+			each(GlyphIndex(internal.DecodeUint16LE(elem)), false)
+		}
+	})
+}
 func (self *GlyphRewriteRule) Equals(other GlyphRewriteRule) bool {
 	if len(self.Data) != len(other.Data) { return false }
 	for i := 0; i < len(self.Data); i++ {
@@ -1136,6 +2040,72 @@ func (self *GlyphRewriteRule) Equals(other GlyphRewriteRule) bool {
 	return true
 }
 
+// Returns a human-readable representation of the rule, formatted as
+// "head | body -> output [tail]". The head and tail parts are omitted
+// when empty. Glyphs are written by name when they have one, or as
+// "#index" otherwise, and glyph sets as "set#index". If the rule has
+// a condition, " (condition #key)" is appended at the end.
+func (self *GlyphRewriteRule) String(font *Font) string {
+	names := make(map[GlyphIndex]string)
+	font.Glyphs().EachNamed(func(glyphIndex GlyphIndex, name string) {
+		names[glyphIndex] = name
+	})
+	var appendGlyph = func(out []byte, glyphIndex GlyphIndex) []byte {
+		name, found := names[glyphIndex]
+		if found { return append(out, name...) }
+		if glyphIndex == GlyphMissing { return append(out, "missing"...) }
+		return strconv.AppendUint(append(out, '#'), uint64(glyphIndex), 10)
+	}
+
+	var head, body, tail []byte
+	headLen, bodyLen := int(self.HeadLen()), int(self.BodyLen())
+	var n int
+	self.EachIn(func(elem GlyphIndex, isSet bool) {
+		part := &body
+		if n < headLen { part = &head } else if n >= headLen + bodyLen { part = &tail }
+		if len(*part) > 0 { *part = append(*part, ' ') }
+		if isSet {
+			*part = strconv.AppendUint(append(*part, "set#"...), uint64(elem), 10)
+		} else {
+			*part = appendGlyph(*part, elem)
+		}
+		n += 1
+	})
+
+	var out []byte
+	if len(head) > 0 { out = append(append(out, head...), " | "...) }
+	out = append(append(out, body...), " ->"...)
+	self.EachOut(func(glyphIndex GlyphIndex) {
+		out = appendGlyph(append(out, ' '), glyphIndex)
+	})
+	if len(tail) > 0 { out = append(append(append(out, " ["...), tail...), ']') }
+	if self.Condition() != 255 {
+		out = strconv.AppendUint(append(out, " (condition #"...), uint64(self.Condition()), 10)
+		out = append(out, ')')
+	}
+	return string(out)
+}
+
+// Returns whether the rule's input pattern (head, body and tail, including
+// glyph sets) matches the start of the given glyph sequence. Extra glyphs
+// after the input pattern are ignored. The rule condition is not checked.
+func (self *GlyphRewriteRule) Matches(font *Font, seq []GlyphIndex) bool {
+	if len(seq) < int(self.InLen()) { return false }
+	matches := true
+	var n int
+	self.EachIn(func(elem GlyphIndex, isSet bool) {
+		if !matches { return }
+		if isSet {
+			set := font.Rewrites().GetGlyphSet(uint8(elem))
+			matches = set.Contains(seq[n])
+		} else {
+			matches = (elem == seq[n])
+		}
+		n += 1
+	})
+	return matches
+}
+
 func (self *FontRewrites) GetGlyphRule(index uint16) GlyphRewriteRule {
 	numRules := uint32(self.NumGlyphRules())
 	index32 := uint32(index)
@@ -1153,6 +2123,14 @@ func (self *FontRewrites) GetGlyphRule(index uint16) GlyphRewriteRule {
 	return GlyphRewriteRule(internal.RawBlock{ ruleData })
 }
 
+// Calls the given function for each glyph rewrite rule, in order.
+func (self *FontRewrites) EachGlyphRule(fn func(index uint16, rule GlyphRewriteRule)) {
+	numRules := self.NumGlyphRules()
+	for i := uint16(0); i < numRules; i++ {
+		fn(i, self.GetGlyphRule(i))
+	}
+}
+
 type Utf8RewriteRule internal.RawBlock
 func (self *Utf8RewriteRule) Condition() uint8 { return self.Data[0] } // 255 means no condition
 func (self *Utf8RewriteRule) HeadLen() uint8 { return self.Data[1] }
@@ -1162,10 +2140,23 @@ func (self *Utf8RewriteRule) InLen() uint8 { return self.Data[1] + self.Data[2]
 func (self *Utf8RewriteRule) OutLen() uint8 { return self.Data[4] } // sequence size
 func (self *Utf8RewriteRule) EachOut(each func(rune)) {
 	outSize := int(self.Data[4])
-	for i := 5; i < 5 + outSize; i += 2 {
+	for i := 5; i < 5 + (outSize << 2); i += 4 {
 		each(rune(internal.DecodeUint32LE(self.Data[i : ])))
 	}
 }
+
+// Iterates the input elements of the rule, going through the head, body
+// and tail in order. If isSet is true, the element is the index of a
+// rune set (see [FontRewrites.GetUtf8Set]()) instead of a code point.
+func (self *Utf8RewriteRule) EachIn(each func(elem rune, isSet bool)) {
+	eachRuleInput(self.Data, 5 + (int(self.Data[4]) << 2), 4, func(elem []byte, set uint8, isSet bool) {
+		if isSet {
+			each(rune(set), true)
+		} else {
+			each(rune(internal.DecodeUint32LE(elem)), false)
+		}
+	})
+}
 func (self *Utf8RewriteRule) Equals(other Utf8RewriteRule) bool {
 	if len(self.Data) != len(other.Data) { return false }
 	for i := 0; i < len(self.Data); i++ {
@@ -1177,6 +2168,32 @@ func (self *Utf8RewriteRule) String() string {
 	return fmt.Sprintf("Utf8RewriteRule%v", self.Data)
 }
 
+// Rule input blocks are made of fragments with sets first and elements
+// afterwards, or a single zero byte if the block is empty.
+func eachRuleInput(data []byte, index int, elemSize int, each func(elem []byte, set uint8, isSet bool)) {
+	for _, blockLen := range [3]uint8{data[1], data[2], data[3]} {
+		if blockLen == 0 {
+			index += 1
+			continue
+		}
+
+		var decoded int
+		for decoded < int(blockLen) {
+			numSets, numElems := int(data[index] >> 4), int(data[index] & 0x0F)
+			index += 1
+			for n := 0; n < numSets; n++ {
+				each(nil, data[index], true)
+				index += 1
+			}
+			for n := 0; n < numElems; n++ {
+				each(data[index : index + elemSize], 0, false)
+				index += elemSize
+			}
+			decoded += numSets + numElems
+		}
+	}
+}
+
 func (self *FontRewrites) GetUtf8Rule(index uint16) Utf8RewriteRule {
 	numRules := uint32(self.NumUTF8Rules())
 	index32 := uint32(index)
@@ -1194,6 +2211,14 @@ func (self *FontRewrites) GetUtf8Rule(index uint16) Utf8RewriteRule {
 	return Utf8RewriteRule(internal.RawBlock{ ruleData })
 }
 
+// Calls the given function for each utf8 rewrite rule, in order.
+func (self *FontRewrites) EachUtf8Rule(fn func(index uint16, rule Utf8RewriteRule)) {
+	numRules := self.NumUTF8Rules()
+	for i := uint16(0); i < numRules; i++ {
+		fn(i, self.GetUtf8Rule(i))
+	}
+}
+
 type GlyphRewriteSet internal.RawBlock
 func (self *GlyphRewriteSet) EachRange(each func(GlyphRange) error) error {
 	numRanges := int(self.Data[0])
@@ -1218,6 +2243,35 @@ func (self *GlyphRewriteSet) EachListGlyph(each func(GlyphIndex) error) error {
 	return nil
 }
 
+// Returns the total number of glyphs in the set, with ranges expanded.
+func (self *GlyphRewriteSet) Size() int {
+	numRanges := int(self.Data[0])
+	var size int
+	for i := 1; i < 1 + numRanges*3; i += 3 {
+		size += int(self.Data[i + 2]) + 1
+	}
+	return size + int(self.Data[1 + numRanges*3])
+}
+
+// Returns whether the given glyph index is part of the set, either
+// within one of its ranges or in its list. The format doesn't require
+// ranges or list elements to be sorted, so this is a linear search, but
+// sets can't have more than 255 ranges and 255 list elements anyway.
+func (self *GlyphRewriteSet) Contains(glyphIndex GlyphIndex) bool {
+	numRanges := int(self.Data[0])
+	for i := 1; i < 1 + numRanges*3; i += 3 {
+		first := GlyphIndex(internal.DecodeUint16LE(self.Data[i : i + 2]))
+		if glyphIndex >= first && glyphIndex <= first + GlyphIndex(self.Data[i + 2]) { return true }
+	}
+	elemsIndex := 1 + numRanges*3
+	numElems := int(self.Data[elemsIndex])
+	for i := 0; i < numElems; i += 1 {
+		dataIndex := elemsIndex + 1 + (i << 1)
+		if GlyphIndex(internal.DecodeUint16LE(self.Data[dataIndex : dataIndex + 2])) == glyphIndex { return true }
+	}
+	return false
+}
+
 func (self *FontRewrites) NumGlyphSets() uint8 {
 	return self.Data[self.OffsetToRewriteGlyphSets + 0]
 }
@@ -1242,9 +2296,9 @@ func (self *FontRewrites) GetGlyphSet(set uint8) GlyphRewriteSet {
 type Utf8RewriteSet internal.RawBlock
 func (self *Utf8RewriteSet) EachRange(each func(start, end rune) error) error {
 	numRanges := int(self.Data[0])
-	for i := 1; i < numRanges*5; i += 5 {
+	for i := 1; i < 1 + numRanges*5; i += 5 {
 		codePoint := rune(internal.DecodeUint32LE(self.Data[i : i + 4]))
-		err := each(codePoint, codePoint + rune(self.Data[i + 3]))
+		err := each(codePoint, codePoint + rune(self.Data[i + 4]))
 		if err != nil { return err }
 	}
 	return nil
@@ -1262,6 +2316,32 @@ func (self *Utf8RewriteSet) EachListRune(each func(rune) error) error {
 	return nil
 }
 
+// Returns the total number of code points in the set, with ranges expanded.
+func (self *Utf8RewriteSet) Size() int {
+	numRanges := int(self.Data[0])
+	var size int
+	for i := 1; i < 1 + numRanges*5; i += 5 {
+		size += int(self.Data[i + 4]) + 1
+	}
+	return size + int(self.Data[1 + numRanges*5])
+}
+
+// Same as [GlyphRewriteSet.Contains](), but for code points.
+func (self *Utf8RewriteSet) Contains(codePoint rune) bool {
+	numRanges := int(self.Data[0])
+	for i := 1; i < 1 + numRanges*5; i += 5 {
+		first := rune(internal.DecodeUint32LE(self.Data[i : i + 4]))
+		if codePoint >= first && codePoint <= first + rune(self.Data[i + 4]) { return true }
+	}
+	elemsIndex := 1 + numRanges*5
+	numElems := int(self.Data[elemsIndex])
+	for i := 0; i < numElems; i += 1 {
+		dataIndex := elemsIndex + 1 + (i << 2)
+		if rune(internal.DecodeUint32LE(self.Data[dataIndex : dataIndex + 4])) == codePoint { return true }
+	}
+	return false
+}
+
 func (self *FontRewrites) NumUTF8Sets() uint8 {
 	return self.Data[self.OffsetToRewriteUtf8Sets + 0]
 }
@@ -1289,11 +2369,117 @@ func (self *FontRewrites) Validate(mode FmtValidation) error {
 
 	// strict checks
 	if mode == FmtStrict {
-		panic("unimplemented")
-		// I need to validate that rewrite rules don't contain control indices,
-		// with the only exception of the initial 'GlyphMissing'.
+		err := self.validateGlyphSetsAndRules()
+		if err != nil { return err }
+		err = self.validateUtf8SetsAndRules()
+		if err != nil { return err }
+	}
+
+	return nil
+}
+
+// Glyph indices in rewrite rules and sets must be within the font glyphs,
+// with the only exception of [GlyphMissing], the first control index.
+func (self *FontRewrites) isValidRuleGlyph(glyphIndex GlyphIndex) bool {
+	return glyphIndex < GlyphIndex((*FontMetrics)(self).NumGlyphs()) || glyphIndex == GlyphMissing
+}
+
+func (self *FontRewrites) validateGlyphSetsAndRules() error {
+	numSets := self.NumGlyphSets()
+	numGlyphs := GlyphIndex((*FontMetrics)(self).NumGlyphs())
+	for i := uint8(0); i < numSets; i++ {
+		set := self.GetGlyphSet(i)
+		err := set.EachRange(func(glyphRange GlyphRange) error {
+			// unlike isValidRuleGlyph(), ranges can't include GlyphMissing
+			// nor any other control glyphs, so both ends must be real glyphs
+			if glyphRange.Last < glyphRange.First || glyphRange.First >= numGlyphs || glyphRange.Last >= numGlyphs {
+				return fmt.Errorf("glyph set #%d contains invalid range [%d, %d]", i, glyphRange.First, glyphRange.Last)
+			}
+			return nil
+		})
+		if err != nil { return err }
+		err = set.EachListGlyph(func(glyphIndex GlyphIndex) error {
+			if !self.isValidRuleGlyph(glyphIndex) {
+				return fmt.Errorf("glyph set #%d contains invalid glyph index %d", i, glyphIndex)
+			}
+			return nil
+		})
+		if err != nil { return err }
+	}
+
+	numConditions := self.NumConditions()
+	for i := uint16(0); i < self.NumGlyphRules(); i++ {
+		rule := self.GetGlyphRule(i)
+		if rule.Condition() != 255 && rule.Condition() >= numConditions {
+			return fmt.Errorf("glyph rule #%d references undefined condition %d", i, rule.Condition())
+		}
+		var err error
+		rule.EachIn(func(elem GlyphIndex, isSet bool) {
+			if err != nil { return }
+			if isSet {
+				if elem >= GlyphIndex(numSets) {
+					err = fmt.Errorf("glyph rule #%d references undefined glyph set %d", i, elem)
+				}
+			} else if !self.isValidRuleGlyph(elem) {
+				err = fmt.Errorf("glyph rule #%d input contains invalid glyph index %d", i, elem)
+			}
+		})
+		if err != nil { return err }
+		rule.EachOut(func(glyphIndex GlyphIndex) {
+			if err == nil && !self.isValidRuleGlyph(glyphIndex) {
+				err = fmt.Errorf("glyph rule #%d output contains invalid glyph index %d", i, glyphIndex)
+			}
+		})
+		if err != nil { return err }
+	}
+	return nil
+}
+
+func (self *FontRewrites) validateUtf8SetsAndRules() error {
+	numSets := self.NumUTF8Sets()
+	for i := uint8(0); i < numSets; i++ {
+		set := self.GetUtf8Set(i)
+		err := set.EachRange(func(start, end rune) error {
+			if !utf8.ValidRune(start) || !utf8.ValidRune(end) {
+				return fmt.Errorf("utf8 set #%d contains invalid range [%d, %d]", i, start, end)
+			}
+			return nil
+		})
+		if err != nil { return err }
+		err = set.EachListRune(func(codePoint rune) error {
+			if !utf8.ValidRune(codePoint) {
+				return fmt.Errorf("utf8 set #%d contains invalid code point %d", i, codePoint)
+			}
+			return nil
+		})
+		if err != nil { return err }
 	}
 
+	numConditions := self.NumConditions()
+	for i := uint16(0); i < self.NumUTF8Rules(); i++ {
+		rule := self.GetUtf8Rule(i)
+		if rule.Condition() != 255 && rule.Condition() >= numConditions {
+			return fmt.Errorf("utf8 rule #%d references undefined condition %d", i, rule.Condition())
+		}
+		var err error
+		rule.EachIn(func(elem rune, isSet bool) {
+			if err != nil { return }
+			if isSet {
+				if elem < 0 || elem >= rune(numSets) {
+					err = fmt.Errorf("utf8 rule #%d references undefined utf8 set %d", i, elem)
+				}
+			} else if !utf8.ValidRune(elem) {
+				err = fmt.Errorf("utf8 rule #%d input contains invalid code point %d", i, elem)
+			}
+		})
+		if err != nil { return err }
+		rule.EachOut(func(codePoint rune) {
+			if err == nil && !utf8.ValidRune(codePoint) {
+				err = fmt.Errorf("utf8 rule #%d output contains invalid code point %d", i, codePoint)
+			}
+		})
+		if err != nil { return err }
+	}
 	return nil
 }
 
@@ -1354,8 +2540,45 @@ func (self *FontKerning) GetVert(prev, curr GlyphIndex) int8 {
 	return int8(self.Data[self.OffsetToVertKernings + 3 + (numPairs << 2) + uint32(minIndex)])
 }
 
-func (self *FontKerning) EachPair(func (prev, curr GlyphIndex, kern int8)) { panic("unimplemented") }
-func (self *FontKerning) EachVertPair(func (prev, curr GlyphIndex, kern int8)) { panic("unimplemented") }
+// Calls the given function for each horizontal kerning pair,
+// sorted by (prev, curr).
+func (self *FontKerning) EachPair(fn func(prev, curr GlyphIndex, kern int8)) {
+	self.eachPairAt(self.OffsetToHorzKernings, fn)
+}
+
+// Same as [FontKerning.EachPair](), but for vertical kerning pairs.
+func (self *FontKerning) EachVertPair(fn func(prev, curr GlyphIndex, kern int8)) {
+	self.eachPairAt(self.OffsetToVertKernings, fn)
+}
+
+// Checks that pairs are strictly sorted and reference valid glyphs.
+func (self *FontKerning) validatePairsAt(offsetToKernings uint32) error {
+	numGlyphs := GlyphIndex((*FontMetrics)(self).NumGlyphs())
+	var prevPair uint32
+	var err error
+	var n int
+	self.eachPairAt(offsetToKernings, func(prev, curr GlyphIndex, _ int8) {
+		if err != nil { return }
+		pair := (uint32(prev) << 16) | uint32(curr)
+		if prev >= numGlyphs || curr >= numGlyphs {
+			err = fmt.Errorf("kerning pair (%d, %d) references glyphs beyond glyph count", prev, curr)
+		} else if n > 0 && pair <= prevPair {
+			err = errors.New("kerning pairs must be sorted and unique")
+		}
+		prevPair = pair
+		n += 1
+	})
+	return err
+}
+
+func (self *FontKerning) eachPairAt(offsetToKernings uint32, fn func(prev, curr GlyphIndex, kern int8)) {
+	numPairs := internal.DecodeUint24LE(self.Data[offsetToKernings : ])
+	offsetToValues := offsetToKernings + 3 + (numPairs << 2)
+	for i := uint32(0); i < numPairs; i++ {
+		pair := internal.DecodeUint32LE(self.Data[offsetToKernings + 3 + (i << 2) : ])
+		fn(GlyphIndex(pair >> 16), GlyphIndex(pair & 0xFFFF), int8(self.Data[offsetToValues + i]))
+	}
+}
 
 func (self *FontKerning) Validate(mode FmtValidation) error {
 	// default checks
@@ -1363,8 +2586,45 @@ func (self *FontKerning) Validate(mode FmtValidation) error {
 
 	// strict checks
 	if mode == FmtStrict {
-		panic("unimplemented")
+		err := self.validatePairsAt(self.OffsetToHorzKernings)
+		if err != nil { return err }
+		err = self.validatePairsAt(self.OffsetToVertKernings)
+		if err != nil { return err }
 	}
 
 	return nil
 }
+
+// Returns the horizontal pen delta between the origins of two consecutive
+// glyphs: the advance of prev, plus the horizontal interspacing, plus the
+// horizontal kerning between the glyphs.
+//
+// For monospaced fonts, [FontMetrics.MonoWidth]() is used as the advance
+// and kerning is ignored.
+func (self *Font) PairAdvance(prev, curr GlyphIndex) int {
+	advance := self.GlyphAdvance(prev) + int(self.Metrics().HorzInterspacing())
+	if self.Metrics().MonoWidth() != 0 { return advance }
+	return advance + int(self.Kerning().Get(prev, curr))
+}
+
+// Returns the horizontal advance of the given glyph, or the font's
+// [FontMetrics.MonoWidth]() for monospaced fonts. This is the space
+// the glyph takes at the end of a line, where no interspacing nor
+// kerning apply.
+func (self *Font) GlyphAdvance(glyphIndex GlyphIndex) int {
+	monoWidth := self.Metrics().MonoWidth()
+	if monoWidth != 0 { return int(monoWidth) }
+	return int(self.Glyphs().Advance(glyphIndex))
+}
+
+// Vertical counterpart of [Font.PairAdvance](). Returns the vertical pen
+// delta between the origins of two consecutive glyphs: the bottom advance
+// of prev, plus the vertical interspacing, plus the top advance of curr,
+// plus the vertical kerning between the glyphs. The font must have a
+// vertical layout, or the result will be meaningless.
+func (self *Font) PairVertAdvance(prev, curr GlyphIndex) int {
+	prevPlacement := self.Glyphs().Placement(prev)
+	currPlacement := self.Glyphs().Placement(curr)
+	advance := int(prevPlacement.BottomAdvance) + int(currPlacement.TopAdvance)
+	return advance + int(self.Metrics().VertInterspacing()) + int(self.Kerning().GetVert(prev, curr))
+}
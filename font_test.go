@@ -0,0 +1,15 @@
+package ggfnt
+
+import "testing"
+
+func TestUtf8RewriteSetContains(t *testing.T) {
+	// one range {'0', 9} and a list with 'x' and 'z'
+	set := Utf8RewriteSet{ Data: []byte{1, '0', 0, 0, 0, 9, 2, 'x', 0, 0, 0, 'z', 0, 0, 0} }
+	if set.Size() != 12 { t.Fatalf("expected set size 12, got %d", set.Size()) }
+	for _, codePoint := range []rune{'0', '5', '9', 'x', 'z'} {
+		if !set.Contains(codePoint) { t.Fatalf("expected Utf8RewriteSet.Contains(%q) to be true", codePoint) }
+	}
+	for _, codePoint := range []rune{'/', ':', 'y', 0} {
+		if set.Contains(codePoint) { t.Fatalf("expected Utf8RewriteSet.Contains(%q) to be false", codePoint) }
+	}
+}
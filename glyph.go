@@ -38,7 +38,12 @@ type GlyphPlacement struct {
 	// vertical bounds fields: these will be zero
 	// unless the font includes vertical layout data
 	TopAdvance, BottomAdvance uint8
-	HorzCenter uint8 // offset to the glyph's center pixel, from the origin
+
+	// Offset to the glyph's center pixel, from the origin. When drawing
+	// vertical text, glyphs are placed within columns of width
+	// [FontMetrics.VertLineWidth](), and the center pixel of each glyph
+	// is aligned with the center of the column (VertLineWidth/2).
+	HorzCenter uint8
 }
 
 func (self *GlyphPlacement) appendWithoutVertLayout(buffer []byte) []byte {
@@ -0,0 +1,127 @@
+package internal
+
+// Appends a human-friendly representation of the given rewrite condition
+// data to str, like "#0 == 1 AND (#1 > 0 OR #2 != #3)". The data must be
+// valid, or the function will panic.
+func AppendConditionString(str []byte, data []byte) []byte {
+	var index int
+	switch data[index] >> 5 {
+	case 0b000: // OR group
+		numTerms := data[index] & 0b0001_1111
+		if numTerms < 2 { panic(invalidConditionData) }
+		index += 1
+		for i := uint8(0); i < numTerms; i++ {
+			index, str = appendConditionSubTerm(data, index, str)
+			if i != numTerms - 1 {
+				str = append(str, []byte{' ', 'O', 'R', ' '}...)
+			}
+		}
+	case 0b001: // AND group
+		numTerms := data[index] & 0b0001_1111
+		if numTerms < 2 { panic(invalidConditionData) }
+		index += 1
+		for i := uint8(0); i < numTerms; i++ {
+			index, str = appendConditionSubTerm(data, index, str)
+			if i != numTerms - 1 {
+				str = append(str, []byte{' ', 'A', 'N', 'D', ' '}...)
+			}
+		}
+	default:
+		index, str = appendConditionSubTerm(data, index, str)
+	}
+	
+	if index != len(data) { panic(invalidConditionData) }
+	return str
+}
+
+// Returns the next index (can be at most len(data)),
+// and the str with the new term appended.
+func appendConditionSubTerm(data []byte, index int, str []byte) (int, []byte) {
+	if index >= len(data) { panic(invalidConditionData) }
+
+	switch data[index] >> 5 {
+	case 0b000: // OR group
+		numTerms := data[index] & 0b0001_1111
+		if numTerms < 2 { panic(invalidConditionData) }
+		index += 1
+		str = append(str, '(')
+		for i := uint8(0); i < numTerms; i++ {
+			index, str = appendConditionSubTerm(data, index, str)
+			if i == numTerms - 1 {
+				str = append(str, ')')
+			} else {
+				str = append(str, []byte{' ', 'O', 'R', ' '}...)
+			}
+		}
+	case 0b001: // AND group
+		numTerms := data[index] & 0b0001_1111
+		if numTerms < 2 { panic(invalidConditionData) }
+		index += 1
+		str = append(str, '(')
+		for i := uint8(0); i < numTerms; i++ {
+			index, str = appendConditionSubTerm(data, index, str)
+			if i == numTerms - 1 {
+				str = append(str, ')')
+			} else {
+				str = append(str, []byte{' ', 'A', 'N', 'D', ' '}...)
+			}
+		}
+	case 0b010: // comparison
+		// append first operand (setting)
+		str = append(str, '#')
+		str = AppendByteDigits(data[index + 1], str)
+
+		// append comparison operator
+		str = append(str, ' ')
+		switch data[index] & 0b0000_1111 {
+		case 0b000: str = append(str, '=', '=')
+		case 0b001: str = append(str, '!', '=')
+		case 0b010: str = append(str, '<')
+		case 0b011: str = append(str, '>')
+		case 0b100: str = append(str, '<', '=')
+		case 0b101: str = append(str, '>', '=')	
+		default:
+			panic(invalidConditionData)
+		}
+		str = append(str, ' ')
+
+		// append second operand
+		if (data[index] & 0b0001_0000) == 0 { // second operand is a setting too
+			str = append(str, '#')	
+		}
+		str = AppendByteDigits(data[index + 2], str)
+
+		// advance index
+		index += 3
+	case 0b011: // quick 'setting == const'
+		str = append(str, '#')
+		str = AppendByteDigits(data[index + 1], str)
+		str = append(str, ' ', '=', '=', ' ')
+		str = AppendByteDigits(data[index] & 0b0001_1111, str)
+		index += 2
+	case 0b100: // quick 'setting != const'
+		str = append(str, '#')
+		str = AppendByteDigits(data[index + 1], str)
+		str = append(str, ' ', '!', '=', ' ')
+		str = AppendByteDigits(data[index] & 0b0001_1111, str)
+		index += 2
+	case 0b101: // quick 'setting < const'
+		str = append(str, '#')
+		str = AppendByteDigits(data[index + 1], str)
+		str = append(str, ' ', '<', ' ')
+		str = AppendByteDigits(data[index] & 0b0001_1111, str)
+		index += 2
+	case 0b110: // quick 'setting > const'
+		str = append(str, '#')
+		str = AppendByteDigits(data[index + 1], str)
+		str = append(str, ' ', '>', ' ')
+		str = AppendByteDigits(data[index] & 0b0001_1111, str)
+		index += 2
+	default:
+		panic(invalidConditionData)
+	}
+	
+	return index, str
+}
+
+const invalidConditionData = "invalid rewrite condition data"
@@ -0,0 +1,50 @@
+package internal
+
+// Decoded glyph mapping group. In the mapping section, each code point
+// has a switch type byte followed by one group per switch case (or a
+// single glyph index if the switch type is 255). Each group starts with
+// a group info byte (range flag and size - 1), then the animation flags
+// if the group has more than one glyph, and then either the first glyph
+// index of a range or the whole list of glyph indices.
+type MappingGroup struct {
+	Size uint8 // between 1 and 128
+	AnimFlags uint8 // always 0 for single glyph groups
+	IsRange bool
+	GlyphsOffset uint32 // offset to the first glyph index within the data
+}
+
+// Decodes the mapping group starting at data[offset].
+func DecodeMappingGroup(data []byte, offset uint32) MappingGroup {
+	groupInfo := data[offset]
+	group := MappingGroup{ Size: (groupInfo & 0b0111_1111) + 1, IsRange: (groupInfo & 0b1000_0000) != 0 }
+	offset += 1
+	if group.Size > 1 {
+		group.AnimFlags = data[offset]
+		offset += 1
+	}
+	group.GlyphsOffset = offset
+	return group
+}
+
+// Returns the n-th glyph index of the group. Precondition: n < Size.
+func (self *MappingGroup) Glyph(data []byte, n uint8) uint16 {
+	if self.IsRange { return DecodeUint16LE(data[self.GlyphsOffset : ]) + uint16(n) }
+	return DecodeUint16LE(data[self.GlyphsOffset + (uint32(n) << 1) : ])
+}
+
+// Returns the offset right after the group's data, where the next
+// group starts, if any.
+func (self *MappingGroup) EndOffset() uint32 {
+	if self.IsRange { return self.GlyphsOffset + 2 }
+	return self.GlyphsOffset + (uint32(self.Size) << 1)
+}
+
+// Skips n consecutive mapping groups starting at data[offset] and
+// returns the offset to the group that follows them.
+func SkipMappingGroups(data []byte, offset uint32, n uint8) uint32 {
+	for ; n > 0; n-- {
+		group := DecodeMappingGroup(data, offset)
+		offset = group.EndOffset()
+	}
+	return offset
+}
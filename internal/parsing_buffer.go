@@ -2,6 +2,7 @@ package internal
 
 import "io"
 import "errors"
+import "strconv"
 import "unsafe"
 import "compress/gzip"
 
@@ -12,55 +13,123 @@ import "compress/gzip"
 
 type ParsingBuffer struct {
 	TempBuff []byte // size 1024, for temporary reads immediately copied to 'bytes'
-	gzipReader *gzip.Reader
+	reader io.Reader // gzip reader, or the raw data reader
 	FileType string
+	Section string // name of the section being parsed, used for errors
 
 	Bytes []byte
 	Index int // index of processed data within 'bytes'. unprocessed data == len(bytes) - index
 	eof bool
 }
 
+// Error returned by parsing functions. Offset is the index
+// of the processed data when the error was detected.
+type ParseError struct {
+	FileType string
+	Section string
+	Offset int
+	Msg string
+}
+
+func (self *ParseError) Error() string {
+	str := self.FileType + " parsing error at byte " + strconv.Itoa(self.Offset)
+	if self.Section != "" { str += " (" + self.Section + ")" }
+	return str + ": " + self.Msg
+}
+
 func (self *ParsingBuffer) NewError(details string) error {
-	return errors.New(self.FileType + " parsing error: " + details)
+	return &ParseError{
+		FileType: self.FileType,
+		Section: self.Section,
+		Offset: self.Index,
+		Msg: details,
+	}
+}
+
+// Like NewError, but taking an existing error. If the error is
+// already a *ParseError, it's returned as is.
+func (self *ParsingBuffer) WrapError(err error) error {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) { return err }
+	return self.NewError(err.Error())
 }
 
 func (self *ParsingBuffer) InitBuffers() {
+	self.InitBuffersWith(nil)
+}
+
+// Like InitBuffers, but reusing the given buffer for the parsed
+// bytes if it's not nil.
+func (self *ParsingBuffer) InitBuffersWith(buffer []byte) {
 	self.TempBuff = make([]byte, 1024)
-	self.Bytes    = make([]byte, 0, 1024)
+	if buffer == nil {
+		self.Bytes = make([]byte, 0, 1024)
+	} else {
+		self.Bytes = buffer[ : 0]
+	}
 	self.Index = 0
+	self.Section = ""
 	self.eof = false
 }
 
 func (self *ParsingBuffer) InitGzipReader(reader io.Reader) error {
 	var err error
-	self.gzipReader, err = gzip.NewReader(reader)
+	self.reader, err = gzip.NewReader(reader)
 	return err
 }
 
+// Like InitGzipReader, but for data that's not compressed.
+func (self *ParsingBuffer) InitRawReader(reader io.Reader) {
+	self.reader = reader
+}
+
 func (self *ParsingBuffer) EnsureEOF() error {
-	if self.eof { return nil }
-	preIndex := self.Index
-	err := self.readMore()
-	if err != nil { return err }
-	if self.Index > preIndex {
-		return errors.New("file continues beyond the expected end")
+	if !self.eof {
+		err := self.readMore()
+		if err != nil { return err }
+	}
+	if len(self.Bytes) > self.Index {
+		return self.NewError("file continues beyond the expected end")
 	}
 	if !self.eof { panic("broken code") }
 	return nil
 }
 
+// Reads and drops all the remaining data until EOF, without storing it
+// in Bytes, so memory usage stays constant regardless of the data size.
+// The data size limit is still enforced.
+func (self *ParsingBuffer) DiscardAll() error {
+	size := len(self.Bytes)
+	for retries := 0; !self.eof; {
+		n, err := self.reader.Read(self.TempBuff)
+		size += n
+		if size > MaxFontDataSize {
+			return self.NewError("font data size exceeds limit")
+		}
+		if err == io.EOF {
+			self.eof = true
+		} else if err != nil {
+			return self.WrapError(err)
+		} else if n == 0 {
+			retries += 1
+			if retries == 3 { return self.NewError("repeated empty reads") }
+		} else {
+			retries = 0
+		}
+	}
+	return nil
+}
+
 // utility function called to read more data
 func (self *ParsingBuffer) readMore() error {
 	for retries := 0; retries < 3; retries++ {
 		// read and process read bytes
-		n, err := self.gzipReader.Read(self.TempBuff)
+		n, err := self.reader.Read(self.TempBuff)
 		if n > 0 {
-			self.Bytes = GrowSliceByN(self.Bytes, n)
-			if len(self.Bytes) > MaxFontDataSize {
+			if len(self.Bytes) + n > MaxFontDataSize {
 				return self.NewError("font data size exceeds limit")
 			}
-			k := copy(self.Bytes[len(self.Bytes) - n : ], self.TempBuff[ : n])
-			if k != n { panic("broken code") }
+			self.Bytes = append(self.Bytes, self.TempBuff[ : n]...) // amortized growth
 		}
 
 		// handle errors
@@ -68,7 +137,7 @@ func (self *ParsingBuffer) readMore() error {
 			self.eof = true
 			return nil
 		} else if err != nil {
-			return err
+			return self.WrapError(err)
 		}
 
 		// return if we have read something
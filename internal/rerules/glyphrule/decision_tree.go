@@ -1,5 +1,7 @@
 package glyphrule
 
+import "strings"
+
 import "github.com/tinne26/ggfnt"
 
 // TODO: it should be possible to store the lowest rule index to bound
@@ -70,3 +72,13 @@ func (self *DecisionTree) Match(glyphs []ggfnt.GlyphIndex) RuleIndex {
 	self.BreakSequence()
 	return self.BestMatch()
 }
+
+// Returns a human-readable representation of the compiled states and
+// their transitions, mostly for debugging purposes.
+func (self *DecisionTree) Dump() string {
+	var builder strings.Builder
+	for i, _ := range self.states {
+		builder.WriteString(self.states[i].debugString(StateIndex(i)))
+	}
+	return builder.String()
+}
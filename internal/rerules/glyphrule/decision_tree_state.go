@@ -1,6 +1,7 @@
 package glyphrule
 
 import "fmt"
+import "strings"
 
 import "github.com/tinne26/ggfnt"
 import "github.com/tinne26/ggfnt/internal"
@@ -233,10 +234,16 @@ func (self *State) unsafeInsertUninitializedTransition(glyphRange ggfnt.GlyphRan
 // ---- debug ----
 
 func (self *State) debugPrint(index StateIndex) {
-	fmt.Printf("[state %03d] (head %d/%d)\n", index, self.MinHeadLen, self.MaxHeadLen)
+	fmt.Print(self.debugString(index))
+}
+
+func (self *State) debugString(index StateIndex) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "[state %03d] (head %d/%d)\n", index, self.MinHeadLen, self.MaxHeadLen)
 	for i, _ := range self.Transitions {
-		fmt.Print("\t")
-		fmt.Print(self.Transitions[i].debugString(TransitionIndex(i)))
-		fmt.Print("\n")
+		builder.WriteString("\t")
+		builder.WriteString(self.Transitions[i].debugString(TransitionIndex(i)))
+		builder.WriteString("\n")
 	}
+	return builder.String()
 }
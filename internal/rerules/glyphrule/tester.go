@@ -2,6 +2,7 @@ package glyphrule
 
 import "errors"
 import "strconv"
+import "strings"
 
 import "github.com/tinne26/ggfnt"
 import "github.com/tinne26/ggfnt/internal"
@@ -28,6 +29,28 @@ func (self *Tester) Resync(font *ggfnt.Font, settingsCache *ggfnt.SettingsCache)
 	return self.recompile(font, settingsCache)
 }
 
+// Returns a human-readable representation of the compiled decision
+// trees, one per condition branch, with their states and transitions.
+// Trees that haven't been compiled since their rules changed are marked
+// as pending, see [Tester.Resync]().
+func (self *Tester) DumpDecisionTree() string {
+	var builder strings.Builder
+	for i, _ := range self.trees {
+		tree := &self.trees[i]
+		if tree.Condition == 255 {
+			builder.WriteString("[no condition]")
+		} else {
+			builder.WriteString("[condition ")
+			builder.WriteString(strconv.Itoa(int(tree.Condition)))
+			builder.WriteString("]")
+		}
+		if tree.NeedsResync { builder.WriteString(" (pending resync)") }
+		builder.WriteString("\n")
+		builder.WriteString(tree.DecisionTree.Dump())
+	}
+	return builder.String()
+}
+
 // --- rule management ---
 
 func (self *Tester) RemoveAllRules() {
@@ -116,6 +139,12 @@ func (self *Tester) Feed(glyphIndex ggfnt.GlyphIndex, fn GlyphConfirmationFunc)
 		return errors.New("unxpected control glyph index '" + strconv.Itoa(int(glyphIndex)) + "'")
 	}
 	
+	// without rules there's nothing to detect (and no accumulator capacity)
+	if len(self.trees) == 0 {
+		fn(glyphIndex)
+		return nil
+	}
+
 	// feed glyph and see if we stopped running
 	self.accumulator.Push(glyphIndex)
 	detectionRunning := self.treesFeedUnrestrictedGlyph(glyphIndex)
@@ -213,6 +242,7 @@ func (self *Tester) recompile(font *ggfnt.Font, settingsCache *ggfnt.SettingsCac
 				}
 			}
 			tree.DecisionTree.states = compiler.Finish()
+			tree.NeedsResync = false
 
 			// if no rules found, delete tree
 			if numRulesFound == 0 {
@@ -329,6 +359,9 @@ func (self *Tester) treesRestartDetection() {
 			self.trees[i].BreakSequence()
 		}
 		self.trees[i].BeginSequence()
+		if self.trees[i].ConditionIsCached && !self.trees[i].ConditionCachedValue {
+			self.trees[i].BreakSequence() // condition not satisfied, keep tree idle
+		}
 	}
 }
 
@@ -3,6 +3,7 @@ package glyphrule
 import "testing"
 
 import "slices"
+import "strings"
 
 import "github.com/tinne26/ggfnt"
 
@@ -293,3 +294,32 @@ func TestTesterSet(t *testing.T) {
 		}
 	}
 }
+
+func TestTesterDumpDecisionTree(t *testing.T) {
+	var font *ggfnt.Font
+	var settingsCache *ggfnt.SettingsCache
+	var tester Tester
+	var rule ggfnt.GlyphRewriteRule
+	rule.Data = []uint8{
+		255, // condition
+		0, 2, 0, 1, // block and output lenghts
+		3, 0, // output
+		0b0000_0000, // head control
+		0b0000_0010, // body control
+		1, 0, 2, 0, // body content
+		0b0000_0000, // tail control
+	}
+	err := tester.AddRule(rule)
+	if err != nil { t.Fatalf("unexpected AddRule() error: %s", err) }
+	dump := tester.DumpDecisionTree()
+	if !strings.HasPrefix(dump, "[no condition] (pending resync)\n") {
+		t.Fatalf("expected pending resync tree dump, got:\n%s", dump)
+	}
+
+	err = tester.Resync(font, settingsCache)
+	if err != nil { t.Fatalf("unexpected resync error: %s", err) }
+	dump = tester.DumpDecisionTree()
+	if !strings.HasPrefix(dump, "[no condition]\n[state 000]") || !strings.Contains(dump, "{match rule 0}") {
+		t.Fatalf("unexpected tree dump:\n%s", dump)
+	}
+}
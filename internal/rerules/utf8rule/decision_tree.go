@@ -1,5 +1,7 @@
 package utf8rule
 
+import "strings"
+
 // Evaluates a set of replacement rules.
 type DecisionTree struct {
 	states []State
@@ -63,3 +65,13 @@ func (self *DecisionTree) Match(runes []rune) RuleIndex {
 	self.BreakSequence()
 	return self.BestMatch()
 }
+
+// Returns a human-readable representation of the compiled states and
+// their transitions, mostly for debugging purposes.
+func (self *DecisionTree) Dump() string {
+	var builder strings.Builder
+	for i, _ := range self.states {
+		builder.WriteString(self.states[i].debugString(StateIndex(i)))
+	}
+	return builder.String()
+}
@@ -1,6 +1,7 @@
 package utf8rule
 
 import "fmt"
+import "strings"
 
 import "github.com/tinne26/ggfnt/internal"
 
@@ -232,10 +233,16 @@ func (self *State) unsafeInsertUninitializedTransition(runeRange RuneRange) (Tra
 // ---- debug ----
 
 func (self *State) debugPrint(index StateIndex) {
-	fmt.Printf("[state %03d] (head %d/%d)\n", index, self.MinHeadLen, self.MaxHeadLen)
+	fmt.Print(self.debugString(index))
+}
+
+func (self *State) debugString(index StateIndex) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "[state %03d] (head %d/%d)\n", index, self.MinHeadLen, self.MaxHeadLen)
 	for i, _ := range self.Transitions {
-		fmt.Print("\t")
-		fmt.Print(self.Transitions[i].debugString(TransitionIndex(i)))
-		fmt.Print("\n")
+		builder.WriteString("\t")
+		builder.WriteString(self.Transitions[i].debugString(TransitionIndex(i)))
+		builder.WriteString("\n")
 	}
+	return builder.String()
 }
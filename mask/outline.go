@@ -0,0 +1,32 @@
+package mask
+
+import "image"
+
+// Returns a new mask with the same contents as the given one, but with
+// an outline of the given color index around them. Empty pixels that
+// are adjacent to any non-empty pixel (including diagonals) are set to
+// the outline color. The resulting mask bounds are the source contents
+// bounds expanded by one pixel on each side, or empty if the source
+// has no contents.
+func Outline(mask *image.Alpha, outlineIndex uint8) *image.Alpha {
+	rect := ComputeRect(mask)
+	if rect.Empty() { return image.NewAlpha(image.Rectangle{}) }
+	
+	outlined := image.NewAlpha(rect.Inset(-1))
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			value := mask.Pix[mask.PixOffset(x, y)]
+			if value == 0 { continue }
+			outlined.Pix[outlined.PixOffset(x, y)] = value
+			for oy := y - 1; oy <= y + 1; oy++ {
+				for ox := x - 1; ox <= x + 1; ox++ {
+					index := outlined.PixOffset(ox, oy)
+					if outlined.Pix[index] != 0 { continue }
+					if image.Pt(ox, oy).In(rect) && mask.Pix[mask.PixOffset(ox, oy)] != 0 { continue }
+					outlined.Pix[index] = outlineIndex
+				}
+			}
+		}
+	}
+	return outlined
+}
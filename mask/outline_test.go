@@ -0,0 +1,32 @@
+package mask
+
+import "image"
+import "image/color"
+import "testing"
+
+func TestOutline(t *testing.T) {
+	mask := image.NewAlpha(image.Rect(-2, -3, 3, 1))
+	mask.SetAlpha(0, -1, color.Alpha{255})
+	mask.SetAlpha(1, -1, color.Alpha{255})
+
+	outlined := Outline(mask, 254)
+	expectedRect := image.Rect(-1, -2, 3, 1)
+	if !outlined.Rect.Eq(expectedRect) {
+		t.Fatalf("expected outlined rect %s, got %s", expectedRect, outlined.Rect)
+	}
+	for y := expectedRect.Min.Y; y < expectedRect.Max.Y; y++ {
+		for x := expectedRect.Min.X; x < expectedRect.Max.X; x++ {
+			var expected uint8 = 254
+			if y == -1 && (x == 0 || x == 1) { expected = 255 }
+			value := outlined.AlphaAt(x, y).A
+			if value != expected {
+				t.Fatalf("expected value %d at (%d, %d), got %d", expected, x, y, value)
+			}
+		}
+	}
+
+	outlined = Outline(image.NewAlpha(image.Rect(0, 0, 2, 2)), 254)
+	if !outlined.Rect.Empty() {
+		t.Fatalf("expected empty outline for empty mask, got %s", outlined.Rect)
+	}
+}
@@ -27,13 +27,31 @@ var ErrDiagonalSinglePixDraw = errors.New("raster operation can't use single pix
 // Given a set of raster operations in binary format, returns
 // the corresponding glyph mask. Empty masks return nil.
 func Rasterize(rasterOps []byte) (*image.Alpha, error) {
+	return RasterizeInto(nil, rasterOps)
+}
+
+// Like [Rasterize](), but reusing the given buffer's pixel data
+// if it has enough capacity. The buffer can be nil. If the buffer
+// is reused, the returned mask will share its memory, so the buffer
+// must not be used independently afterwards.
+func RasterizeInto(buffer *image.Alpha, rasterOps []byte) (*image.Alpha, error) {
 	// obtain mask bounds
 	rect, err := computeRasterOpsRect(rasterOps)
 	if err != nil { return nil, err }
 	if rect.Empty() { return nil, nil }
 	
-	// create mask
-	mask := image.NewAlpha(rect)
+	// create or reuse mask
+	var mask *image.Alpha
+	numPixels := rect.Dx()*rect.Dy()
+	if buffer != nil && cap(buffer.Pix) >= numPixels {
+		mask = buffer
+		mask.Pix = mask.Pix[ : numPixels]
+		clear(mask.Pix)
+		mask.Stride = rect.Dx()
+		mask.Rect = rect
+	} else {
+		mask = image.NewAlpha(rect)
+	}
 
 	var index int = 0
 	var paletteIndex uint8 = 255
@@ -140,6 +158,16 @@ func Rasterize(rasterOps []byte) (*image.Alpha, error) {
 	panic("unreachable")
 }
 
+// Returns the bounds of the mask described by the given raster
+// operations, without rasterizing it. Empty masks return an empty
+// rectangle.
+func RasterOpsRect(rasterOps []byte) (image.Rectangle, error) {
+	rect, err := computeRasterOpsRect(rasterOps)
+	if err != nil { return image.Rectangle{}, err }
+	if rect.Empty() { return image.Rectangle{}, nil }
+	return rect, nil
+}
+
 // You should generally check if the rect is empty afterwards.
 // It can be in many cases.
 func computeRasterOpsRect(rasterOps []byte) (image.Rectangle, error) {
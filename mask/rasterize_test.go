@@ -89,3 +89,38 @@ func TestRngRasterize(t *testing.T) {
 		}
 	}
 }
+
+func TestRasterizeInto(t *testing.T) {
+	var encoder Encoder
+	var buffer *image.Alpha
+	rasterOps := make([]uint8, 0, 256)
+
+	// alternate big and small masks to exercise both reuse and growth
+	for i := 0; i < 64; i++ {
+		size := 2 + (i % 3)*2
+		img := image.NewAlpha(image.Rect(-size, -size, size, size))
+		for j := 0; j < len(img.Pix); j++ {
+			if rand.Float64() < 0.3 { img.Pix[j] = uint8(254 + rand.Intn(2)) }
+		}
+
+		rasterOps = encoder.AppendRasterOps(rasterOps[ : 0], img)
+		expected, err := Rasterize(rasterOps)
+		if err != nil { t.Fatal(err) }
+		mask, err := RasterizeInto(buffer, rasterOps)
+		if err != nil { t.Fatal(err) }
+		rect, err := RasterOpsRect(rasterOps)
+		if err != nil { t.Fatal(err) }
+		if (expected == nil && !rect.Empty()) || (expected != nil && !rect.Eq(expected.Rect)) {
+			t.Fatalf("expected mask bounds to match RasterOpsRect(), found %v", rect)
+		}
+		if expected == nil || mask == nil {
+			if expected != mask { t.Fatalf("expected mask: %v\nfound mask: %v\n", expected, mask) }
+			continue
+		}
+		
+		if !mask.Rect.Eq(expected.Rect) || mask.Stride != expected.Stride || string(mask.Pix) != string(expected.Pix) {
+			t.Fatalf("expected mask: %v\nfound mask: %v\n", expected, mask)
+		}
+		buffer = mask
+	}
+}
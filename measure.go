@@ -0,0 +1,33 @@
+package ggfnt
+
+// Returns the width of the widest line and the number of lines that
+// the given text would take when drawn with the font. Advances, kerning,
+// horizontal interspacing and '\n' line breaks are all considered, while
+// code points without a glyph mapping are skipped. Rewrite rules are not
+// applied. Groups of glyphs are measured with their first glyph.
+//
+// For monospaced fonts, [FontMetrics.MonoWidth]() is used as the advance
+// of every glyph and kerning is ignored.
+func (self *Font) MeasureText(text string, settings *SettingsCache) (width int, lines int) {
+	var x int
+	var numLines int = 1
+	var prevGlyph GlyphIndex = GlyphMissing
+	for _, codePoint := range text {
+		if codePoint == '\n' {
+			if prevGlyph != GlyphMissing { x += self.GlyphAdvance(prevGlyph) }
+			width = max(width, x)
+			x = 0
+			numLines += 1
+			prevGlyph = GlyphMissing
+			continue
+		}
+
+		group, found := self.Mapping().Utf8WithCache(codePoint, settings)
+		if !found { continue }
+		glyphIndex := group.Select(0)
+		if prevGlyph != GlyphMissing { x += self.PairAdvance(prevGlyph, glyphIndex) }
+		prevGlyph = glyphIndex
+	}
+	if prevGlyph != GlyphMissing { x += self.GlyphAdvance(prevGlyph) }
+	return max(width, x), numLines
+}
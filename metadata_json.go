@@ -0,0 +1,128 @@
+package ggfnt
+
+import "fmt"
+import "strings"
+import "encoding/json"
+
+// JSON schema for [Font.MetadataJSON](). Field names are part of
+// the stable schema, so they must not be changed.
+type jsonMetadata struct {
+	ID string `json:"id"`
+	Name string `json:"name"`
+	Family string `json:"family"`
+	Author string `json:"author"`
+	About string `json:"about"`
+	Version string `json:"version"`
+	VersionMajor uint16 `json:"versionMajor"`
+	VersionMinor uint16 `json:"versionMinor"`
+	FirstVersionDate string `json:"firstVersionDate"`
+	MajorVersionDate string `json:"majorVersionDate"`
+	MinorVersionDate string `json:"minorVersionDate"`
+	Metrics jsonMetrics `json:"metrics"`
+	NumGlyphs uint16 `json:"numGlyphs"`
+	Dyes []string `json:"dyes"`
+	Palettes []string `json:"palettes"`
+	Settings []jsonSetting `json:"settings"`
+	Coverage [][2]rune `json:"coverage"`
+}
+
+type jsonMetrics struct {
+	HasVertLayout bool `json:"hasVertLayout"`
+	MonoWidth uint8 `json:"monoWidth"`
+	Ascent uint8 `json:"ascent"`
+	ExtraAscent uint8 `json:"extraAscent"`
+	Descent uint8 `json:"descent"`
+	ExtraDescent uint8 `json:"extraDescent"`
+	UppercaseAscent uint8 `json:"uppercaseAscent"`
+	MidlineAscent uint8 `json:"midlineAscent"`
+	HorzInterspacing uint8 `json:"horzInterspacing"`
+	VertInterspacing uint8 `json:"vertInterspacing"`
+	LineGap uint8 `json:"lineGap"`
+	VertLineWidth uint8 `json:"vertLineWidth"`
+	VertLineGap uint8 `json:"vertLineGap"`
+}
+
+type jsonSetting struct {
+	Name string `json:"name"`
+	Options []string `json:"options"`
+}
+
+// Returns the font metadata as a JSON document: header fields, metrics,
+// number of glyphs, color section names, settings and their options, and
+// the mapped code point coverage as a list of inclusive [first, last] ranges.
+//
+// Dates are formatted as "YYYY-MM-DD", with zeros for missing parts.
+func (self *Font) MetadataJSON() ([]byte, error) {
+	metadata := self.jsonMetadata()
+	return json.Marshal(&metadata)
+}
+
+// Collects the metadata exported by [Font.MetadataJSON]() and
+// [Font.ExportJSON]().
+func (self *Font) jsonMetadata() jsonMetadata {
+	header  := self.Header()
+	metrics := self.Metrics()
+	metadata := jsonMetadata{
+		ID: header.IDString(),
+		Name: strings.Clone(header.Name()),
+		Family: strings.Clone(header.Family()),
+		Author: strings.Clone(header.Author()),
+		About: strings.Clone(header.About()),
+		Version: fmt.Sprintf("v%d.%02d", header.VersionMajor(), header.VersionMinor()),
+		VersionMajor: header.VersionMajor(),
+		VersionMinor: header.VersionMinor(),
+		FirstVersionDate: jsonDate(header.FirstVersionDate()),
+		MajorVersionDate: jsonDate(header.MajorVersionDate()),
+		MinorVersionDate: jsonDate(header.MinorVersionDate()),
+		Metrics: jsonMetrics{
+			HasVertLayout: metrics.HasVertLayout(),
+			MonoWidth: metrics.MonoWidth(),
+			Ascent: metrics.Ascent(),
+			ExtraAscent: metrics.ExtraAscent(),
+			Descent: metrics.Descent(),
+			ExtraDescent: metrics.ExtraDescent(),
+			UppercaseAscent: metrics.UppercaseAscent(),
+			MidlineAscent: metrics.MidlineAscent(),
+			HorzInterspacing: metrics.HorzInterspacing(),
+			VertInterspacing: metrics.VertInterspacing(),
+			LineGap: metrics.LineGap(),
+			VertLineWidth: metrics.VertLineWidth(),
+			VertLineGap: metrics.VertLineGap(),
+		},
+		NumGlyphs: metrics.NumGlyphs(),
+		Dyes: []string{},
+		Palettes: []string{},
+		Settings: []jsonSetting{},
+		Coverage: [][2]rune{},
+	}
+
+	// color sections
+	self.Color().EachDye(func(_ DyeKey, name string) {
+		metadata.Dyes = append(metadata.Dyes, strings.Clone(name))
+	})
+	self.Color().EachPalette(func(_ PaletteKey, name string) {
+		metadata.Palettes = append(metadata.Palettes, strings.Clone(name))
+	})
+
+	// settings
+	settings := self.Settings()
+	settings.Each(func(key SettingKey, name string) {
+		numOptions := settings.GetNumOptions(key)
+		setting := jsonSetting{ Name: strings.Clone(name), Options: make([]string, 0, numOptions) }
+		for i := uint8(0); i < numOptions; i++ {
+			setting.Options = append(setting.Options, strings.Clone(settings.GetOptionName(key, i)))
+		}
+		metadata.Settings = append(metadata.Settings, setting)
+	})
+
+	// coverage ranges
+	for _, runeRange := range self.Mapping().CoverageRanges() {
+		metadata.Coverage = append(metadata.Coverage, [2]rune{runeRange.First, runeRange.Last})
+	}
+
+	return metadata
+}
+
+func jsonDate(date Date) string {
+	return date.ISOString()
+}
@@ -0,0 +1,63 @@
+package ggfnt_test
+
+import "testing"
+import "encoding/json"
+
+import "github.com/tinne26/ggfnt"
+import "github.com/tinne26/ggfnt/builder"
+
+func TestMetadataJSON(t *testing.T) {
+	fontBuilder := builder.New()
+	_ = fontBuilder.SetName("Test Font")
+	_ = fontBuilder.SetAuthor("Tester")
+	_ = fontBuilder.SetFirstVerDate(ggfnt.Date{ Year: 2024, Month: 2, Day: 29 })
+	for _, codePoint := range "abcxz" {
+		glyphUID, err := fontBuilder.AddBlankGlyph(3)
+		if err != nil { t.Fatalf("unexpected AddBlankGlyph() error: %s", err) }
+		err = fontBuilder.Map(codePoint, glyphUID)
+		if err != nil { t.Fatalf("unexpected Map() error: %s", err) }
+	}
+	_, err := fontBuilder.AddSetting("style", "regular", "bold")
+	if err != nil { t.Fatalf("unexpected AddSetting() error: %s", err) }
+	font, err := fontBuilder.Build()
+	if err != nil { t.Fatalf("unexpected Build() error: %s", err) }
+
+	data, err := font.MetadataJSON()
+	if err != nil { t.Fatalf("unexpected MetadataJSON() error: %s", err) }
+	
+	var metadata map[string]any
+	err = json.Unmarshal(data, &metadata)
+	if err != nil { t.Fatalf("MetadataJSON() produced invalid JSON: %s", err) }
+
+	// check the stable schema keys
+	keys := []string{
+		"id", "name", "family", "author", "about", "version", "versionMajor", "versionMinor",
+		"firstVersionDate", "majorVersionDate", "minorVersionDate", "metrics", "numGlyphs",
+		"dyes", "palettes", "settings", "coverage",
+	}
+	for _, key := range keys {
+		if _, found := metadata[key]; !found {
+			t.Fatalf("expected key '%s' in metadata JSON:\n%s", key, data)
+		}
+	}
+
+	// check some values
+	if metadata["name"] != "Test Font" || metadata["author"] != "Tester" {
+		t.Fatalf("unexpected name or author in metadata JSON:\n%s", data)
+	}
+	if metadata["firstVersionDate"] != "2024-02-29" {
+		t.Fatalf("expected firstVersionDate '2024-02-29', got '%v'", metadata["firstVersionDate"])
+	}
+	if metadata["numGlyphs"] != float64(5) {
+		t.Fatalf("expected numGlyphs 5, got '%v'", metadata["numGlyphs"])
+	}
+	
+	coverage, _ := json.Marshal(metadata["coverage"])
+	if string(coverage) != "[[97,99],[120,120],[122,122]]" {
+		t.Fatalf("unexpected coverage ranges %s", coverage)
+	}
+	settings, _ := json.Marshal(metadata["settings"])
+	if string(settings) != `[{"name":"style","options":["regular","bold"]}]` {
+		t.Fatalf("unexpected settings %s", settings)
+	}
+}
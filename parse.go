@@ -11,6 +11,13 @@ import "github.com/tinne26/ggfnt/internal"
 
 const traceParsing = false
 
+// Error type returned by [Parse]() and similar functions when the
+// font data is invalid. Offset is the byte index within the parsed
+// (uncompressed) data where the error was detected, and Section
+// is the name of the font section being parsed at that point
+// ("header", "metrics", "glyphs", etc.). Use errors.As to access it.
+type ParseError = internal.ParseError
+
 // Utility method for parsing from a fs.FS, like when using embed.
 func ParseFromFS(filesys fs.FS, filename string) (*Font, error) {
 	file, err := filesys.Open(filename)
@@ -43,56 +50,124 @@ func ParseFromPath(path string) (*Font, error) {
 	}
 }
 
-func Parse(reader io.Reader) (*Font, error) {
+// Checks the integrity of a font file without constructing the full
+// font: the signature and header are validated, and the rest of the
+// gzipped data is decompressed until the end, which also verifies
+// the gzip checksum. Sections other than the header are not validated,
+// so a nil error doesn't guarantee that [Parse]() will succeed.
+//
+// The decompression cost is still linear in the file size, but the
+// data after the header is discarded as it's read instead of being
+// kept in memory.
+func VerifyFile(reader io.Reader) error {
 	var font Font
 	var parser internal.ParsingBuffer
 	parser.InitBuffers()
 	parser.FileType = "ggfnt"
 
-	if traceParsing { fmt.Printf("starting parsing...\n") }
+	err := parseSignatureAndHeader(reader, &parser, &font)
+	if err != nil { return err }
+	err = parser.DiscardAll()
+	if err != nil { return parser.WrapError(err) }
+	return nil
+}
 
+func parseSignatureAndHeader(reader io.Reader, parser *internal.ParsingBuffer, font *Font) error {
+	return parseSignatureAndHeaderWith(reader, parser, font, false)
+}
+
+// Same as parseSignatureAndHeader, but raw can be set to expect the
+// raw signature and uncompressed data instead.
+func parseSignatureAndHeaderWith(reader io.Reader, parser *internal.ParsingBuffer, font *Font, raw bool) error {
 	// read signature first (this is not gzipped, so it's important)
+	parser.Section = "signature"
 	n, err := reader.Read(parser.TempBuff[0 : 6])
 	if err != nil || n != 6 {
 		if n == 0 {
-			return &font, parser.NewError("failed to read any data from the file")
+			return parser.NewError("failed to read any data from the file")
 		}
-		return &font, parser.NewError("failed to read file signature")
+		return parser.NewError("failed to read file signature")
 	}
-	if !slices.Equal(parser.TempBuff[0 : 6], []byte{'t', 'g', 'g', 'f', 'n', 't'}) {
-		return &font, parser.NewError("invalid signature")
+	if raw {
+		if !slices.Equal(parser.TempBuff[0 : 6], []byte{'r', 'g', 'g', 'f', 'n', 't'}) {
+			return parser.NewError("invalid raw signature")
+		}
+		parser.InitRawReader(reader)
+	} else {
+		if !slices.Equal(parser.TempBuff[0 : 6], []byte{'t', 'g', 'g', 'f', 'n', 't'}) {
+			return parser.NewError("invalid signature")
+		}
+		err = parser.InitGzipReader(reader)
+		if err != nil { return parser.WrapError(err) }
 	}
 
-	err = parser.InitGzipReader(reader)
-	if err != nil { return &font, parser.NewError(err.Error()) }
-
 	// --- header ---
 	if traceParsing { fmt.Printf("parsing header...\n") }
+	parser.Section = "header"
 	err = parser.AdvanceBytes(28)
-	if err != nil { return &font, err }
+	if err != nil { return err }
 	for i := 0; i < 3; i++ {
 		_, err = parser.ReadShortStr()
-		if err != nil { return &font, err }
+		if err != nil { return err }
 	}
 	_, err = parser.ReadString()
-	if err != nil { return &font, err }
+	if err != nil { return err }
 	
 	font.Data = parser.Bytes // initial assignation (required before validation)
 	err = font.Header().Validate(FmtDefault)
-	if err != nil { return &font, parser.NewError(err.Error()) }
+	if err != nil { return parser.WrapError(err) }
+	return nil
+}
+
+func Parse(reader io.Reader) (*Font, error) {
+	return parse(reader, false, nil)
+}
+
+// Like [Parse](), but for uncompressed font data exported with
+// [Font.ExportRaw]().
+func ParseRaw(reader io.Reader) (*Font, error) {
+	return parse(reader, true, nil)
+}
+
+// Like [Parse](), but decompressing the font data into the given buffer
+// in order to reuse allocations when loading many fonts. The contents of
+// the buffer are overwritten, and only its capacity matters.
+//
+// The returned font takes ownership of the buffer: if the capacity was
+// enough, the font data aliases it, so the buffer can't be reused until
+// the font is no longer needed. Otherwise, a bigger buffer is allocated
+// and the given one is left unused. In either case, once the font is
+// no longer needed, its Data field is the buffer to reuse.
+func ParseInto(reader io.Reader, buffer []byte) (*Font, error) {
+	return parse(reader, false, buffer)
+}
+
+func parse(reader io.Reader, raw bool, buffer []byte) (*Font, error) {
+	var font Font
+	var parser internal.ParsingBuffer
+	parser.InitBuffersWith(buffer)
+	parser.FileType = "ggfnt"
+
+	if traceParsing { fmt.Printf("starting parsing...\n") }
+
+	// read signature and header
+	err := parseSignatureAndHeaderWith(reader, &parser, &font, raw)
+	if err != nil { return &font, err }
 
 	// --- metrics ---
 	if traceParsing { fmt.Printf("parsing metrics... (index = %d)\n", parser.Index) }
+	parser.Section = "metrics"
 	font.OffsetToMetrics = uint32(parser.Index)
 	err = parser.AdvanceBytes(15)
 	if err != nil { return &font, err }
 
 	font.Data = parser.Bytes // possible slice reallocs
 	err = font.Metrics().Validate(FmtDefault)
-	if err != nil { return &font, parser.NewError(err.Error()) }
+	if err != nil { return &font, parser.WrapError(err) }
 
 	// --- color sections ---
 	if traceParsing { fmt.Printf("parsing dyes... (index = %d)\n", parser.Index) }
+	parser.Section = "dyes"
 	font.OffsetToDyes = uint32(parser.Index)
 	numDyes, err := parser.ReadUint8() // NumDyes
 	if err != nil { return &font, err }
@@ -122,6 +197,7 @@ func Parse(reader io.Reader) (*Font, error) {
 	}
 
 	if traceParsing { fmt.Printf("parsing palettes... (index = %d)\n", parser.Index) }
+	parser.Section = "palettes"
 	font.OffsetToPalettes = uint32(parser.Index)
 	numPalettes, err := parser.ReadUint8() // NumPalettes
 	if err != nil { return &font, err }
@@ -153,10 +229,11 @@ func Parse(reader io.Reader) (*Font, error) {
 	
 	font.Data = parser.Bytes // possible slice reallocs
 	err = font.Color().Validate(FmtDefault)
-	if err != nil { return &font, parser.NewError(err.Error()) }	
+	if err != nil { return &font, parser.WrapError(err) }	
 
 	// --- glyphs ---
 	if traceParsing { fmt.Printf("parsing glyphs... (index = %d)\n", parser.Index) }
+	parser.Section = "glyphs"
 	font.OffsetToGlyphNames = uint32(parser.Index)
 	numNamedGlyphs, err := parser.ReadUint16()
 	if err != nil { return &font, err }
@@ -205,10 +282,11 @@ func Parse(reader io.Reader) (*Font, error) {
 	// (glyphs validation)
 	font.Data = parser.Bytes // possible slice reallocs
 	err = font.Glyphs().Validate(FmtDefault)
-	if err != nil { return &font, parser.NewError(err.Error()) }
+	if err != nil { return &font, parser.WrapError(err) }
 
 	// --- settings ---
 	if traceParsing { fmt.Printf("parsing settings... (index = %d)\n", parser.Index) }
+	parser.Section = "settings"
 	font.OffsetToWords = uint32(parser.Index)
 	numWords, err := parser.ReadUint8()
 	if err != nil { return &font, err }
@@ -249,6 +327,10 @@ func Parse(reader io.Reader) (*Font, error) {
 		err = parser.AdvanceBytes(int(settingNamesLen))
 		if err != nil { return &font, err }
 
+		// advance SettingInitValues (validated later)
+		err = parser.AdvanceBytes(int(numSettings))
+		if err != nil { return &font, err }
+
 		// advance SettingEndOffsets
 		font.OffsetToSettingDefinitions = uint32(parser.Index)
 		err = parser.AdvanceBytes(int(numSettings - 1)*2)
@@ -264,10 +346,11 @@ func Parse(reader io.Reader) (*Font, error) {
 
 	font.Data = parser.Bytes // possible slice reallocs
 	err = font.Settings().Validate(FmtDefault)
-	if err != nil { return &font, parser.NewError(err.Error()) }
+	if err != nil { return &font, parser.WrapError(err) }
 
 	// --- mappings ---
 	if traceParsing { fmt.Printf("parsing mappings... (index = %d)\n", parser.Index) }
+	parser.Section = "mappings"
 	
 	// mapping switches
 	font.OffsetToMappingSwitches = uint32(parser.Index)
@@ -318,10 +401,11 @@ func Parse(reader io.Reader) (*Font, error) {
 
 	font.Data = parser.Bytes // possible slice reallocs
 	err = font.Mapping().Validate(FmtDefault)
-	if err != nil { return &font, parser.NewError(err.Error()) }
+	if err != nil { return &font, parser.WrapError(err) }
 
 	// --- rewrite rules ---
 	if traceParsing { fmt.Printf("parsing rewrite rules... (index = %d)\n", parser.Index) }
+	parser.Section = "rewrite rules"
 	font.OffsetToRewriteConditions = uint32(parser.Index)
 	numConditions, err := parser.ReadUint8()
 	if err != nil { return &font, err }
@@ -418,14 +502,16 @@ func Parse(reader io.Reader) (*Font, error) {
 
 	font.Data = parser.Bytes // possible slice reallocs
 	err = font.Rewrites().Validate(FmtDefault)
-	if err != nil { return &font, parser.NewError(err.Error()) }
+	if err != nil { return &font, parser.WrapError(err) }
 
 	// --- kerning ---
 	if traceParsing { fmt.Printf("parsing kernings... (index = %d)\n", parser.Index) }
+	parser.Section = "kerning"
 	font.OffsetToHorzKernings = uint32(parser.Index)
 	maxKerningPairs := uint32(numGlyphs)*uint32(numGlyphs)
 
 	numHorzKerningPairs, err := parser.ReadUint24()
+	if err != nil { return &font, err }
 	if numHorzKerningPairs > 0 {
 		if numHorzKerningPairs > maxKerningPairs {
 			return &font, parser.NewError("NumHorzKerningPairs can't exceed NumGlyphs^2")
@@ -438,6 +524,7 @@ func Parse(reader io.Reader) (*Font, error) {
 
 	font.OffsetToVertKernings = uint32(parser.Index)
 	numVertKerningPairs, err := parser.ReadUint24()
+	if err != nil { return &font, err }
 	if numVertKerningPairs > 0 {
 		if numVertKerningPairs > maxKerningPairs {
 			return &font, parser.NewError("NumVertKerningPairs can't exceed NumGlyphs^2")
@@ -450,13 +537,14 @@ func Parse(reader io.Reader) (*Font, error) {
 
 	font.Data = parser.Bytes // possible slice reallocs
 	err = font.Kerning().Validate(FmtDefault)
-	if err != nil { return &font, parser.NewError(err.Error()) }
+	if err != nil { return &font, parser.WrapError(err) }
 
 	// --- EOF ---
 	if traceParsing { fmt.Printf("testing EOF... (index = %d)\n", parser.Index) }
+	parser.Section = "EOF"
 	// ensure we reach EOF exactly at the right time
 	err = parser.EnsureEOF()
-	if err != nil { return &font, parser.NewError(err.Error()) }
+	if err != nil { return &font, parser.WrapError(err) }
 
 	// everything went well
 	if traceParsing { fmt.Printf("parsing correct!\n") }
@@ -0,0 +1,54 @@
+package ggfnt
+
+import "math"
+
+// A pen with a fractional position, for renderers that need to
+// advance text by non-integer amounts (scrolling marquees, smooth
+// animations, etc.) while still blitting glyphs at integer pixels.
+//
+// The position is always accumulated at full precision and only
+// snapped when queried, so long runs of text don't drift due to
+// accumulated rounding errors.
+type FractionalPen struct {
+	x float64
+	y float64
+}
+
+// Creates a new pen at the given position.
+func NewFractionalPen(x, y float64) FractionalPen {
+	return FractionalPen{ x: x, y: y }
+}
+
+// Returns the unsnapped pen position.
+func (self *FractionalPen) Position() (float64, float64) {
+	return self.x, self.y
+}
+
+// Sets the unsnapped pen position.
+func (self *FractionalPen) SetPosition(x, y float64) {
+	self.x, self.y = x, y
+}
+
+// Returns the pen position snapped to integer pixels. Coordinates
+// are floored, so moving the pen smoothly never makes glyphs jitter
+// back and forth between two pixel positions.
+func (self *FractionalPen) Snapped() (int, int) {
+	return int(math.Floor(self.x)), int(math.Floor(self.y))
+}
+
+// Advances the pen horizontally by the given amount.
+func (self *FractionalPen) Advance(dx float64) {
+	self.x += dx
+}
+
+// Advances the pen vertically by the given amount.
+func (self *FractionalPen) AdvanceVert(dy float64) {
+	self.y += dy
+}
+
+// Advances the pen horizontally from the origin of prev to the origin of
+// curr, as given by [Font.PairAdvance](), scaled by the given factor. Use
+// a scale of 1 for regular integer advances.
+func (self *FractionalPen) AdvancePair(font *Font, prev, curr GlyphIndex, scale float64) {
+	self.x += float64(font.PairAdvance(prev, curr))*scale
+}
@@ -0,0 +1,21 @@
+package ggfnt
+
+import "testing"
+
+func TestFractionalPenDrift(t *testing.T) {
+	pen := NewFractionalPen(0, 0)
+	for i := 0; i < 1000; i++ {
+		pen.Advance(0.25)
+	}
+	x, y := pen.Snapped()
+	if x != 250 {
+		t.Fatalf("expected snapped x 250 after 1000 advances of 0.25, got %d", x)
+	}
+	if y != 0 { t.Fatalf("expected snapped y 0, got %d", y) }
+
+	pen.SetPosition(-0.5, 2.75)
+	x, y = pen.Snapped()
+	if x != -1 || y != 2 {
+		t.Fatalf("expected snapped position (-1, 2), got (%d, %d)", x, y)
+	}
+}
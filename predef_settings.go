@@ -0,0 +1,42 @@
+package ggfnt
+
+// Predefined settings are conventional setting templates that
+// fonts can use in order to make common features discoverable
+// by renderers through standard setting and option names.
+//
+// See [PredefinedSetting.Name]() and [PredefinedSetting.Options]().
+type PredefinedSetting uint8
+const (
+	PredefStyleRegularBold PredefinedSetting = iota // "style": "regular", "bold"
+	PredefSmallCaps // "small-caps": "off", "on"
+	PredefSlashedZero // "slashed-zero": "off", "on"
+	PredefNumericStyle // "numeric-style": "lining", "oldstyle"
+	PredefAnimations // "animations": "on", "off"
+	PredefLigatures // "ligatures": "on", "off"
+
+	predefSettingsCount // keep this last
+)
+
+var predefSettings = [predefSettingsCount]struct{ name string; options []string }{
+	{ "style", []string{"regular", "bold"} },
+	{ "small-caps", []string{"off", "on"} },
+	{ "slashed-zero", []string{"off", "on"} },
+	{ "numeric-style", []string{"lining", "oldstyle"} },
+	{ "animations", []string{"on", "off"} },
+	{ "ligatures", []string{"on", "off"} },
+}
+
+// Returns the conventional setting name.
+func (self PredefinedSetting) Name() string {
+	if self >= predefSettingsCount { panic("invalid predefined setting") }
+	return predefSettings[self].name
+}
+
+// Returns a copy of the conventional option names, in order. The
+// first option is always the default.
+func (self PredefinedSetting) Options() []string {
+	if self >= predefSettingsCount { panic("invalid predefined setting") }
+	options := make([]string, len(predefSettings[self].options))
+	copy(options, predefSettings[self].options)
+	return options
+}
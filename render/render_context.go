@@ -0,0 +1,208 @@
+package render
+
+import "errors"
+import "image"
+import "image/color"
+import "image/draw"
+
+import "github.com/tinne26/ggfnt"
+import "github.com/tinne26/ggfnt/internal"
+import "github.com/tinne26/ggfnt/rerules"
+
+// Options for [RenderContext.DrawWithOptions]().
+type DrawOptions struct {
+	// If not nil, the text is drawn starting at the pen position instead
+	// of the given point. Glyph origins are snapped to integer pixels as
+	// in [ggfnt.FractionalPen.Snapped](), while advances are accumulated
+	// at full precision, so long runs of text don't drift. The pen is
+	// left after the last glyph advance, ready to continue drawing.
+	Pen *ggfnt.FractionalPen
+
+	// The scale applied to the glyph advances. If zero, 1 is used.
+	AdvanceScale float64
+}
+
+// A render context bundles the stateful machinery needed to measure
+// and draw text with a font: a [ggfnt.SettingsCache], a [rerules.Shaper]
+// with all the font's rewrite rules, and scratch buffers reused between
+// calls.
+//
+// Render contexts are not safe for concurrent use.
+type RenderContext struct {
+	font *ggfnt.Font
+	settings *ggfnt.SettingsCache
+	shaper *rerules.Shaper
+
+	glyphs []ggfnt.GlyphIndex
+	lineBreaks []int // glyph positions at which each '\n' appears
+	maskBuffer image.Alpha
+	coverageMask image.Alpha
+	source image.Uniform
+}
+
+// Creates a new render context for the given font, with all its
+// settings initialized to their init values. Returns an error if
+// the font rewrite rules can't be loaded into the shaper.
+func NewRenderContext(font *ggfnt.Font) (*RenderContext, error) {
+	if font == nil { panic("render context can't accept nil font") }
+	shaper, err := rerules.NewShaper(font)
+	if err != nil { return nil, err }
+	context := &RenderContext{
+		font: font,
+		settings: ggfnt.NewSettingsCache(font),
+		shaper: shaper,
+	}
+	shaper.SetControlFunc(context.registerControl)
+	return context, nil
+}
+
+// Returns the font the render context was created for.
+func (self *RenderContext) Font() *ggfnt.Font {
+	return self.font
+}
+
+// Returns the underlying settings cache.
+func (self *RenderContext) SettingsCache() *ggfnt.SettingsCache {
+	return self.settings
+}
+
+// Returns the underlying shaper. Its control function is used by the
+// render context to detect line breaks, so it must not be replaced.
+func (self *RenderContext) Shaper() *rerules.Shaper {
+	return self.shaper
+}
+
+// Sets the setting with the given name to the given option. Returns
+// an error if the setting doesn't exist or the option is out of range.
+func (self *RenderContext) SetSetting(name string, option uint8) error {
+	key, found := self.findSettingKey(name)
+	if !found { return errors.New("setting '" + name + "' not found") }
+	if option >= self.font.Settings().GetNumOptions(key) {
+		return errors.New("option out of range for setting '" + name + "'")
+	}
+	self.settings.Set(key, option)
+	return nil
+}
+
+func (self *RenderContext) findSettingKey(name string) (ggfnt.SettingKey, bool) {
+	var key ggfnt.SettingKey
+	var found bool
+	self.font.Settings().Each(func(settingKey ggfnt.SettingKey, settingName string) {
+		if found || settingName != name { return }
+		key, found = settingKey, true
+	})
+	return key, found
+}
+
+// Returns the size of the given text when drawn with [RenderContext.Draw]().
+// The width is the widest line advance, and the height goes from the
+// ascent of the first line to the descent of the last one, with lines
+// separated by [ggfnt.FontMetrics.LineHeight](). Returns an error if the
+// text can't be shaped, e.g. if it contains code points not mapped by
+// the font.
+func (self *RenderContext) Measure(text string) (width, height int, err error) {
+	pen := ggfnt.NewFractionalPen(0, 0)
+	lineWidth, numLines, err := self.layout(text, &pen, 1, nil)
+	if err != nil { return 0, 0, err }
+	metrics := self.font.Metrics()
+	height = int(metrics.Ascent()) + int(metrics.Descent()) + (numLines - 1)*metrics.LineHeight()
+	return int(lineWidth), height, nil
+}
+
+// Draws the given text into dst, with the first line's baseline
+// origin at the given point. Line breaks are supported through '\n',
+// and the font rewrite rules are applied before mapping. Returns an
+// error if the text can't be shaped.
+//
+// The palette maps glyph mask color indices to RGBA colors, as
+// described in [ggfnt.NewPaletteResolver](). Glyphs are composited
+// over the existing dst contents.
+func (self *RenderContext) Draw(dst draw.Image, at image.Point, palette []color.RGBA, text string) error {
+	return self.DrawWithOptions(dst, at, palette, text, DrawOptions{})
+}
+
+// Like [RenderContext.Draw](), but with additional options, like
+// fractional pen positioning.
+func (self *RenderContext) DrawWithOptions(dst draw.Image, at image.Point, palette []color.RGBA, text string, opts DrawOptions) error {
+	pen := opts.Pen
+	if pen == nil {
+		atPen := ggfnt.NewFractionalPen(float64(at.X), float64(at.Y))
+		pen = &atPen
+	}
+	scale := opts.AdvanceScale
+	if scale == 0 { scale = 1 }
+
+	resolver := ggfnt.NewPaletteResolver(palette)
+	_, _, err := self.layout(text, pen, scale, func(glyphIndex ggfnt.GlyphIndex, x, y int) {
+		self.drawGlyph(dst, x, y, glyphIndex, resolver)
+	})
+	return err
+}
+
+// Composites the given glyph over dst, with its origin at (x, y). Each
+// color index is drawn with its own coverage mask, so most glyphs take
+// a single draw.DrawMask() call.
+func (self *RenderContext) drawGlyph(dst draw.Image, x, y int, glyphIndex ggfnt.GlyphIndex, resolver ggfnt.ColorResolver) {
+	err := self.font.Glyphs().RasterizeMaskInto(glyphIndex, &self.maskBuffer)
+	if err != nil { panic(err) }
+	glyphMask := &self.maskBuffer
+	if glyphMask.Rect.Empty() { return }
+
+	self.coverageMask.Rect, self.coverageMask.Stride = glyphMask.Rect, glyphMask.Stride
+	self.coverageMask.Pix = internal.SetSliceSize(self.coverageMask.Pix, len(glyphMask.Pix))
+	target := glyphMask.Rect.Add(image.Pt(x, y))
+	var drawn [256]bool
+	for _, index := range glyphMask.Pix {
+		if index == 0 || drawn[index] { continue }
+		drawn[index] = true
+		for i, value := range glyphMask.Pix {
+			if value == index { self.coverageMask.Pix[i] = 255 } else { self.coverageMask.Pix[i] = 0 }
+		}
+		self.source.C = resolver(index)
+		draw.DrawMask(dst, target, &self.source, image.Point{}, &self.coverageMask, glyphMask.Rect.Min, draw.Over)
+	}
+}
+
+// Shapes the given text and positions its glyphs with the given pen,
+// invoking fn (if not nil) for each glyph with its snapped origin. Glyphs
+// are advanced with [ggfnt.FractionalPen.AdvancePair](), and each line
+// starts at the initial pen x, [ggfnt.FontMetrics.LineHeight]() below the
+// previous one. Returns the widest line advance and the number of lines.
+func (self *RenderContext) layout(text string, pen *ggfnt.FractionalPen, scale float64, fn func(glyphIndex ggfnt.GlyphIndex, x, y int)) (float64, int, error) {
+	var err error
+	self.lineBreaks = self.lineBreaks[ : 0]
+	self.glyphs, err = self.shaper.AppendShape(self.glyphs[ : 0], text, self.settings)
+	if err != nil { return 0, 0, err }
+
+	startX, _ := pen.Position()
+	lineHeight := float64(self.font.Metrics().LineHeight())
+	var width float64
+	var nextBreak int
+	var prevGlyph ggfnt.GlyphIndex = ggfnt.GlyphMissing
+	for i := 0; i <= len(self.glyphs); i++ {
+		for nextBreak < len(self.lineBreaks) && self.lineBreaks[nextBreak] == i {
+			if prevGlyph != ggfnt.GlyphMissing { pen.Advance(float64(self.font.GlyphAdvance(prevGlyph))*scale) }
+			x, y := pen.Position()
+			width = max(width, x - startX)
+			pen.SetPosition(startX, y + lineHeight)
+			prevGlyph = ggfnt.GlyphMissing
+			nextBreak += 1
+		}
+		if i == len(self.glyphs) { break }
+
+		glyphIndex := self.glyphs[i]
+		if prevGlyph != ggfnt.GlyphMissing { pen.AdvancePair(self.font, prevGlyph, glyphIndex, scale) }
+		if fn != nil {
+			x, y := pen.Snapped()
+			fn(glyphIndex, x, y)
+		}
+		prevGlyph = glyphIndex
+	}
+	if prevGlyph != ggfnt.GlyphMissing { pen.Advance(float64(self.font.GlyphAdvance(prevGlyph))*scale) }
+	x, _ := pen.Position()
+	return max(width, x - startX), len(self.lineBreaks) + 1, nil
+}
+
+func (self *RenderContext) registerControl(codePoint rune, glyphPosition int) {
+	if codePoint == '\n' { self.lineBreaks = append(self.lineBreaks, glyphPosition) }
+}
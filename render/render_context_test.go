@@ -0,0 +1,128 @@
+package render
+
+import "testing"
+import "image"
+import "image/color"
+import "image/draw"
+
+import "github.com/tinne26/ggfnt"
+import "github.com/tinne26/ggfnt/builder"
+
+func TestRenderContext(t *testing.T) {
+	fontBuilder := builder.New()
+	blankUID, err := fontBuilder.AddBlankGlyph(4)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddBlankGlyph() error: %s", err) }
+	uids := []uint64{blankUID}
+	for x := 0; x < 2; x++ {
+		glyphMask := image.NewAlpha(image.Rect(0, -1, x + 1, 0))
+		glyphMask.SetAlpha(x, -1, color.Alpha{255})
+		uid, err := fontBuilder.AddGlyph(glyphMask)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+		err = fontBuilder.SetGlyphPlacement(uid, ggfnt.GlyphPlacement{ Advance: uint8(x + 2) })
+		if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphPlacement() error: %s", err) }
+		uids = append(uids, uid)
+	}
+	fontBuilder.SetHorzInterspacing(0)
+	setting, err := fontBuilder.AddSetting("style", "regular", "bold")
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSetting() error: %s", err) }
+	switchKey, err := fontBuilder.AddSwitch(setting)
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSwitch() error: %s", err) }
+	err = fontBuilder.MapWithSwitchSingles('a', switchKey, uids[1], uids[2])
+	if err != nil { t.Fatalf("unexpected FontBuilder.MapWithSwitchSingles() error: %s", err) }
+	err = fontBuilder.Map('b', uids[0])
+	if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+	err = fontBuilder.AddSimpleUtf8RewriteRule('a', 'c', 'c') // 'c' is only valid through the rule
+	if err != nil { t.Fatalf("unexpected FontBuilder.AddSimpleUtf8RewriteRule() error: %s", err) }
+	font, err := fontBuilder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+
+	context, err := NewRenderContext(font)
+	if err != nil { t.Fatalf("unexpected NewRenderContext() error: %s", err) }
+	if context.SetSetting("weight", 0) == nil { t.Fatalf("expected RenderContext.SetSetting() to fail with unknown setting") }
+	if context.SetSetting("style", 2) == nil { t.Fatalf("expected RenderContext.SetSetting() to fail with out of range option") }
+
+	// measuring, with mappings and rewrites following setting changes
+	metrics := font.Metrics()
+	lineHeight := int(metrics.Ascent()) + int(metrics.Descent())
+	tests := []struct{ Option uint8; Text string; Width, Height int }{
+		{0, "ab", 6, lineHeight}, {1, "ab", 7, lineHeight}, {0, "ab", 6, lineHeight},
+		{1, "a\nb", 4, lineHeight + metrics.LineHeight()}, {0, "ccb", 6, lineHeight},
+		{0, "\n", 0, lineHeight + metrics.LineHeight()},
+	}
+	for _, test := range tests {
+		err = context.SetSetting("style", test.Option)
+		if err != nil { t.Fatalf("unexpected RenderContext.SetSetting() error: %s", err) }
+		width, height, err := context.Measure(test.Text)
+		if err != nil { t.Fatalf("unexpected RenderContext.Measure() error: %s", err) }
+		if width != test.Width || height != test.Height {
+			t.Fatalf("option %d, Measure(%q) expected (%d, %d), got (%d, %d)", test.Option, test.Text, test.Width, test.Height, width, height)
+		}
+	}
+	_, _, err = context.Measure("az")
+	if err == nil { t.Fatalf("expected RenderContext.Measure() to fail with unmapped code point") }
+
+	// drawing
+	err = context.SetSetting("style", 1)
+	if err != nil { t.Fatalf("unexpected RenderContext.SetSetting() error: %s", err) }
+	baseline := int(metrics.Ascent())
+	img := image.NewRGBA(image.Rect(0, 0, 10, lineHeight))
+	err = context.Draw(img, image.Pt(1, baseline), []color.RGBA{255: {255, 0, 0, 255}}, "ba")
+	if err != nil { t.Fatalf("unexpected RenderContext.Draw() error: %s", err) }
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			expected := color.RGBA{}
+			if x == 1 + 4 + 1 && y == baseline - 1 { expected = color.RGBA{255, 0, 0, 255} }
+			if img.RGBAAt(x, y) != expected {
+				t.Fatalf("expected pixel (%d, %d) to be %v, got %v", x, y, expected, img.RGBAAt(x, y))
+			}
+		}
+	}
+
+	// glyphs are composited over the existing contents
+	blue := color.RGBA{0, 0, 255, 255}
+	draw.Draw(img, img.Rect, image.NewUniform(blue), image.Point{}, draw.Src)
+	err = context.Draw(img, image.Pt(1, baseline), []color.RGBA{255: {128, 0, 0, 128}}, "ba")
+	if err != nil { t.Fatalf("unexpected RenderContext.Draw() error: %s", err) }
+	blended := img.RGBAAt(1 + 4 + 1, baseline - 1)
+	if blended.R != 128 || blended.B == 0 || blended.B == 255 || blended.A != 255 {
+		t.Fatalf("expected glyph pixel to be blended over blue, got %v", blended)
+	}
+	if img.RGBAAt(0, 0) != blue { t.Fatalf("expected pixels outside glyphs to be preserved") }
+}
+
+func TestDrawWithPen(t *testing.T) {
+	fontBuilder := builder.New()
+	glyphMask := image.NewAlpha(image.Rect(0, -1, 1, 0))
+	glyphMask.SetAlpha(0, -1, color.Alpha{255})
+	var uids []uint64
+	for _, codePoint := range []rune{'a', 'b'} {
+		uid, err := fontBuilder.AddGlyph(glyphMask)
+		if err != nil { t.Fatalf("unexpected FontBuilder.AddGlyph() error: %s", err) }
+		err = fontBuilder.SetGlyphPlacement(uid, ggfnt.GlyphPlacement{ Advance: 2 })
+		if err != nil { t.Fatalf("unexpected FontBuilder.SetGlyphPlacement() error: %s", err) }
+		err = fontBuilder.Map(codePoint, uid)
+		if err != nil { t.Fatalf("unexpected FontBuilder.Map() error: %s", err) }
+		uids = append(uids, uid)
+	}
+	fontBuilder.SetHorzInterspacing(1)
+	fontBuilder.SetKerningPair(uids[0], uids[1], -1)
+	font, err := fontBuilder.Build()
+	if err != nil { t.Fatalf("unexpected FontBuilder.Build() error: %s", err) }
+	context, err := NewRenderContext(font)
+	if err != nil { t.Fatalf("unexpected NewRenderContext() error: %s", err) }
+
+	// pair advances are 2 (a, b) and 3 (b, a), halved
+	baseline := int(font.Metrics().Ascent())
+	pen := ggfnt.NewFractionalPen(0.5, float64(baseline))
+	img := image.NewRGBA(image.Rect(0, 0, 8, baseline + 1))
+	err = context.DrawWithOptions(img, image.Point{}, nil, "abab", DrawOptions{ Pen: &pen, AdvanceScale: 0.5 })
+	if err != nil { t.Fatalf("unexpected RenderContext.DrawWithOptions() error: %s", err) }
+	for x := 0; x < img.Rect.Dx(); x++ {
+		expected := (x == 0 || x == 1 || x == 3 || x == 4)
+		if (img.RGBAAt(x, baseline - 1).A != 0) != expected {
+			t.Fatalf("expected pixel (%d, %d) to be set = %t", x, baseline - 1, expected)
+		}
+	}
+	x, y := pen.Position()
+	if x != 5 || y != float64(baseline) { t.Fatalf("expected pen to end at (5, %d), got (%f, %f)", baseline, x, y) }
+}
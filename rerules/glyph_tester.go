@@ -3,10 +3,25 @@ package rerules
 import "github.com/tinne26/ggfnt"
 import "github.com/tinne26/ggfnt/internal/rerules/glyphrule"
 
+// A GlyphTester applies glyph rewrite rules to a stream of glyph indices. The
+// zero value is a valid tester without any rules. To load all the glyph
+// rules of a font, see [NewGlyphTester]().
+//
+// Sequences are processed with BeginSequence(), Feed() and FinishSequence(),
+// and the confirmed (and possibly rewritten) glyph indices are passed to
+// the given callback as soon as they can't be affected by further rules.
 type GlyphTester struct {
 	tester glyphrule.Tester
 }
 
+// Creates a new tester with all the glyph rewrite rules of the given font.
+func NewGlyphTester(font *ggfnt.Font) (*GlyphTester, error) {
+	tester := &GlyphTester{}
+	err := tester.addFontRules(font)
+	if err != nil { return nil, err }
+	return tester, nil
+}
+
 // --- general operations ---
 
 func (self *GlyphTester) NumRules() int {
@@ -25,6 +40,14 @@ func (self *GlyphTester) Resync(font *ggfnt.Font, settingsCache *ggfnt.SettingsC
 	return self.tester.Resync(font, settingsCache)
 }
 
+// Returns a human-readable representation of the tester's compiled
+// decision trees, mostly for debugging rewrite rules. Trees are only
+// compiled on [GlyphTester.Resync]() or when beginning a sequence, so
+// until then they may be reported as pending.
+func (self *GlyphTester) DumpDecisionTree() string {
+	return self.tester.DumpDecisionTree()
+}
+
 // --- rule management ---
 
 func (self *GlyphTester) RemoveAllRules() {
@@ -39,8 +62,20 @@ func (self *GlyphTester) RemoveRule(rule ggfnt.GlyphRewriteRule) bool {
 	return self.tester.RemoveRule(rule)
 }
 
+func (self *GlyphTester) addFontRules(font *ggfnt.Font) error {
+	rewrites := font.Rewrites()
+	for i := uint16(0); i < rewrites.NumGlyphRules(); i++ {
+		err := self.AddRule(rewrites.GetGlyphRule(i))
+		if err != nil { return err }
+	}
+	return nil
+}
+
 // --- condition control ---
 
+// Re-evaluates the rule conditions with the given settings. Changes
+// take effect on the next BeginSequence(), so this should be called
+// before starting a sequence whenever the settings might have changed.
 func (self *GlyphTester) RefreshConditions(font *ggfnt.Font, settingsCache *ggfnt.SettingsCache) {
 	self.tester.RefreshConditions(font, settingsCache)
 }
@@ -62,3 +97,37 @@ func (self *GlyphTester) Break(fn func(ggfnt.GlyphIndex)) {
 func (self *GlyphTester) FinishSequence(fn func(ggfnt.GlyphIndex)) {
 	self.tester.FinishSequence(fn)
 }
+
+// --- one-shot helpers ---
+
+// Applies the font's glyph rewrite rules to the given glyph sequence
+// and returns the result in a new slice. Control glyph indices (e.g.
+// [ggfnt.GlyphNewLine]) break rule sequences and are passed through
+// unchanged, while [ggfnt.GlyphZilch] glyphs are dropped.
+//
+// This loads and compiles all the font rules on each call, so for
+// repeated or streaming use a [GlyphTester] should be kept instead.
+func ApplyGlyphRewrites(font *ggfnt.Font, cache *ggfnt.SettingsCache, in []ggfnt.GlyphIndex) ([]ggfnt.GlyphIndex, error) {
+	tester, err := NewGlyphTester(font)
+	if err != nil { return nil, err }
+
+	out := make([]ggfnt.GlyphIndex, 0, len(in))
+	appendGlyph := func(glyphIndex ggfnt.GlyphIndex) { out = append(out, glyphIndex) }
+	tester.RefreshConditions(font, cache)
+	err = tester.BeginSequence(font, cache)
+	if err != nil { return nil, err }
+	for _, glyphIndex := range in {
+		if glyphIndex > ggfnt.GlyphZilch {
+			tester.Break(appendGlyph)
+			out = append(out, glyphIndex)
+			continue
+		}
+		err = tester.Feed(glyphIndex, appendGlyph)
+		if err != nil {
+			tester.FinishSequence(func(ggfnt.GlyphIndex) {})
+			return nil, err
+		}
+	}
+	tester.FinishSequence(appendGlyph)
+	return out, nil
+}
@@ -0,0 +1,135 @@
+package rerules
+
+import "errors"
+import "strconv"
+
+import "github.com/tinne26/ggfnt"
+
+// A Shaper converts text to the final sequence of glyph indices that
+// have to be drawn for it, applying in order:
+//  - The font's utf8 rewrite rules.
+//  - Code point to glyph mapping, including mapping switches and the
+//    selection of a specific glyph for glyph groups.
+//  - The font's glyph rewrite rules.
+//
+// Control codes (code points before ' ', like '\n') are not mapped. Instead,
+// they break the rewrite rule sequences and are reported through the control
+// function, if any (see [Shaper.SetControlFunc]()).
+//
+// Shapers are not safe for concurrent use.
+type Shaper struct {
+	font *ggfnt.Font
+	utf8Tester Utf8Tester
+	glyphTester GlyphTester
+
+	selectFn func(rune, ggfnt.GlyphMappingGroup) uint8
+	controlFn func(rune, int)
+
+	// state for the current Shape() call
+	settings *ggfnt.SettingsCache
+	output []ggfnt.GlyphIndex
+	err error
+}
+
+// Creates a new shaper for the given font, loading all its rewrite rules.
+func NewShaper(font *ggfnt.Font) (*Shaper, error) {
+	shaper := &Shaper{ font: font }
+	err := shaper.utf8Tester.addFontRules(font)
+	if err != nil { return nil, err }
+	err = shaper.glyphTester.addFontRules(font)
+	if err != nil { return nil, err }
+	return shaper, nil
+}
+
+func (self *Shaper) Font() *ggfnt.Font {
+	return self.font
+}
+
+// Sets the function used to choose a glyph when a code point is mapped to
+// a glyph group (e.g. an animation). The function must return a value between
+// 0 and group.Size() - 1. If nil, the first glyph of the group is always used.
+func (self *Shaper) SetSelectFunc(fn func(codePoint rune, group ggfnt.GlyphMappingGroup) uint8) {
+	self.selectFn = fn
+}
+
+// Sets the function to be invoked when a control code is found. The
+// second argument is the index in the output glyph sequence at which
+// the control code appears, so a line break handler would typically
+// start a new line before drawing glyph at that index.
+func (self *Shaper) SetControlFunc(fn func(codePoint rune, glyphPosition int)) {
+	self.controlFn = fn
+}
+
+// Shapes the given text and returns the resulting glyph indices.
+// An error is returned if the text contains code points that the
+// font doesn't map.
+func (self *Shaper) Shape(text string, settings *ggfnt.SettingsCache) ([]ggfnt.GlyphIndex, error) {
+	return self.AppendShape(nil, text, settings)
+}
+
+// Same as [Shaper.Shape](), but appending the glyph indices to the
+// given buffer. Control function glyph positions are relative to
+// the start of the buffer.
+func (self *Shaper) AppendShape(buffer []ggfnt.GlyphIndex, text string, settings *ggfnt.SettingsCache) ([]ggfnt.GlyphIndex, error) {
+	self.settings = settings
+	self.output = buffer
+	self.err = nil
+
+	self.utf8Tester.RefreshConditions(self.font, settings)
+	self.glyphTester.RefreshConditions(self.font, settings)
+	err := self.utf8Tester.BeginSequence(self.font, settings)
+	if err != nil { return buffer, err }
+	err = self.glyphTester.BeginSequence(self.font, settings)
+	if err != nil {
+		self.utf8Tester.FinishSequence(func(rune) {})
+		return buffer, err
+	}
+
+	for _, codePoint := range text {
+		if codePoint < ' ' {
+			self.utf8Tester.Break(self.mapRune)
+			self.glyphTester.Break(self.appendGlyph)
+			if self.controlFn != nil { self.controlFn(codePoint, len(self.output)) }
+			continue
+		}
+
+		err = self.utf8Tester.Feed(codePoint, self.mapRune)
+		if err == nil { err = self.err }
+		if err != nil {
+			self.utf8Tester.FinishSequence(func(rune) {})
+			self.glyphTester.FinishSequence(func(ggfnt.GlyphIndex) {})
+			return self.clearState(buffer, err)
+		}
+	}
+	self.utf8Tester.FinishSequence(self.mapRune)
+	self.glyphTester.FinishSequence(self.appendGlyph)
+	if self.err != nil { return self.clearState(buffer, self.err) }
+	return self.clearState(self.output, nil)
+}
+
+func (self *Shaper) clearState(output []ggfnt.GlyphIndex, err error) ([]ggfnt.GlyphIndex, error) {
+	self.settings = nil
+	self.output = nil
+	self.err = nil
+	return output, err
+}
+
+func (self *Shaper) mapRune(codePoint rune) {
+	if self.err != nil { return }
+	group, found := self.font.Mapping().Utf8WithCache(codePoint, self.settings)
+	if !found {
+		self.err = errors.New("code point " + strconv.QuoteRune(codePoint) + " not mapped by the font")
+		return
+	}
+
+	var choice uint8
+	if self.selectFn != nil && group.Size() > 1 {
+		choice = self.selectFn(codePoint, group)
+	}
+	err := self.glyphTester.Feed(group.Select(choice), self.appendGlyph)
+	if err != nil { self.err = err }
+}
+
+func (self *Shaper) appendGlyph(glyphIndex ggfnt.GlyphIndex) {
+	self.output = append(self.output, glyphIndex)
+}
@@ -1,12 +1,29 @@
 package rerules
 
+import "strings"
+
 import "github.com/tinne26/ggfnt"
 import "github.com/tinne26/ggfnt/internal/rerules/utf8rule"
 
+// A Utf8Tester applies utf8 rewrite rules to a stream of code points. The
+// zero value is a valid tester without any rules. To load all the utf8
+// rules of a font, see [NewUtf8Tester]().
+//
+// Sequences are processed with BeginSequence(), Feed() and FinishSequence(),
+// and the confirmed (and possibly rewritten) code points are passed to
+// the given callback as soon as they can't be affected by further rules.
 type Utf8Tester struct {
 	tester utf8rule.Tester
 }
 
+// Creates a new tester with all the utf8 rewrite rules of the given font.
+func NewUtf8Tester(font *ggfnt.Font) (*Utf8Tester, error) {
+	tester := &Utf8Tester{}
+	err := tester.addFontRules(font)
+	if err != nil { return nil, err }
+	return tester, nil
+}
+
 // --- general operations ---
 
 func (self *Utf8Tester) NumRules() int {
@@ -25,6 +42,14 @@ func (self *Utf8Tester) Resync(font *ggfnt.Font, settingsCache *ggfnt.SettingsCa
 	return self.tester.Resync(font, settingsCache)
 }
 
+// Returns a human-readable representation of the tester's compiled
+// decision trees, mostly for debugging rewrite rules. Trees are only
+// compiled on [Utf8Tester.Resync]() or when beginning a sequence, so
+// until then they may be reported as pending.
+func (self *Utf8Tester) DumpDecisionTree() string {
+	return self.tester.DumpDecisionTree()
+}
+
 // --- rule management ---
 
 func (self *Utf8Tester) RemoveAllRules() {
@@ -39,8 +64,20 @@ func (self *Utf8Tester) RemoveRule(rule ggfnt.Utf8RewriteRule) bool {
 	return self.tester.RemoveRule(rule)
 }
 
+func (self *Utf8Tester) addFontRules(font *ggfnt.Font) error {
+	rewrites := font.Rewrites()
+	for i := uint16(0); i < rewrites.NumUTF8Rules(); i++ {
+		err := self.AddRule(rewrites.GetUtf8Rule(i))
+		if err != nil { return err }
+	}
+	return nil
+}
+
 // --- condition control ---
 
+// Re-evaluates the rule conditions with the given settings. Changes
+// take effect on the next BeginSequence(), so this should be called
+// before starting a sequence whenever the settings might have changed.
 func (self *Utf8Tester) RefreshConditions(font *ggfnt.Font, settingsCache *ggfnt.SettingsCache) {
 	self.tester.RefreshConditions(font, settingsCache)
 }
@@ -62,3 +99,38 @@ func (self *Utf8Tester) Break(fn func(rune)) {
 func (self *Utf8Tester) FinishSequence(fn func(rune)) {
 	self.tester.FinishSequence(fn)
 }
+
+// --- one-shot helpers ---
+
+// Applies the font's utf8 rewrite rules to the given text and returns
+// the rewritten string. Control codes (code points before ' ', like '\n')
+// break rule sequences and are passed through unchanged. Invalid UTF-8
+// in the text results in an error.
+//
+// This loads and compiles all the font rules on each call, so for
+// repeated or streaming use a [Utf8Tester] should be kept instead.
+func ApplyUtf8Rewrites(font *ggfnt.Font, cache *ggfnt.SettingsCache, text string) (string, error) {
+	tester, err := NewUtf8Tester(font)
+	if err != nil { return "", err }
+
+	var out strings.Builder
+	out.Grow(len(text))
+	appendRune := func(codePoint rune) { out.WriteRune(codePoint) }
+	tester.RefreshConditions(font, cache)
+	err = tester.BeginSequence(font, cache)
+	if err != nil { return "", err }
+	for _, codePoint := range text {
+		if codePoint < ' ' {
+			tester.Break(appendRune)
+			out.WriteRune(codePoint)
+			continue
+		}
+		err = tester.Feed(codePoint, appendRune)
+		if err != nil {
+			tester.FinishSequence(func(rune) {})
+			return "", err
+		}
+	}
+	tester.FinishSequence(appendRune)
+	return out.String(), nil
+}
@@ -27,42 +27,71 @@ type SettingsCache struct {
 	settings []uint8 // reference to current settings
 }
 
+// Creates a settings cache for the given font, with each setting
+// initialized to its init value. See also [FontSettings.GetInitValue]().
+//
+// The cache also keeps track of which settings are referenced by mapping
+// switches and rewrite conditions, so [SettingsCache.Set]() can avoid
+// invalidating cached values when irrelevant settings are modified.
 func NewSettingsCache(font *Font) *SettingsCache {
 	var cache SettingsCache
 	
 	numSettings := int(font.Settings().Count())
 	cache.settings = make([]uint8, numSettings)
+	for i := 0; i < numSettings; i++ {
+		cache.settings[i] = font.Settings().GetInitValue(SettingKey(i))
+	}
 	cache.mappingSettingRelevanceFlags = internal.NewBoolList(numSettings)
 	cache.rewriteConditionsSettingRelevanceFlags = internal.NewBoolList(numSettings)
-	cache.mappingSettingRelevanceFlags.SetAllTrue()
-	cache.rewriteConditionsSettingRelevanceFlags.SetAllTrue()
 	
 	numSwitchTypes := int(font.Mapping().NumSwitchTypes())
 	cache.mappingCaseCachingFlags = internal.NewBoolList(numSwitchTypes)
 	cache.mappingCachedCases = make([]uint8, numSwitchTypes)
+	if numSwitchTypes > 0 {
+		// switch data is a plain list of setting keys
+		offsetToEndOffsets := font.OffsetToMappingSwitches + 1
+		dataSize := internal.DecodeUint16LE(font.Data[offsetToEndOffsets + uint32(numSwitchTypes - 1)*2 : ])
+		offsetToData := offsetToEndOffsets + uint32(numSwitchTypes)*2
+		for _, settingKey := range font.Data[offsetToData : offsetToData + uint32(dataSize)] {
+			cache.mappingSettingRelevanceFlags.Set(int(settingKey), true)
+		}
+	}
 
 	numConditions := int(font.Rewrites().NumConditions())
 	cache.rewriteConditionsCachingFlags = internal.NewBoolList(numConditions)
 	cache.rewriteConditionsCachedBools = internal.NewBoolList(numConditions)
-
-	// TODO: need to see if I can set relevance flags for rewrite rules and
-	//       mapping conditions more precisely in a reasonable way than just
-	//       SetAllTrue().
+	for i := 0; i < numConditions; i++ {
+		for _, settingKey := range font.Rewrites().ConditionSettings(uint8(i)) {
+			cache.rewriteConditionsSettingRelevanceFlags.Set(int(settingKey), true)
+		}
+	}
 
 	return &cache
 }
 
+// Returns the underlying settings values, indexed by [SettingKey].
+// The slice must be treated as read only, use [SettingsCache.Set]()
+// for any modifications.
+//
 // TODO: read only, might remove later.
 func (self *SettingsCache) UnsafeSlice() []uint8 {
 	return self.settings
 }
 
+// Returns the current option index for the given setting,
+// or 0 if the setting is undefined.
 func (self *SettingsCache) Get(key SettingKey) uint8 {
 	if len(self.settings) <= int(key) { return 0 }
 	return self.settings[key]
 }
 
-// Returns two bools for mappings and rewrite conditions affected.
+// Sets the option index for the given setting. The key must be a valid
+// setting key and the option must be in range for it.
+//
+// Invalidation is not tracked per switch or condition: if the setting is
+// referenced by any mapping switch, all cached mapping cases are flushed,
+// and likewise for rewrite conditions. The returned bools indicate which
+// of the two caches was flushed.
 func (self *SettingsCache) Set(key SettingKey, option uint8) (mappingsAffected, rewriteConditionsAffected bool) {
 	self.settings[key] = option
 	
@@ -0,0 +1,98 @@
+package ggfnt
+
+import "image"
+import "image/color"
+import "image/draw"
+
+const specimenColumns = 16
+const specimenPadding = 2
+const specimenDigitWidth = 3
+const specimenDigitHeight = 5
+
+var specimenLabelColor = color.RGBA{128, 128, 128, 255}
+
+// 3x5 digit bitmaps, one row per uint8 (the lowest 3 bits are used,
+// with the highest of them being the leftmost pixel)
+var specimenDigits = [10][specimenDigitHeight]uint8{
+	{0b111, 0b101, 0b101, 0b101, 0b111}, // 0
+	{0b010, 0b110, 0b010, 0b010, 0b111}, // 1
+	{0b111, 0b001, 0b111, 0b100, 0b111}, // 2
+	{0b111, 0b001, 0b011, 0b001, 0b111}, // 3
+	{0b101, 0b101, 0b111, 0b001, 0b001}, // 4
+	{0b111, 0b100, 0b111, 0b001, 0b111}, // 5
+	{0b111, 0b100, 0b111, 0b101, 0b111}, // 6
+	{0b111, 0b001, 0b010, 0b010, 0b010}, // 7
+	{0b111, 0b101, 0b111, 0b101, 0b111}, // 8
+	{0b111, 0b101, 0b111, 0b001, 0b111}, // 9
+}
+
+// Renders all the glyphs of the font into a single image, laid out
+// in a grid, with each glyph labeled with its index. This is mainly
+// useful for previews and documentation.
+//
+// Cells are sized to fit the widest glyph, using [Font.GlyphAdvance]()
+// and [FontGlyphs.Bounds](), so monospaced fonts get cells matching
+// their mono width. The palette maps glyph mask color indices to RGBA
+// colors, as described in [NewPaletteResolver]().
+func RenderSpecimen(font *Font, palette []color.RGBA) image.Image {
+	glyphs := font.Glyphs()
+	numGlyphs := int(glyphs.Count())
+
+	// compute cell sizes
+	var minX, maxX int // ink and advance extents relative to the glyph origins
+	for i := 0; i < numGlyphs; i++ {
+		maxX = max(maxX, font.GlyphAdvance(GlyphIndex(i)))
+		bounds := glyphs.Bounds(GlyphIndex(i))
+		if bounds.Empty() { continue }
+		minX, maxX = min(minX, bounds.Min.X), max(maxX, bounds.Max.X)
+	}
+	cellWidth := max(specimenLabelWidth(numGlyphs - 1), maxX - minX) + specimenPadding*2
+	ascent, descent := font.Metrics().InkBox()
+	cellHeight := specimenPadding*3 + specimenDigitHeight + ascent + descent
+
+	// create image
+	numRows := (numGlyphs + specimenColumns - 1)/specimenColumns
+	numCols := min(numGlyphs, specimenColumns)
+	img := image.NewRGBA(image.Rect(0, 0, numCols*cellWidth, numRows*cellHeight))
+
+	// draw each glyph with its label
+	resolver := NewPaletteResolver(palette)
+	for i := 0; i < numGlyphs; i++ {
+		cellX := (i % specimenColumns)*cellWidth
+		cellY := (i / specimenColumns)*cellHeight
+		specimenDrawLabel(img, i, cellX + specimenPadding, cellY + specimenPadding)
+
+		glyphImg := glyphs.RasterizeRGBA(GlyphIndex(i), resolver)
+		if glyphImg.Rect.Empty() { continue }
+		origin := image.Pt(cellX + specimenPadding - minX, cellY + specimenPadding*2 + specimenDigitHeight + ascent)
+		draw.Draw(img, glyphImg.Rect.Add(origin), glyphImg, glyphImg.Rect.Min, draw.Over)
+	}
+
+	return img
+}
+
+func specimenLabelWidth(n int) int {
+	numDigits := 1
+	for n >= 10 {
+		n /= 10
+		numDigits += 1
+	}
+	return numDigits*(specimenDigitWidth + 1) - 1
+}
+
+func specimenDrawLabel(img *image.RGBA, n int, x, y int) {
+	// draw digits from right to left
+	x += specimenLabelWidth(n) - specimenDigitWidth
+	for {
+		digit := specimenDigits[n % 10]
+		for row := 0; row < specimenDigitHeight; row++ {
+			for col := 0; col < specimenDigitWidth; col++ {
+				if (digit[row] >> (specimenDigitWidth - 1 - col)) & 1 == 0 { continue }
+				img.SetRGBA(x + col, y + row, specimenLabelColor)
+			}
+		}
+		n /= 10
+		if n == 0 { break }
+		x -= specimenDigitWidth + 1
+	}
+}